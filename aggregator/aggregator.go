@@ -18,6 +18,8 @@ import (
 	"context"
 	"time"
 
+	"github.com/chenmingyong0423/go-mongox/v2/cursor"
+	"github.com/chenmingyong0423/go-mongox/v2/internal/pkg/utils"
 	"github.com/chenmingyong0423/go-mongox/v2/operation"
 
 	"github.com/chenmingyong0423/go-mongox/v2/callback"
@@ -37,6 +39,8 @@ var _ IAggregator[any] = (*Aggregator[any])(nil)
 type Aggregator[T any] struct {
 	collection *mongo.Collection
 	pipeline   any
+	unscoped   bool
+	batchSize  int32
 
 	dbCallbacks *callback.Callback
 	fields      []*field.Filed
@@ -44,6 +48,9 @@ type Aggregator[T any] struct {
 	modelHook   any
 	beforeHooks []beforeHookFn
 	afterHooks  []afterHookFn
+
+	clock   func() time.Time
+	timeout time.Duration
 }
 
 func NewAggregator[T any](collection *mongo.Collection, dbCallbacks *callback.Callback, fields []*field.Filed) *Aggregator[T] {
@@ -51,6 +58,7 @@ func NewAggregator[T any](collection *mongo.Collection, dbCallbacks *callback.Ca
 		collection:  collection,
 		dbCallbacks: dbCallbacks,
 		fields:      fields,
+		clock:       time.Now,
 	}
 }
 
@@ -58,6 +66,18 @@ func (a *Aggregator[T]) ModelHook(modelHook any) *Aggregator[T] {
 	a.modelHook = modelHook
 	return a
 }
+
+// Clock is used to set the function which returns the current time of the aggregation, time.Now by default
+func (a *Aggregator[T]) Clock(clock func() time.Time) *Aggregator[T] {
+	a.clock = clock
+	return a
+}
+
+// Timeout is used to set the timeout of the aggregation, it is ignored if the context already has a deadline
+func (a *Aggregator[T]) Timeout(timeout time.Duration) *Aggregator[T] {
+	a.timeout = timeout
+	return a
+}
 func (a *Aggregator[T]) RegisterBeforeHooks(hooks ...beforeHookFn) *Aggregator[T] {
 	a.beforeHooks = append(a.beforeHooks, hooks...)
 	return a
@@ -68,36 +88,55 @@ func (a *Aggregator[T]) RegisterAfterHooks(hooks ...afterHookFn) *Aggregator[T]
 	return a
 }
 
+// Unscoped is used to include the soft-deleted documents in the aggregation
+func (a *Aggregator[T]) Unscoped() *Aggregator[T] {
+	a.unscoped = true
+	return a
+}
+
 func (a *Aggregator[T]) Pipeline(pipeline any) *Aggregator[T] {
 	a.pipeline = pipeline
 	return a
 }
 
+// BatchSize is used to set the number of documents returned by each batch of the cursor
+func (a *Aggregator[T]) BatchSize(batchSize int32) *Aggregator[T] {
+	a.batchSize = batchSize
+	return a
+}
+
 func (a *Aggregator[T]) Aggregate(ctx context.Context, opts ...options.Lister[options.AggregateOptions]) ([]*T, error) {
-	currentTime := time.Now()
-	globalOpContext := operation.NewOpContext(a.collection, operation.WithPipeline(a.pipeline), operation.WithMongoOptions(opts), operation.WithModelHook(a.modelHook), operation.WithStartTime(currentTime), operation.WithFields(a.fields))
+	ctx, cancel := utils.WithTimeout(ctx, a.timeout)
+	defer cancel()
+	currentTime := a.clock()
+	opts = a.aggregateOptions(opts)
+	globalOpContext := operation.NewOpContext(a.collection, operation.WithPipeline(a.pipeline), operation.WithMongoOptions(opts), operation.WithModelHook(a.modelHook), operation.WithStartTime(currentTime), operation.WithFields(a.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameAggregate), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(a.unscoped))
 	opContext := NewOpContext(a.collection, a.pipeline, WithMongoOptions(opts), WithModelHook(a.modelHook), WithStartTime(currentTime), WithFields(a.fields))
 
-	err := a.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeInsert)
+	err := a.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeAggregate)
 	if err != nil {
 		return nil, err
 	}
+	ctx = globalOpContext.Context(ctx)
 
-	cursor, err := a.collection.Aggregate(ctx, a.pipeline, opts...)
+	cursor, err := a.collection.Aggregate(ctx, globalOpContext.Pipeline, opts...)
 	if err != nil {
-		return nil, err
+		globalOpContext.Duration = a.clock().Sub(currentTime)
+		return nil, a.dbCallbacks.OnError(ctx, globalOpContext, err)
 	}
 	defer cursor.Close(ctx)
 
 	result := make([]*T, 0)
 	err = cursor.All(ctx, &result)
+	globalOpContext.Duration = a.clock().Sub(currentTime)
 	if err != nil {
-		return nil, err
+		return nil, a.dbCallbacks.OnError(ctx, globalOpContext, err)
 	}
 
 	globalOpContext.Result = cursor
+	globalOpContext.Doc = result
 	opContext.Result = cursor
-	err = a.postActionHandler(ctx, globalOpContext, opContext, operation.OpTypeAfterInsert)
+	err = a.postActionHandler(ctx, globalOpContext, opContext, operation.OpTypeAfterAggregate)
 	if err != nil {
 		return nil, err
 	}
@@ -107,30 +146,43 @@ func (a *Aggregator[T]) Aggregate(ctx context.Context, opts ...options.Lister[op
 
 // AggregateWithParse is used to parse the result of the aggregation
 // result must be a pointer to a slice
+//
+// Deprecated: use AggregateAs, which decodes the result into a typed slice
 func (a *Aggregator[T]) AggregateWithParse(ctx context.Context, result any, opts ...options.Lister[options.AggregateOptions]) error {
+	return a.aggregateWithParse(ctx, result, opts...)
+}
+
+func (a *Aggregator[T]) aggregateWithParse(ctx context.Context, result any, opts ...options.Lister[options.AggregateOptions]) error {
 
-	currentTime := time.Now()
-	globalOpContext := operation.NewOpContext(a.collection, operation.WithPipeline(a.pipeline), operation.WithMongoOptions(opts), operation.WithModelHook(a.modelHook), operation.WithStartTime(currentTime), operation.WithFields(a.fields))
+	ctx, cancel := utils.WithTimeout(ctx, a.timeout)
+	defer cancel()
+	currentTime := a.clock()
+	opts = a.aggregateOptions(opts)
+	globalOpContext := operation.NewOpContext(a.collection, operation.WithPipeline(a.pipeline), operation.WithMongoOptions(opts), operation.WithModelHook(a.modelHook), operation.WithStartTime(currentTime), operation.WithFields(a.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameAggregate), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(a.unscoped))
 	opContext := NewOpContext(a.collection, a.pipeline, WithMongoOptions(opts), WithModelHook(a.modelHook), WithStartTime(currentTime), WithFields(a.fields))
 
-	err := a.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeInsert)
+	err := a.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeAggregate)
 	if err != nil {
 		return err
 	}
+	ctx = globalOpContext.Context(ctx)
 
-	cursor, err := a.collection.Aggregate(ctx, a.pipeline, opts...)
+	cursor, err := a.collection.Aggregate(ctx, globalOpContext.Pipeline, opts...)
 	if err != nil {
-		return err
+		globalOpContext.Duration = a.clock().Sub(currentTime)
+		return a.dbCallbacks.OnError(ctx, globalOpContext, err)
 	}
 	defer cursor.Close(ctx)
 	err = cursor.All(ctx, result)
+	globalOpContext.Duration = a.clock().Sub(currentTime)
 	if err != nil {
-		return err
+		return a.dbCallbacks.OnError(ctx, globalOpContext, err)
 	}
 
 	globalOpContext.Result = cursor
+	globalOpContext.Doc = result
 	opContext.Result = cursor
-	err = a.postActionHandler(ctx, globalOpContext, opContext, operation.OpTypeAfterInsert)
+	err = a.postActionHandler(ctx, globalOpContext, opContext, operation.OpTypeAfterAggregate)
 	if err != nil {
 		return err
 	}
@@ -138,15 +190,69 @@ func (a *Aggregator[T]) AggregateWithParse(ctx context.Context, result any, opts
 	return nil
 }
 
+// Cursor is used to iterate the result of the aggregation one document at a time instead of loading all of them in memory.
+// The after callbacks are executed once the aggregate command returns, with the cursor as Result and a nil Doc,
+// then the after callbacks and hooks are executed for each decoded document. The timeout only applies to the aggregate command.
+func (a *Aggregator[T]) Cursor(ctx context.Context, opts ...options.Lister[options.AggregateOptions]) (*cursor.Cursor[T], error) {
+	queryCtx, cancel := utils.WithTimeout(ctx, a.timeout)
+	defer cancel()
+	currentTime := a.clock()
+	opts = a.aggregateOptions(opts)
+	globalOpContext := operation.NewOpContext(a.collection, operation.WithPipeline(a.pipeline), operation.WithMongoOptions(opts), operation.WithModelHook(a.modelHook), operation.WithStartTime(currentTime), operation.WithFields(a.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameAggregate), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(a.unscoped))
+	opContext := NewOpContext(a.collection, a.pipeline, WithMongoOptions(opts), WithModelHook(a.modelHook), WithStartTime(currentTime), WithFields(a.fields))
+
+	err := a.preActionHandler(queryCtx, globalOpContext, opContext, operation.OpTypeBeforeAggregate)
+	if err != nil {
+		return nil, err
+	}
+	queryCtx = globalOpContext.Context(queryCtx)
+
+	mongoCursor, err := a.collection.Aggregate(queryCtx, globalOpContext.Pipeline, opts...)
+	globalOpContext.Duration = a.clock().Sub(currentTime)
+	if err != nil {
+		return nil, a.dbCallbacks.OnError(queryCtx, globalOpContext, err)
+	}
+
+	globalOpContext.Result = mongoCursor
+	opContext.Result = mongoCursor
+	err = a.dbCallbacks.Execute(queryCtx, globalOpContext, operation.OpTypeAfterAggregate)
+	if err != nil {
+		_ = mongoCursor.Close(queryCtx)
+		return nil, err
+	}
+	return cursor.NewCursor[T](mongoCursor, func(ctx context.Context, doc *T) error {
+		globalOpContext.Doc = doc
+		return a.postActionHandler(ctx, globalOpContext, opContext, operation.OpTypeAfterAggregate)
+	}), nil
+}
+
+func (a *Aggregator[T]) aggregateOptions(opts []options.Lister[options.AggregateOptions]) []options.Lister[options.AggregateOptions] {
+	if a.batchSize != 0 {
+		opts = append(opts, options.Aggregate().SetBatchSize(a.batchSize))
+	}
+	return opts
+}
+
+// AggregateAs executes the aggregation of the aggregator and decodes the result into R,
+// it is used when the pipeline reshapes the documents of T, e.g. with $group or $project
+func AggregateAs[T any, R any](ctx context.Context, a *Aggregator[T], opts ...options.Lister[options.AggregateOptions]) ([]*R, error) {
+	result := make([]*R, 0)
+	if err := a.aggregateWithParse(ctx, &result, opts...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// preActionHandler runs the before callbacks and hooks, the onError callbacks are run with the error if one of them fails
 func (a *Aggregator[T]) preActionHandler(ctx context.Context, globalOpContext *operation.OpContext, opContext *OpContext, opType operation.OpType) error {
 	err := a.dbCallbacks.Execute(ctx, globalOpContext, opType)
 	if err != nil {
-		return err
+		return a.dbCallbacks.OnError(globalOpContext.Context(ctx), globalOpContext, err)
 	}
 	for _, beforeHook := range a.beforeHooks {
 		err = beforeHook(ctx, opContext)
 		if err != nil {
-			return err
+			return a.dbCallbacks.OnError(globalOpContext.Context(ctx), globalOpContext, err)
 		}
 	}
 	return nil
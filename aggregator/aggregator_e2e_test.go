@@ -22,6 +22,7 @@ import (
 
 	"github.com/chenmingyong0423/go-mongox/v2/callback"
 	"github.com/chenmingyong0423/go-mongox/v2/field"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
 
 	"github.com/chenmingyong0423/go-mongox/v2/bsonx"
 	"github.com/stretchr/testify/require"
@@ -345,3 +346,87 @@ func TestAggregator_e2e_AggregateWithParse(t *testing.T) {
 		})
 	}
 }
+
+func TestAggregator_e2e_Cursor(t *testing.T) {
+	collection := getCollection(t)
+
+	docs := make([]any, 0, 10)
+	for i := 0; i < 10; i++ {
+		docs = append(docs, &TestUser{Name: "user", Age: int64(i)})
+	}
+	insertManyResult, err := collection.InsertMany(context.Background(), docs)
+	require.NoError(t, err)
+	require.Len(t, insertManyResult.InsertedIDs, 10)
+	defer func() {
+		_, err := collection.DeleteMany(context.Background(), query.In("_id", insertManyResult.InsertedIDs...))
+		require.NoError(t, err)
+	}()
+
+	callbacks := callback.InitializeCallbacks()
+	afterAggregates, queries := 0, 0
+	err = callbacks.Register(operation.OpTypeAfterAggregate, "count", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+		if _, ok := opCtx.Doc.(*TestUser); ok {
+			afterAggregates++
+		} else if _, ok := opCtx.Result.(*mongo.Cursor); ok && opCtx.Doc == nil {
+			queries++
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	c, err := NewAggregator[TestUser](collection, callbacks, field.ParseFields(TestUser{})).
+		Pipeline(aggregation.NewStageBuilder().Match(query.Gte("age", 5)).Sort(bsonx.M("age", -1)).Build()).
+		BatchSize(2).
+		Cursor(context.Background())
+	require.NoError(t, err)
+
+	ages := make([]int64, 0)
+	err = c.ForEach(context.Background(), func(user *TestUser) error {
+		ages = append(ages, user.Age)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int64{9, 8, 7, 6, 5}, ages)
+	require.Equal(t, 5, afterAggregates)
+	require.Equal(t, 1, queries)
+
+	// the after callbacks run once the aggregate command returns even if the result is empty
+	afterAggregates, queries = 0, 0
+	c, err = NewAggregator[TestUser](collection, callbacks, field.ParseFields(TestUser{})).
+		Pipeline(aggregation.NewStageBuilder().Match(query.Gt("age", 100)).Build()).
+		Cursor(context.Background())
+	require.NoError(t, err)
+	require.False(t, c.Next(context.Background()))
+	require.NoError(t, c.Close(context.Background()))
+	require.Equal(t, 0, afterAggregates)
+	require.Equal(t, 1, queries)
+}
+
+func TestAggregator_e2e_AggregateAs(t *testing.T) {
+	collection := getCollection(t)
+
+	insertManyResult, err := collection.InsertMany(context.Background(), []*TestUser{
+		{Name: "chenmingyong", Age: 24},
+		{Name: "chenmingyong", Age: 26},
+		{Name: "burt", Age: 25},
+	})
+	require.NoError(t, err)
+	defer func() {
+		_, err := collection.DeleteMany(context.Background(), query.In("_id", insertManyResult.InsertedIDs...))
+		require.NoError(t, err)
+	}()
+
+	type AgeStats struct {
+		Name   string `bson:"_id"`
+		MaxAge int64  `bson:"max_age"`
+	}
+
+	aggregator := NewAggregator[TestUser](collection, callback.InitializeCallbacks(), field.ParseFields(TestUser{})).
+		Pipeline(aggregation.NewStageBuilder().
+			Match(query.In("_id", insertManyResult.InsertedIDs...)).
+			Group("$name", aggregation.NewBuilder().Max("max_age", "$age").Build()...).
+			Sort(bsonx.M("_id", 1)).Build())
+	stats, err := AggregateAs[TestUser, AgeStats](context.Background(), aggregator)
+	require.NoError(t, err)
+	require.Equal(t, []*AgeStats{{Name: "burt", MaxAge: 25}, {Name: "chenmingyong", MaxAge: 26}}, stats)
+}
@@ -0,0 +1,447 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulkwriter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/chenmingyong0423/go-mongox/v2/bsonx"
+	"github.com/chenmingyong0423/go-mongox/v2/callback"
+	"github.com/chenmingyong0423/go-mongox/v2/field"
+	"github.com/chenmingyong0423/go-mongox/v2/internal/pkg/utils"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+//go:generate mockgen -source=bulkwriter.go -destination=../mock/bulkwriter.mock.go -package=mocks
+type IBulkWriter[T any] interface {
+	BulkWrite(ctx context.Context, opts ...options.Lister[options.BulkWriteOptions]) (*BulkWriteResult, error)
+}
+
+var _ IBulkWriter[any] = (*BulkWriter[any])(nil)
+
+type modelType int
+
+const (
+	insertOne modelType = iota
+	updateOne
+	updateMany
+	replaceOne
+	deleteOne
+	deleteMany
+)
+
+type model struct {
+	modelType modelType
+	filter    any
+	updates   any
+	doc       any
+}
+
+// BulkWriteResult is the result of BulkWrite, the indexes are the ones of the models in the order they were added
+type BulkWriteResult struct {
+	InsertedCount int64
+	MatchedCount  int64
+	// ModifiedCount also counts the documents soft-deleted by DeleteOne and DeleteMany
+	ModifiedCount int64
+	DeletedCount  int64
+	UpsertedCount int64
+	UpsertedIDs   map[int]any
+	// WriteErrors maps the index of each failed model to its write error
+	WriteErrors map[int]mongo.BulkWriteError
+}
+
+type BulkWriter[T any] struct {
+	collection *mongo.Collection
+	fields     []*field.Filed
+
+	models    []model
+	ordered   *bool
+	chunkSize int
+	modelHook any
+
+	dbCallbacks *callback.Callback
+	beforeHooks []hookFn[T]
+	afterHooks  []hookFn[T]
+
+	clock   func() time.Time
+	timeout time.Duration
+}
+
+func NewBulkWriter[T any](collection *mongo.Collection, dbCallbacks *callback.Callback, fields []*field.Filed) *BulkWriter[T] {
+	return &BulkWriter[T]{collection: collection, dbCallbacks: dbCallbacks, fields: fields, clock: time.Now}
+}
+
+// InsertOne is used to add a model which inserts the document
+func (b *BulkWriter[T]) InsertOne(doc *T) *BulkWriter[T] {
+	b.models = append(b.models, model{modelType: insertOne, doc: doc})
+	return b
+}
+
+// UpdateOne is used to add a model which updates the first document matching the filter
+func (b *BulkWriter[T]) UpdateOne(filter any, updates any) *BulkWriter[T] {
+	b.models = append(b.models, model{modelType: updateOne, filter: filter, updates: updates})
+	return b
+}
+
+// UpdateMany is used to add a model which updates all the documents matching the filter
+func (b *BulkWriter[T]) UpdateMany(filter any, updates any) *BulkWriter[T] {
+	b.models = append(b.models, model{modelType: updateMany, filter: filter, updates: updates})
+	return b
+}
+
+// ReplaceOne is used to add a model which replaces the first document matching the filter,
+// the _id and the creation time of the stored document are kept if doc does not set them
+func (b *BulkWriter[T]) ReplaceOne(filter any, doc *T) *BulkWriter[T] {
+	b.models = append(b.models, model{modelType: replaceOne, filter: filter, doc: doc})
+	return b
+}
+
+// DeleteOne is used to add a model which deletes the first document matching the filter
+func (b *BulkWriter[T]) DeleteOne(filter any) *BulkWriter[T] {
+	b.models = append(b.models, model{modelType: deleteOne, filter: filter})
+	return b
+}
+
+// DeleteMany is used to add a model which deletes all the documents matching the filter
+func (b *BulkWriter[T]) DeleteMany(filter any) *BulkWriter[T] {
+	b.models = append(b.models, model{modelType: deleteMany, filter: filter})
+	return b
+}
+
+// Ordered is used to set whether the models are executed in order, true by default.
+// An ordered bulk write stops at the first failed model, an unordered one executes all of them.
+func (b *BulkWriter[T]) Ordered(ordered bool) *BulkWriter[T] {
+	b.ordered = &ordered
+	return b
+}
+
+// ChunkSize is used to split the models into several bulk writes of at most size models,
+// the models are sent at once by default.
+func (b *BulkWriter[T]) ChunkSize(size int) *BulkWriter[T] {
+	b.chunkSize = size
+	return b
+}
+
+func (b *BulkWriter[T]) ModelHook(modelHook any) *BulkWriter[T] {
+	b.modelHook = modelHook
+	return b
+}
+
+// Clock is used to set the function which returns the current time of the bulk write, time.Now by default
+func (b *BulkWriter[T]) Clock(clock func() time.Time) *BulkWriter[T] {
+	b.clock = clock
+	return b
+}
+
+// Timeout is used to set the timeout of the bulk write, it is ignored if the context already has a deadline
+func (b *BulkWriter[T]) Timeout(timeout time.Duration) *BulkWriter[T] {
+	b.timeout = timeout
+	return b
+}
+
+// RegisterBeforeHooks is used to set the before hooks of the bulk write,
+// they are executed once after the db callbacks of every model
+func (b *BulkWriter[T]) RegisterBeforeHooks(hooks ...hookFn[T]) *BulkWriter[T] {
+	b.beforeHooks = append(b.beforeHooks, hooks...)
+	return b
+}
+
+func (b *BulkWriter[T]) RegisterAfterHooks(hooks ...hookFn[T]) *BulkWriter[T] {
+	b.afterHooks = append(b.afterHooks, hooks...)
+	return b
+}
+
+// BulkWrite executes the models. Each model runs the same db callbacks as the single operation,
+// e.g. InsertOne runs beforeInsert and afterInsert, UpdateOne and UpdateMany run beforeUpdate and afterUpdate,
+// ReplaceOne runs beforeReplace and afterReplace, DeleteOne and DeleteMany run beforeDelete and afterDelete.
+// The after callbacks are only executed for the models which succeeded, the onError callbacks are executed for the failed ones
+// and for the ones not executed, e.g. the models following the failed one of an ordered bulk.
+// The write errors replaced by the onError callbacks are ignored, they are reported by BulkWriteResult.WriteErrors,
+// but the error which aborts a chunk goes through the onError callbacks of the models of the chunk and may be replaced.
+// If some models fail, the result is returned along with a mongo.BulkWriteException whose indexes are the ones of the models.
+//
+// The callbacks of the models share OpContext.Bulk, and the context set by the before callbacks of a model is passed to
+// the ones of the next model and to the driver, e.g. a tracing callback may record one span for the whole bulk write.
+func (b *BulkWriter[T]) BulkWrite(ctx context.Context, opts ...options.Lister[options.BulkWriteOptions]) (*BulkWriteResult, error) {
+	if len(b.models) == 0 {
+		return nil, mongo.ErrEmptySlice
+	}
+	ctx, cancel := utils.WithTimeout(ctx, b.timeout)
+	defer cancel()
+	currentTime := b.clock()
+
+	if b.ordered != nil {
+		opts = append(opts, options.BulkWrite().SetOrdered(*b.ordered))
+	}
+	ordered := isOrdered(opts)
+
+	bulk := &operation.Bulk{Size: len(b.models)}
+	models := make([]mongo.WriteModel, 0, len(b.models))
+	opContexts := make([]*operation.OpContext, 0, len(b.models))
+	defer func() {
+		for _, opCtx := range opContexts {
+			opCtx.Release()
+		}
+	}()
+	for i, m := range b.models {
+		bulk.Index = i
+		writeModel, globalOpContext, err := b.prepare(ctx, m, bulk, currentTime, opts)
+		opContexts = append(opContexts, globalOpContext)
+		if err != nil {
+			return nil, b.abort(ctx, opContexts, 0, err)
+		}
+		ctx = globalOpContext.Context(ctx)
+		models = append(models, writeModel)
+	}
+
+	opContext := NewOpContext(b.collection, WithModels[T](models), WithMongoOptions[T](opts), WithModelHook[T](b.modelHook), WithFields[T](b.fields), WithStartTime[T](currentTime))
+	for _, beforeHook := range b.beforeHooks {
+		if err := beforeHook(ctx, opContext); err != nil {
+			return nil, b.abort(ctx, opContexts, 0, err)
+		}
+	}
+
+	result := &BulkWriteResult{UpsertedIDs: make(map[int]any), WriteErrors: make(map[int]mongo.BulkWriteError)}
+	var exception *mongo.BulkWriteException
+	// executed is the number of models sent to the server
+	executed := 0
+	for start := 0; start < len(opContext.Models); start += b.size() {
+		end := start + b.size()
+		if end > len(opContext.Models) {
+			end = len(opContext.Models)
+		}
+		chunkResult, err := b.collection.BulkWrite(ctx, opContext.Models[start:end], opts...)
+		executed = end
+
+		var bwe mongo.BulkWriteException
+		if err != nil && !errors.As(err, &bwe) {
+			// the previous chunks have been committed, the failed chunk and the next ones have not
+			duration := b.clock().Sub(currentTime)
+			for _, opCtx := range opContexts {
+				opCtx.Duration = duration
+			}
+			bulk.Err = err
+			cbErr := b.complete(ctx, opContexts[:start], result)
+			err = b.abort(ctx, opContexts, start, err)
+			if cbErr != nil {
+				return nil, cbErr
+			}
+			return nil, err
+		}
+		result.merge(chunkResult, start)
+		if err == nil {
+			continue
+		}
+
+		if exception == nil {
+			exception = &mongo.BulkWriteException{}
+		}
+		for _, writeError := range bwe.WriteErrors {
+			writeError.Index += start
+			result.WriteErrors[writeError.Index] = writeError
+			exception.WriteErrors = append(exception.WriteErrors, writeError)
+		}
+		if bwe.WriteConcernError != nil {
+			exception.WriteConcernError = bwe.WriteConcernError
+		}
+		exception.Labels = append(exception.Labels, bwe.Labels...)
+		if ordered && len(bwe.WriteErrors) > 0 {
+			// the models after the failed one have not been executed
+			executed = bwe.WriteErrors[0].Index + start + 1
+			break
+		}
+	}
+
+	duration := b.clock().Sub(currentTime)
+	for _, opCtx := range opContexts {
+		opCtx.Duration = duration
+	}
+	if exception != nil {
+		bulk.Err = *exception
+	}
+	cbErr := b.complete(ctx, opContexts[:executed], result)
+	for _, opCtx := range opContexts[executed:] {
+		// the model has not been executed because a previous model of the ordered bulk failed
+		bulk.Pending--
+		opCtx.Err = *exception
+		if err := b.dbCallbacks.Execute(ctx, opCtx, operation.OpTypeOnError); err != nil && cbErr == nil {
+			cbErr = err
+		}
+	}
+	if cbErr != nil {
+		return nil, cbErr
+	}
+
+	opContext.Result = result
+	for _, afterHook := range b.afterHooks {
+		if err := afterHook(ctx, opContext); err != nil {
+			return nil, err
+		}
+	}
+
+	if exception != nil {
+		return result, *exception
+	}
+	return result, nil
+}
+
+// complete executes the after callbacks of the executed models which succeeded and the onError callbacks of the failed ones.
+// The callbacks of every model are executed even if the ones of a previous model fail, the first error is returned.
+func (b *BulkWriter[T]) complete(ctx context.Context, opContexts []*operation.OpContext, result *BulkWriteResult) error {
+	var cbErr error
+	for i, opCtx := range opContexts {
+		opCtx.Bulk.Pending--
+		var err error
+		if writeError, ok := result.WriteErrors[i]; ok {
+			opCtx.Err = writeError
+			err = b.dbCallbacks.Execute(ctx, opCtx, operation.OpTypeOnError)
+		} else {
+			opCtx.Result = result
+			err = b.dbCallbacks.Execute(ctx, opCtx, afterOpType(b.models[i].modelType))
+		}
+		if err != nil && cbErr == nil {
+			cbErr = err
+		}
+	}
+	return cbErr
+}
+
+// abort executes the onError callbacks of the models from the index with the error which stopped the bulk write
+// before they were executed, the callbacks may replace the error
+func (b *BulkWriter[T]) abort(ctx context.Context, opContexts []*operation.OpContext, from int, err error) error {
+	opContexts[0].Bulk.Err = err
+	for _, opCtx := range opContexts[from:] {
+		opCtx.Bulk.Pending--
+		err = b.dbCallbacks.OnError(ctx, opCtx, err)
+	}
+	return err
+}
+
+// prepare executes the before callbacks of the model and builds the write model from the rewritten filter and updates,
+// the operation context is returned even if a callback fails
+func (b *BulkWriter[T]) prepare(ctx context.Context, m model, bulk *operation.Bulk, currentTime time.Time, opts any) (mongo.WriteModel, *operation.OpContext, error) {
+	updates := m.updates
+	if m.updates != nil {
+		if updatesM := bsonx.ToBsonM(m.updates); len(updatesM) != 0 {
+			updates = updatesM
+		}
+	}
+
+	globalOpContext := operation.NewOpContext(b.collection, operation.WithFilter(m.filter), operation.WithUpdates(updates), operation.WithMongoOptions(opts), operation.WithModelHook(b.modelHook), operation.WithFields(b.fields), operation.WithStartTime(currentTime), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameBulkWrite), operation.WithMulti(true), operation.WithBulk(bulk), operation.WithLabels(operation.LabelsFromContext(ctx)))
+	// every prepared model runs its after or onError callbacks
+	bulk.Pending++
+	switch {
+	case m.modelType == replaceOne:
+		globalOpContext.Doc = new(T)
+		globalOpContext.Replacement = m.doc
+	case m.doc != nil:
+		globalOpContext.Doc = m.doc
+		globalOpContext.ReflectValue = reflect.ValueOf(m.doc)
+	default:
+		globalOpContext.Doc = new(T)
+	}
+
+	err := b.dbCallbacks.Execute(ctx, globalOpContext, beforeOpType(m.modelType))
+	if err != nil {
+		return nil, globalOpContext, err
+	}
+
+	switch m.modelType {
+	case insertOne:
+		return mongo.NewInsertOneModel().SetDocument(m.doc), globalOpContext, nil
+	case updateOne:
+		return mongo.NewUpdateOneModel().SetFilter(globalOpContext.Filter).SetUpdate(globalOpContext.Updates), globalOpContext, nil
+	case updateMany:
+		return mongo.NewUpdateManyModel().SetFilter(globalOpContext.Filter).SetUpdate(globalOpContext.Updates), globalOpContext, nil
+	case replaceOne:
+		return mongo.NewReplaceOneModel().SetFilter(globalOpContext.Filter).SetReplacement(globalOpContext.Replacement), globalOpContext, nil
+	case deleteOne:
+		if globalOpContext.Updates != nil {
+			// soft delete
+			return mongo.NewUpdateOneModel().SetFilter(globalOpContext.Filter).SetUpdate(globalOpContext.Updates), globalOpContext, nil
+		}
+		return mongo.NewDeleteOneModel().SetFilter(globalOpContext.Filter), globalOpContext, nil
+	default:
+		if globalOpContext.Updates != nil {
+			// soft delete
+			return mongo.NewUpdateManyModel().SetFilter(globalOpContext.Filter).SetUpdate(globalOpContext.Updates), globalOpContext, nil
+		}
+		return mongo.NewDeleteManyModel().SetFilter(globalOpContext.Filter), globalOpContext, nil
+	}
+}
+
+func (b *BulkWriter[T]) size() int {
+	if b.chunkSize <= 0 {
+		return len(b.models)
+	}
+	return b.chunkSize
+}
+
+func (r *BulkWriteResult) merge(result *mongo.BulkWriteResult, offset int) {
+	if result == nil {
+		return
+	}
+	r.InsertedCount += result.InsertedCount
+	r.MatchedCount += result.MatchedCount
+	r.ModifiedCount += result.ModifiedCount
+	r.DeletedCount += result.DeletedCount
+	r.UpsertedCount += result.UpsertedCount
+	for idx, id := range result.UpsertedIDs {
+		r.UpsertedIDs[int(idx)+offset] = id
+	}
+}
+
+// isOrdered reports whether the bulk write is ordered, the last option wins as it does in the driver
+func isOrdered(opts []options.Lister[options.BulkWriteOptions]) bool {
+	args := &options.BulkWriteOptions{}
+	for _, opt := range opts {
+		for _, fn := range opt.List() {
+			_ = fn(args)
+		}
+	}
+	return args.Ordered == nil || *args.Ordered
+}
+
+func beforeOpType(t modelType) operation.OpType {
+	switch t {
+	case insertOne:
+		return operation.OpTypeBeforeInsert
+	case updateOne, updateMany:
+		return operation.OpTypeBeforeUpdate
+	case replaceOne:
+		return operation.OpTypeBeforeReplace
+	default:
+		return operation.OpTypeBeforeDelete
+	}
+}
+
+func afterOpType(t modelType) operation.OpType {
+	switch t {
+	case insertOne:
+		return operation.OpTypeAfterInsert
+	case updateOne, updateMany:
+		return operation.OpTypeAfterUpdate
+	case replaceOne:
+		return operation.OpTypeAfterReplace
+	default:
+		return operation.OpTypeAfterDelete
+	}
+}
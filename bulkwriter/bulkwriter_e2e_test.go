@@ -0,0 +1,160 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build e2e
+
+package bulkwriter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chenmingyong0423/go-mongox/v2/callback"
+	"github.com/chenmingyong0423/go-mongox/v2/field"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+func getCollection(t *testing.T) *mongo.Collection {
+	client, err := mongo.Connect(options.Client().ApplyURI("mongodb://localhost:27017").SetAuth(options.Credential{
+		Username:   "test",
+		Password:   "test",
+		AuthSource: "db-test",
+	}))
+	assert.NoError(t, err)
+	assert.NoError(t, client.Ping(context.Background(), readpref.Primary()))
+
+	return client.Database("db-test").Collection("test_user")
+}
+
+func TestBulkWriter_e2e_BulkWrite(t *testing.T) {
+	collection := getCollection(t)
+	fields := field.ParseFields(TestUser{})
+	defer func() {
+		_, err := collection.DeleteMany(context.Background(), bson.D{})
+		require.NoError(t, err)
+	}()
+
+	t.Run("all models", func(t *testing.T) {
+		defer func() {
+			_, err := collection.DeleteMany(context.Background(), bson.D{})
+			require.NoError(t, err)
+		}()
+		callbacks := callback.InitializeCallbacks()
+		afterInserts := 0
+		callbacks.Register(operation.OpTypeAfterInsert, "count", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+			afterInserts++
+			return nil
+		})
+
+		users := []*TestUser{{Name: "chenmingyong", Age: 24}, {Name: "burt", Age: 25}, {Name: "gopher", Age: 26}}
+		result, err := NewBulkWriter[TestUser](collection, callbacks, fields).
+			InsertOne(users[0]).InsertOne(users[1]).InsertOne(users[2]).
+			UpdateOne(bson.M{"name": "chenmingyong"}, bson.M{"$set": bson.M{"age": 18}}).
+			UpdateMany(bson.M{"age": bson.M{"$gte": 25}}, bson.M{"$inc": bson.M{"age": 1}}).
+			ReplaceOne(bson.M{"name": "burt"}, &TestUser{Name: "burt", Age: 30}).
+			DeleteOne(bson.M{"name": "gopher"}).
+			BulkWrite(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, int64(3), result.InsertedCount)
+		require.Equal(t, int64(5), result.MatchedCount)
+		// the soft delete is an update
+		require.Equal(t, int64(5), result.ModifiedCount)
+		require.Equal(t, 3, afterInserts)
+		for _, user := range users {
+			require.False(t, user.ID.IsZero())
+			require.False(t, user.CreatedAt.IsZero())
+		}
+
+		var found []TestUser
+		cursor, err := collection.Find(context.Background(), bson.M{"deleted_at": nil}, options.Find().SetSort(bson.M{"age": 1}))
+		require.NoError(t, err)
+		require.NoError(t, cursor.All(context.Background(), &found))
+		require.Len(t, found, 2)
+		require.Equal(t, int64(18), found[0].Age)
+		require.Equal(t, int64(30), found[1].Age)
+	})
+
+	t.Run("unordered with chunks", func(t *testing.T) {
+		defer func() {
+			_, err := collection.DeleteMany(context.Background(), bson.D{})
+			require.NoError(t, err)
+		}()
+		id := bson.NewObjectID()
+		_, err := collection.InsertOne(context.Background(), bson.M{"_id": id, "name": "chenmingyong"})
+		require.NoError(t, err)
+
+		callbacks := callback.InitializeCallbacks()
+		callbacks.Remove(operation.OpTypeBeforeInsert, "mongox:fieds")
+		result, err := NewBulkWriter[TestUser](collection, callbacks, fields).Ordered(false).ChunkSize(2).
+			InsertOne(&TestUser{ID: bson.NewObjectID(), Name: "burt"}).
+			InsertOne(&TestUser{ID: bson.NewObjectID(), Name: "gopher"}).
+			InsertOne(&TestUser{ID: id, Name: "chenmingyong"}).
+			InsertOne(&TestUser{ID: bson.NewObjectID(), Name: "mongox"}).
+			BulkWrite(context.Background())
+
+		var bwe mongo.BulkWriteException
+		require.True(t, errors.As(err, &bwe))
+		require.Len(t, bwe.WriteErrors, 1)
+		require.Equal(t, 2, bwe.WriteErrors[0].Index)
+		require.Equal(t, int64(3), result.InsertedCount)
+		require.Len(t, result.WriteErrors, 1)
+		require.True(t, mongo.IsDuplicateKeyError(result.WriteErrors[2]))
+	})
+
+	t.Run("ordered stops at the first error", func(t *testing.T) {
+		defer func() {
+			_, err := collection.DeleteMany(context.Background(), bson.D{})
+			require.NoError(t, err)
+		}()
+		id := bson.NewObjectID()
+		_, err := collection.InsertOne(context.Background(), bson.M{"_id": id, "name": "chenmingyong"})
+		require.NoError(t, err)
+
+		callbacks := callback.InitializeCallbacks()
+		callbacks.Remove(operation.OpTypeBeforeInsert, "mongox:fieds")
+		var afterInserts []string
+		callbacks.Register(operation.OpTypeAfterInsert, "count", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+			afterInserts = append(afterInserts, opCtx.Doc.(*TestUser).Name)
+			return nil
+		})
+		var onErrors []error
+		callbacks.Register(operation.OpTypeOnError, "errors", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+			onErrors = append(onErrors, opCtx.Err)
+			return nil
+		})
+		result, err := NewBulkWriter[TestUser](collection, callbacks, fields).ChunkSize(1).Clock(func() time.Time { return time.Unix(0, 0) }).
+			InsertOne(&TestUser{ID: bson.NewObjectID(), Name: "burt"}).
+			InsertOne(&TestUser{ID: id, Name: "chenmingyong"}).
+			InsertOne(&TestUser{ID: bson.NewObjectID(), Name: "gopher"}).
+			BulkWrite(context.Background())
+		require.Error(t, err)
+		require.Equal(t, int64(1), result.InsertedCount)
+		require.Contains(t, result.WriteErrors, 1)
+		require.Equal(t, []string{"burt"}, afterInserts)
+		// the failed model and the one which has not been executed
+		require.Len(t, onErrors, 2)
+		require.True(t, mongo.IsDuplicateKeyError(onErrors[0]))
+		var bwe mongo.BulkWriteException
+		require.True(t, errors.As(onErrors[1], &bwe))
+		require.Equal(t, 1, bwe.WriteErrors[0].Index)
+	})
+}
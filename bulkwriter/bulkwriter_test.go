@@ -0,0 +1,167 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulkwriter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chenmingyong0423/go-mongox/v2/callback"
+	"github.com/chenmingyong0423/go-mongox/v2/field"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type TestUser struct {
+	ID        bson.ObjectID `bson:"_id,omitempty" mongox:"autoID"`
+	Name      string        `bson:"name"`
+	Age       int64         `bson:"age"`
+	CreatedAt time.Time     `bson:"created_at"`
+	UpdatedAt time.Time     `bson:"updated_at"`
+	DeletedAt time.Time     `bson:"deleted_at,omitempty" mongox:"softDelete"`
+}
+
+func TestNewBulkWriter(t *testing.T) {
+	mongoCollection := &mongo.Collection{}
+	bulkWriter := NewBulkWriter[any](mongoCollection, nil, nil)
+
+	assert.NotNil(t, bulkWriter)
+	assert.Equal(t, mongoCollection, bulkWriter.collection)
+}
+
+func TestBulkWriter_prepare(t *testing.T) {
+	now := time.Now()
+	notDeleted := bson.D{{Key: "deleted_at", Value: nil}}
+	filter := bson.M{"name": "cmy"}
+	merged := bson.D{{Key: "$and", Value: bson.A{filter, notDeleted}}}
+	id := bson.NewObjectID()
+	createdAt := now.Add(-time.Hour)
+
+	testCases := []struct {
+		name  string
+		model model
+
+		want mongo.WriteModel
+	}{
+		{
+			name:  "update one",
+			model: model{modelType: updateOne, filter: filter, updates: bson.M{"$set": bson.M{"age": 24}}},
+			want:  mongo.NewUpdateOneModel().SetFilter(merged).SetUpdate(bson.M{"$set": bson.M{"age": 24, "updated_at": now}}),
+		},
+		{
+			name:  "update many",
+			model: model{modelType: updateMany, filter: filter, updates: bson.D{{Key: "$set", Value: bson.D{{Key: "age", Value: 24}}}}},
+			want:  mongo.NewUpdateManyModel().SetFilter(merged).SetUpdate(bson.M{"$set": bson.M{"age": int32(24), "updated_at": now}}),
+		},
+		{
+			name:  "replace one",
+			model: model{modelType: replaceOne, filter: filter, doc: &TestUser{ID: id, Name: "cmy", CreatedAt: createdAt}},
+			want:  mongo.NewReplaceOneModel().SetFilter(merged).SetReplacement(&TestUser{ID: id, Name: "cmy", CreatedAt: createdAt, UpdatedAt: now}),
+		},
+		{
+			name:  "soft delete one",
+			model: model{modelType: deleteOne, filter: filter},
+			want:  mongo.NewUpdateOneModel().SetFilter(merged).SetUpdate(bson.M{"$set": bson.M{"deleted_at": now}}),
+		},
+		{
+			name:  "soft delete many",
+			model: model{modelType: deleteMany, filter: filter},
+			want:  mongo.NewUpdateManyModel().SetFilter(merged).SetUpdate(bson.M{"$set": bson.M{"deleted_at": now}}),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bulkWriter := NewBulkWriter[TestUser](nil, callback.InitializeCallbacks(), field.ParseFields(TestUser{}))
+			got, _, err := bulkWriter.prepare(context.Background(), tc.model, &operation.Bulk{}, now, nil)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+
+	t.Run("insert one", func(t *testing.T) {
+		bulkWriter := NewBulkWriter[TestUser](nil, callback.InitializeCallbacks(), field.ParseFields(TestUser{}))
+		user := &TestUser{Name: "cmy"}
+		got, _, err := bulkWriter.prepare(context.Background(), model{modelType: insertOne, doc: user}, &operation.Bulk{}, now, nil)
+		require.NoError(t, err)
+		require.Equal(t, mongo.NewInsertOneModel().SetDocument(user), got)
+		require.False(t, user.ID.IsZero())
+		require.Equal(t, now, user.CreatedAt)
+		require.Equal(t, now, user.UpdatedAt)
+	})
+}
+
+func TestBulkWriter_BulkWrite_beforeError(t *testing.T) {
+	callbacks := callback.InitializeCallbacks()
+	errRejected := errors.New("rejected")
+	var contexts []*operation.OpContext
+	require.NoError(t, callbacks.Register(operation.OpTypeBeforeInsert, "reject", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+		contexts = append(contexts, opCtx)
+		if opCtx.Bulk.Index == 1 {
+			return errRejected
+		}
+		return nil
+	}))
+	var pending []int
+	require.NoError(t, callbacks.Register(operation.OpTypeOnError, "errors", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+		require.ErrorIs(t, opCtx.Err, errRejected)
+		require.ErrorIs(t, opCtx.Bulk.Err, errRejected)
+		pending = append(pending, opCtx.Bulk.Pending)
+		return nil
+	}))
+
+	_, err := NewBulkWriter[TestUser](nil, callbacks, field.ParseFields(TestUser{})).
+		InsertOne(&TestUser{Name: "cmy"}).InsertOne(&TestUser{Name: "burt"}).InsertOne(&TestUser{Name: "gopher"}).
+		BulkWrite(context.Background())
+	require.ErrorIs(t, err, errRejected)
+	// the models prepared before the failure and the failed one run the onError callbacks, the last one is not prepared
+	require.Len(t, contexts, 2)
+	require.Same(t, contexts[0].Bulk, contexts[1].Bulk)
+	require.Equal(t, 3, contexts[0].Bulk.Size)
+	require.Equal(t, []int{1, 0}, pending)
+}
+
+func TestIsOrdered(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts []options.Lister[options.BulkWriteOptions]
+		want bool
+	}{
+		{
+			name: "default",
+			want: true,
+		},
+		{
+			name: "unordered",
+			opts: []options.Lister[options.BulkWriteOptions]{options.BulkWrite().SetOrdered(false)},
+			want: false,
+		},
+		{
+			name: "last option wins",
+			opts: []options.Lister[options.BulkWriteOptions]{options.BulkWrite().SetOrdered(false), options.BulkWrite().SetOrdered(true)},
+			want: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, isOrdered(tc.opts))
+		})
+	}
+}
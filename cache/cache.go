@@ -0,0 +1,208 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cache caches the results of the queries of Finder, see Finder.Cache
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// HitKey is the key of the OpContext metadata set to true when the result of the query is read from the cache
+const HitKey = "mongox:cache:hit"
+
+// Cache stores the encoded results of the queries, it must be safe for concurrent use.
+// Each value carries tags, the values are dropped when one of their tags is invalidated,
+// e.g. an adapter for Redis may keep the keys of each tag in a set.
+//
+// The errors of Get and Set are ignored by Finder, which reads from the server instead,
+// the errors of Invalidate are returned by the writes.
+type Cache interface {
+	// Get returns the value of the key, false if it is missing or expired. The value must not be modified.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value of the key for the ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error
+	// Invalidate drops the values carrying one of the tags
+	Invalidate(ctx context.Context, tags ...string) error
+}
+
+// Key returns the key of the result of the query of the operation context, it is built from the canonical
+// Extended JSON of the database, the collection, the operation, the filter and the options shaping the result,
+// e.g. the sort, the skip, the limit and the projection. The keys of the maps are sorted, the ones of bson.D are not.
+func Key(opCtx *operation.OpContext, options bson.D) (string, error) {
+	query := bson.D{
+		{Key: "operation", Value: opCtx.OpName},
+		{Key: "filter", Value: canonical(opCtx.Filter)},
+		{Key: "options", Value: canonical(options)},
+	}
+	b, err := bson.MarshalExtJSON(query, true, false)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return fmt.Sprintf("mongox:%s.%s:%s", opCtx.DatabaseName, opCtx.CollectionName, hex.EncodeToString(sum[:])), nil
+}
+
+// Tags returns the tags of the result of the query of the operation context.
+// The result of a query on a single _id only depends on the document, so it is not dropped by the writes on other documents.
+func Tags(opCtx *operation.OpContext) []string {
+	tags := []string{collectionTag(opCtx)}
+	if id, ok := documentID(opCtx.Filter); ok {
+		return append(tags, documentTag(opCtx, id))
+	}
+	return append(tags, queryTag(opCtx))
+}
+
+// Invalidate drops the results of the queries which the write of the operation context may have changed.
+// The results of the queries on the written documents are dropped if their _id are known, e.g. the write of InsertOne
+// or of UpdateOne with a filter on _id, together with the results of all the queries not on a single _id.
+// All the results of the collection are dropped otherwise.
+func Invalidate(ctx context.Context, c Cache, opCtx *operation.OpContext) error {
+	var ids []any
+	switch r := opCtx.Result.(type) {
+	case *mongo.InsertOneResult:
+		if r != nil {
+			ids = []any{r.InsertedID}
+		}
+	case *mongo.InsertManyResult:
+		if r != nil {
+			ids = r.InsertedIDs
+		}
+	default:
+		if id, ok := documentID(opCtx.Filter); ok && !opCtx.Multi {
+			ids = []any{id}
+		}
+	}
+	if len(ids) == 0 {
+		return c.Invalidate(ctx, collectionTag(opCtx))
+	}
+
+	tags := make([]string, 0, len(ids)+1)
+	tags = append(tags, queryTag(opCtx))
+	for _, id := range ids {
+		tags = append(tags, documentTag(opCtx, id))
+	}
+	return c.Invalidate(ctx, tags...)
+}
+
+func collectionTag(opCtx *operation.OpContext) string {
+	return fmt.Sprintf("mongox:%s.%s", opCtx.DatabaseName, opCtx.CollectionName)
+}
+
+func queryTag(opCtx *operation.OpContext) string {
+	return collectionTag(opCtx) + ":query"
+}
+
+func documentTag(opCtx *operation.OpContext, id any) string {
+	b, err := bson.MarshalExtJSON(bson.D{{Key: "_id", Value: id}}, true, false)
+	if err != nil {
+		// the id was marshaled with the filter or returned by the server, it can not fail
+		return queryTag(opCtx)
+	}
+	return collectionTag(opCtx) + ":document:" + string(b)
+}
+
+// documentID returns the _id the filter is equal to, including in the conditions of $and,
+// e.g. the _id of a filter scoped to the documents not soft-deleted
+func documentID(filter any) (bson.RawValue, bool) {
+	if filter == nil {
+		return bson.RawValue{}, false
+	}
+	doc, err := bson.Marshal(filter)
+	if err != nil {
+		return bson.RawValue{}, false
+	}
+	return rawDocumentID(doc)
+}
+
+func rawDocumentID(doc bson.Raw) (bson.RawValue, bool) {
+	if id, err := doc.LookupErr("_id"); err == nil {
+		if id.Type != bson.TypeEmbeddedDocument {
+			return id, true
+		}
+		// {_id: {$eq: id}}, an _id which is an embedded document is not supported
+		elements, _ := id.Document().Elements()
+		if len(elements) == 1 && elements[0].Key() == "$eq" {
+			return elements[0].Value(), true
+		}
+		return bson.RawValue{}, false
+	}
+	and, err := doc.LookupErr("$and")
+	if err != nil || and.Type != bson.TypeArray {
+		return bson.RawValue{}, false
+	}
+	values, _ := and.Array().Values()
+	for _, value := range values {
+		if value.Type != bson.TypeEmbeddedDocument {
+			continue
+		}
+		if id, ok := rawDocumentID(value.Document()); ok {
+			return id, true
+		}
+	}
+	return bson.RawValue{}, false
+}
+
+// canonical replaces the maps with bson.D sorted by key, so that the same query is always encoded the same way
+func canonical(value any) any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case bson.D:
+		d := make(bson.D, len(v))
+		for i, e := range v {
+			d[i] = bson.E{Key: e.Key, Value: canonical(e.Value)}
+		}
+		return d
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return value
+		}
+		keys := make([]string, 0, rv.Len())
+		for _, k := range rv.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+		d := make(bson.D, 0, len(keys))
+		for _, k := range keys {
+			d = append(d, bson.E{Key: k, Value: canonical(rv.MapIndex(reflect.ValueOf(k).Convert(rv.Type().Key())).Interface())})
+		}
+		return d
+	case reflect.Slice:
+		// the binary values, e.g. bson.Raw, are kept
+		if rv.IsNil() || rv.Type().Elem().Kind() == reflect.Uint8 {
+			return value
+		}
+		a := make(bson.A, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			a[i] = canonical(rv.Index(i).Interface())
+		}
+		return a
+	}
+	return value
+}
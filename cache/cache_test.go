@@ -0,0 +1,169 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func newOpContext(opts ...operation.OpContextOption) *operation.OpContext {
+	opCtx := operation.NewOpContext(nil, opts...)
+	opCtx.DatabaseName = "db-test"
+	opCtx.CollectionName = "users"
+	return opCtx
+}
+
+func TestKey(t *testing.T) {
+	key := func(filter any, options bson.D, opts ...operation.OpContextOption) string {
+		opts = append([]operation.OpContextOption{operation.WithOpName(operation.OpNameFind), operation.WithFilter(filter)}, opts...)
+		k, err := Key(newOpContext(opts...), options)
+		require.NoError(t, err)
+		return k
+	}
+
+	k := key(bson.M{"name": "cmy", "age": bson.M{"$gt": 18, "$lt": 30}}, bson.D{{Key: "limit", Value: 10}})
+	require.Regexp(t, `^mongox:db-test\.users:[0-9a-f]{64}$`, k)
+	// the keys of the maps are sorted
+	for i := 0; i < 10; i++ {
+		require.Equal(t, k, key(map[string]any{"age": map[string]any{"$lt": 30, "$gt": 18}, "name": "cmy"}, bson.D{{Key: "limit", Value: 10}}))
+	}
+	require.Equal(t, k, key(bson.D{{Key: "age", Value: bson.D{{Key: "$gt", Value: 18}, {Key: "$lt", Value: 30}}}, {Key: "name", Value: "cmy"}}, bson.D{{Key: "limit", Value: 10}}))
+
+	require.NotEqual(t, k, key(bson.M{"name": "cmy", "age": bson.M{"$gt": 18, "$lt": 30}}, bson.D{{Key: "limit", Value: 20}}))
+	require.NotEqual(t, k, key(bson.M{"name": "burt", "age": bson.M{"$gt": 18, "$lt": 30}}, bson.D{{Key: "limit", Value: 10}}))
+	require.NotEqual(t, k, key(bson.M{"name": "cmy", "age": bson.M{"$gt": 18, "$lt": 30}}, bson.D{{Key: "limit", Value: 10}}, operation.WithOpName(operation.OpNameCount)))
+	// the order of the keys of bson.D is kept, e.g. the one of the sort
+	require.NotEqual(t, key(nil, bson.D{{Key: "sort", Value: bson.D{{Key: "name", Value: 1}, {Key: "age", Value: 1}}}}),
+		key(nil, bson.D{{Key: "sort", Value: bson.D{{Key: "age", Value: 1}, {Key: "name", Value: 1}}}}))
+
+	_, err := Key(newOpContext(operation.WithFilter(bson.M{"ch": make(chan int)})), nil)
+	require.Error(t, err)
+}
+
+func TestCanonical(t *testing.T) {
+	id := bson.NewObjectID()
+	require.Equal(t,
+		bson.D{{Key: "_id", Value: id}, {Key: "raw", Value: bson.Raw{5, 0, 0, 0, 0}}, {Key: "tags", Value: bson.A{bson.D{{Key: "a", Value: 1}, {Key: "b", Value: 2}}}}},
+		canonical(bson.M{"tags": []bson.M{{"b": 2, "a": 1}}, "raw": bson.Raw{5, 0, 0, 0, 0}, "_id": id}))
+	require.Nil(t, canonical(nil))
+	require.Equal(t, "cmy", canonical("cmy"))
+}
+
+func TestTags(t *testing.T) {
+	id := bson.NewObjectID()
+	documentTag := `mongox:db-test.users:document:{"_id":{"$oid":"` + id.Hex() + `"}}`
+	testCases := []struct {
+		name   string
+		filter any
+		want   []string
+	}{
+		{name: "nil", filter: nil, want: []string{"mongox:db-test.users", "mongox:db-test.users:query"}},
+		{name: "query", filter: bson.M{"name": "cmy"}, want: []string{"mongox:db-test.users", "mongox:db-test.users:query"}},
+		{name: "id", filter: bson.M{"_id": id}, want: []string{"mongox:db-test.users", documentTag}},
+		{name: "id and other conditions", filter: bson.D{{Key: "_id", Value: id}, {Key: "name", Value: "cmy"}}, want: []string{"mongox:db-test.users", documentTag}},
+		{name: "$eq", filter: bson.M{"_id": bson.M{"$eq": id}}, want: []string{"mongox:db-test.users", documentTag}},
+		{name: "$in", filter: bson.M{"_id": bson.M{"$in": bson.A{id}}}, want: []string{"mongox:db-test.users", "mongox:db-test.users:query"}},
+		{
+			name:   "soft delete",
+			filter: bson.D{{Key: "$and", Value: bson.A{bson.M{"_id": id}, bson.D{{Key: "deleted_at", Value: 0}}}}},
+			want:   []string{"mongox:db-test.users", documentTag},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, Tags(newOpContext(operation.WithFilter(tc.filter))))
+		})
+	}
+}
+
+// recorder is a Cache recording the invalidated tags
+type recorder struct {
+	tags []string
+	err  error
+}
+
+func (r *recorder) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, nil
+}
+
+func (r *recorder) Set(context.Context, string, []byte, time.Duration, ...string) error {
+	return nil
+}
+
+func (r *recorder) Invalidate(_ context.Context, tags ...string) error {
+	r.tags = append(r.tags, tags...)
+	return r.err
+}
+
+func TestInvalidate(t *testing.T) {
+	id := bson.NewObjectID()
+	documentTag := func(id string) string {
+		return `mongox:db-test.users:document:{"_id":"` + id + `"}`
+	}
+	testCases := []struct {
+		name  string
+		opCtx *operation.OpContext
+		want  []string
+	}{
+		{
+			name:  "insert one",
+			opCtx: newOpContext(operation.WithResult(&mongo.InsertOneResult{InsertedID: "1"})),
+			want:  []string{"mongox:db-test.users:query", documentTag("1")},
+		},
+		{
+			name:  "insert many",
+			opCtx: newOpContext(operation.WithResult(&mongo.InsertManyResult{InsertedIDs: []any{"1", "2"}}), operation.WithMulti(true)),
+			want:  []string{"mongox:db-test.users:query", documentTag("1"), documentTag("2")},
+		},
+		{
+			name:  "update by id",
+			opCtx: newOpContext(operation.WithFilter(bson.M{"_id": id}), operation.WithResult(&mongo.UpdateResult{MatchedCount: 1})),
+			want:  []string{"mongox:db-test.users:query", `mongox:db-test.users:document:{"_id":{"$oid":"` + id.Hex() + `"}}`},
+		},
+		{
+			name:  "update one",
+			opCtx: newOpContext(operation.WithFilter(bson.M{"name": "cmy"}), operation.WithResult(&mongo.UpdateResult{MatchedCount: 1})),
+			want:  []string{"mongox:db-test.users"},
+		},
+		{
+			name:  "delete many by id",
+			opCtx: newOpContext(operation.WithFilter(bson.M{"_id": id}), operation.WithResult(&mongo.DeleteResult{DeletedCount: 1}), operation.WithMulti(true)),
+			want:  []string{"mongox:db-test.users"},
+		},
+		{
+			name:  "bulk write",
+			opCtx: newOpContext(operation.WithResult(&mongo.BulkWriteResult{InsertedCount: 1}), operation.WithMulti(true)),
+			want:  []string{"mongox:db-test.users"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &recorder{}
+			require.NoError(t, Invalidate(context.Background(), r, tc.opCtx))
+			require.Equal(t, tc.want, r.tags)
+		})
+	}
+
+	wantErr := errors.New("unavailable")
+	require.Equal(t, wantErr, Invalidate(context.Background(), &recorder{err: wantErr}, newOpContext()))
+}
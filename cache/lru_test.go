@@ -0,0 +1,75 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLRU(t *testing.T) {
+	require.Panics(t, func() { NewLRU(0) })
+	require.Equal(t, 0, NewLRU(1).Len())
+}
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewLRU(2)
+	c.now = func() time.Time { return now }
+
+	value, ok, err := c.Get(ctx, "a")
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Nil(t, value)
+
+	require.NoError(t, c.Set(ctx, "a", []byte("1"), time.Minute, "users"))
+	require.NoError(t, c.Set(ctx, "b", []byte("2"), time.Minute, "users", "orders"))
+	value, ok, err = c.Get(ctx, "a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []byte("1"), value)
+
+	// b is the least recently used
+	require.NoError(t, c.Set(ctx, "c", []byte("3"), time.Minute, "orders"))
+	require.Equal(t, 2, c.Len())
+	_, ok, _ = c.Get(ctx, "b")
+	require.False(t, ok)
+	require.NotContains(t, c.tags["users"], "b")
+
+	// the value is replaced with its tags
+	require.NoError(t, c.Set(ctx, "a", []byte("4"), time.Minute, "orders"))
+	value, _, _ = c.Get(ctx, "a")
+	require.Equal(t, []byte("4"), value)
+	require.NotContains(t, c.tags, "users")
+
+	require.NoError(t, c.Invalidate(ctx, "orders", "unknown"))
+	require.Equal(t, 0, c.Len())
+	require.Empty(t, c.tags)
+
+	// the value is expired after the ttl
+	require.NoError(t, c.Set(ctx, "a", []byte("1"), time.Minute))
+	now = now.Add(time.Minute)
+	_, ok, _ = c.Get(ctx, "a")
+	require.False(t, ok)
+	require.Equal(t, 0, c.Len())
+
+	// the values without ttl are not stored
+	require.NoError(t, c.Set(ctx, "a", []byte("1"), 0))
+	require.Equal(t, 0, c.Len())
+}
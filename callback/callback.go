@@ -16,127 +16,296 @@ package callback
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
 
 	"github.com/chenmingyong0423/go-mongox/v2/internal/hook/field"
 
 	"github.com/chenmingyong0423/go-mongox/v2/operation"
 )
 
+const (
+	// FieldsPlugin is the name of the built-in handler which fills the ID, time and version fields of the model
+	FieldsPlugin = "mongox:fieds"
+	// SoftDeletePlugin is the name of the built-in handler which scopes the operations to the documents not soft-deleted
+	SoftDeletePlugin = "mongox:softDelete"
+)
+
 type CbFn func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error
 
+// NewScope returns a registry without handlers, its handlers run after the handlers of the parent.
+// The parent may be nil.
+func NewScope(parent *Callback) *Callback {
+	set := make(handlerSet, len(beforeOpTypes)+len(afterOpTypes)+1)
+	for _, opType := range beforeOpTypes {
+		set[opType] = []callbackHandler{}
+	}
+	for _, opType := range afterOpTypes {
+		set[opType] = []callbackHandler{}
+	}
+	set[operation.OpTypeOnError] = []callbackHandler{}
+	c := &Callback{parent: parent}
+	c.snapshot.Store(set)
+	return c
+}
+
+// InitializeCallbacks returns a registry with the built-in handlers
 func InitializeCallbacks() *Callback {
-	return &Callback{
-		beforeInsert: []callbackHandler{
+	c := &Callback{}
+	c.snapshot.Store(handlerSet{
+		operation.OpTypeBeforeInsert: {
 			{
-				name: "mongox:fieds",
+				name: FieldsPlugin,
 				fn: func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
 					return field.Execute(ctx, opCtx, operation.OpTypeBeforeInsert, opts...)
 				},
 			},
 		},
-		afterInsert: make([]callbackHandler, 0),
-		beforeUpdate: []callbackHandler{
+		operation.OpTypeAfterInsert: {},
+		operation.OpTypeBeforeUpdate: {
 			{
-				name: "mongox:fieds",
+				name: FieldsPlugin,
 				fn: func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
 					return field.Execute(ctx, opCtx, operation.OpTypeBeforeUpdate, opts...)
 				},
 			},
+			{
+				name: SoftDeletePlugin,
+				fn: func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+					return field.SoftDelete(ctx, opCtx, operation.OpTypeBeforeUpdate, opts...)
+				},
+			},
+		},
+		operation.OpTypeAfterUpdate: {},
+		operation.OpTypeBeforeDelete: {
+			{
+				name: SoftDeletePlugin,
+				fn: func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+					return field.SoftDelete(ctx, opCtx, operation.OpTypeBeforeDelete, opts...)
+				},
+			},
 		},
-		afterUpdate:  make([]callbackHandler, 0),
-		beforeDelete: make([]callbackHandler, 0),
-		afterDelete:  make([]callbackHandler, 0),
-		beforeUpsert: []callbackHandler{
+		operation.OpTypeAfterDelete: {},
+		operation.OpTypeBeforeUpsert: {
 			{
-				name: "mongox:fieds",
+				name: FieldsPlugin,
 				fn: func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
 					return field.Execute(ctx, opCtx, operation.OpTypeBeforeUpsert, opts...)
 				},
 			},
+			{
+				name: SoftDeletePlugin,
+				fn: func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+					return field.SoftDelete(ctx, opCtx, operation.OpTypeBeforeUpsert, opts...)
+				},
+			},
 		},
-		afterUpsert: make([]callbackHandler, 0),
-		beforeFind:  make([]callbackHandler, 0),
-		afterFind:   make([]callbackHandler, 0),
-	}
+		operation.OpTypeAfterUpsert: {},
+		operation.OpTypeBeforeFind: {
+			{
+				name: SoftDeletePlugin,
+				fn: func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+					return field.SoftDelete(ctx, opCtx, operation.OpTypeBeforeFind, opts...)
+				},
+			},
+		},
+		operation.OpTypeAfterFind: {},
+		operation.OpTypeBeforeReplace: {
+			// the filter is scoped first, the fields of the replacement are read from the stored document matching it
+			{
+				name: SoftDeletePlugin,
+				fn: func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+					return field.SoftDelete(ctx, opCtx, operation.OpTypeBeforeReplace, opts...)
+				},
+			},
+			{
+				name: FieldsPlugin,
+				fn: func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+					return field.Execute(ctx, opCtx, operation.OpTypeBeforeReplace, opts...)
+				},
+			},
+		},
+		operation.OpTypeAfterReplace: {},
+		operation.OpTypeBeforeAggregate: {
+			{
+				name: SoftDeletePlugin,
+				fn: func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+					return field.SoftDelete(ctx, opCtx, operation.OpTypeBeforeAggregate, opts...)
+				},
+			},
+		},
+		operation.OpTypeAfterAggregate: {},
+		operation.OpTypeBeforeCount: {
+			{
+				name: SoftDeletePlugin,
+				fn: func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+					return field.SoftDelete(ctx, opCtx, operation.OpTypeBeforeCount, opts...)
+				},
+			},
+		},
+		operation.OpTypeAfterCount: {},
+		operation.OpTypeBeforeDistinct: {
+			{
+				name: SoftDeletePlugin,
+				fn: func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+					return field.SoftDelete(ctx, opCtx, operation.OpTypeBeforeDistinct, opts...)
+				},
+			},
+		},
+		operation.OpTypeAfterDistinct: {},
+		operation.OpTypeOnError:       {},
+	})
+	return c
 }
 
+// Callback is the registry of the handlers executed before and after the operations,
+// it is safe to change the handlers while the operations are running
 type Callback struct {
-	beforeInsert []callbackHandler
-	afterInsert  []callbackHandler
-	beforeUpdate []callbackHandler
-	afterUpdate  []callbackHandler
-	beforeDelete []callbackHandler
-	afterDelete  []callbackHandler
-	beforeUpsert []callbackHandler
-	afterUpsert  []callbackHandler
-	beforeFind   []callbackHandler
-	afterFind    []callbackHandler
+	// mu serializes the changes of the handlers
+	mu sync.Mutex
+	// snapshot holds the current handlerSet, a handlerSet is never modified once stored,
+	// so that Execute reads it without locking and the running operations keep the handlers they started with
+	snapshot atomic.Value
+	// seq is the number of registered handlers, it keeps the registration order among the handlers without constraints
+	seq int
+	// parent is the enclosing scope, e.g. the client of a database, its handlers run first
+	parent *Callback
+}
+
+// Inherit sets the enclosing scope whose handlers run before the handlers of the registry,
+// it must be called before the registry is used
+func (c *Callback) Inherit(parent *Callback) *Callback {
+	c.parent = parent
+	return c
+}
+
+// handlerSet maps every operation type to its handlers in the execution order
+type handlerSet map[operation.OpType][]callbackHandler
+
+func (c *Callback) load() handlerSet {
+	set, _ := c.snapshot.Load().(handlerSet)
+	return set
 }
 
 func (c *Callback) BeforeInsert() []callbackHandler {
-	return c.beforeInsert
+	return c.load()[operation.OpTypeBeforeInsert]
 }
 
 func (c *Callback) AfterInsert() []callbackHandler {
-	return c.afterInsert
+	return c.load()[operation.OpTypeAfterInsert]
 }
 
 func (c *Callback) BeforeUpdate() []callbackHandler {
-	return c.beforeUpdate
+	return c.load()[operation.OpTypeBeforeUpdate]
 }
 
 func (c *Callback) AfterUpdate() []callbackHandler {
-	return c.afterUpdate
+	return c.load()[operation.OpTypeAfterUpdate]
 }
 
 func (c *Callback) BeforeDelete() []callbackHandler {
-	return c.beforeDelete
+	return c.load()[operation.OpTypeBeforeDelete]
 }
 
 func (c *Callback) AfterDelete() []callbackHandler {
-	return c.afterDelete
+	return c.load()[operation.OpTypeAfterDelete]
 }
 
 func (c *Callback) BeforeUpsert() []callbackHandler {
-	return c.beforeUpsert
+	return c.load()[operation.OpTypeBeforeUpsert]
 }
 
 func (c *Callback) AfterUpsert() []callbackHandler {
-	return c.afterUpsert
+	return c.load()[operation.OpTypeAfterUpsert]
 }
 
 func (c *Callback) BeforeFind() []callbackHandler {
-	return c.beforeFind
+	return c.load()[operation.OpTypeBeforeFind]
 }
 
 func (c *Callback) AfterFind() []callbackHandler {
-	return c.afterFind
+	return c.load()[operation.OpTypeAfterFind]
+}
+
+func (c *Callback) BeforeReplace() []callbackHandler {
+	return c.load()[operation.OpTypeBeforeReplace]
+}
+
+func (c *Callback) AfterReplace() []callbackHandler {
+	return c.load()[operation.OpTypeAfterReplace]
 }
 
+func (c *Callback) BeforeAggregate() []callbackHandler {
+	return c.load()[operation.OpTypeBeforeAggregate]
+}
+
+func (c *Callback) AfterAggregate() []callbackHandler {
+	return c.load()[operation.OpTypeAfterAggregate]
+}
+
+func (c *Callback) BeforeCount() []callbackHandler {
+	return c.load()[operation.OpTypeBeforeCount]
+}
+
+func (c *Callback) AfterCount() []callbackHandler {
+	return c.load()[operation.OpTypeAfterCount]
+}
+
+func (c *Callback) BeforeDistinct() []callbackHandler {
+	return c.load()[operation.OpTypeBeforeDistinct]
+}
+
+func (c *Callback) AfterDistinct() []callbackHandler {
+	return c.load()[operation.OpTypeAfterDistinct]
+}
+
+// PluginInfo describes a registered handler
+type PluginInfo struct {
+	Name     string
+	Before   []string
+	After    []string
+	Priority int
+}
+
+// Plugins returns the handlers registered in the registry for every operation type in the execution order,
+// the handlers of the enclosing scopes are not included
+func (c *Callback) Plugins() map[operation.OpType][]PluginInfo {
+	set := c.load()
+	plugins := make(map[operation.OpType][]PluginInfo, len(set))
+	for opType, handlers := range set {
+		infos := make([]PluginInfo, 0, len(handlers))
+		for _, handler := range handlers {
+			infos = append(infos, PluginInfo{
+				Name:     handler.name,
+				Before:   append([]string(nil), handler.before...),
+				After:    append([]string(nil), handler.after...),
+				Priority: handler.priority,
+			})
+		}
+		plugins[opType] = infos
+	}
+	return plugins
+}
+
+// Execute runs the handlers of the enclosing scopes and then the handlers of the registry
 func (c *Callback) Execute(ctx context.Context, opCtx *operation.OpContext, opType operation.OpType, opts ...any) error {
-	switch opType {
-	case operation.OpTypeBeforeInsert:
-		return c.execute(ctx, opCtx, c.beforeInsert, opts...)
-	case operation.OpTypeAfterInsert:
-		return c.execute(ctx, opCtx, c.afterInsert, opts...)
-	case operation.OpTypeBeforeUpdate:
-		return c.execute(ctx, opCtx, c.beforeUpdate, opts...)
-	case operation.OpTypeAfterUpdate:
-		return c.execute(ctx, opCtx, c.afterUpdate, opts...)
-	case operation.OpTypeBeforeDelete:
-		return c.execute(ctx, opCtx, c.beforeDelete, opts...)
-	case operation.OpTypeAfterDelete:
-		return c.execute(ctx, opCtx, c.afterDelete, opts...)
-	case operation.OpTypeBeforeUpsert:
-		return c.execute(ctx, opCtx, c.beforeUpsert, opts...)
-	case operation.OpTypeAfterUpsert:
-		return c.execute(ctx, opCtx, c.afterUpsert, opts...)
-	case operation.OpTypeBeforeFind:
-		return c.execute(ctx, opCtx, c.beforeFind, opts...)
-	case operation.OpTypeAfterFind:
-		return c.execute(ctx, opCtx, c.afterFind, opts...)
+	if c.parent != nil {
+		if err := c.parent.Execute(ctx, opCtx, opType, opts...); err != nil {
+			return err
+		}
 	}
-	return nil
+	return c.execute(ctx, opCtx, c.load()[opType], opts...)
+}
+
+// OnError runs the OpTypeOnError handlers with the error of the operation and returns the error the operator must return,
+// which is opCtx.Err unless a handler fails. The handlers may replace opCtx.Err, e.g. to map a driver error to a domain error.
+func (c *Callback) OnError(ctx context.Context, opCtx *operation.OpContext, err error, opts ...any) error {
+	opCtx.Err = err
+	if cbErr := c.Execute(ctx, opCtx, operation.OpTypeOnError, opts...); cbErr != nil {
+		return cbErr
+	}
+	return opCtx.Err
 }
 
 func (c *Callback) execute(ctx context.Context, opCtx *operation.OpContext, handlers []callbackHandler, opts ...any) error {
@@ -148,151 +317,138 @@ func (c *Callback) execute(ctx context.Context, opCtx *operation.OpContext, hand
 	return nil
 }
 
-func (c *Callback) Register(opType operation.OpType, name string, fn CbFn) {
-	switch opType {
-	case operation.OpTypeBeforeInsert:
-		c.beforeInsert = append(c.beforeInsert, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
-	case operation.OpTypeAfterInsert:
-		c.afterInsert = append(c.afterInsert, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
-	case operation.OpTypeBeforeUpdate:
-		c.beforeUpdate = append(c.beforeUpdate, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
-	case operation.OpTypeAfterUpdate:
-		c.afterUpdate = append(c.afterUpdate, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
-	case operation.OpTypeBeforeDelete:
-		c.beforeDelete = append(c.beforeDelete, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
-	case operation.OpTypeAfterDelete:
-		c.afterDelete = append(c.afterDelete, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
-	case operation.OpTypeBeforeUpsert:
-		c.beforeUpsert = append(c.beforeUpsert, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
-	case operation.OpTypeAfterUpsert:
-		c.afterUpsert = append(c.afterUpsert, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
-	case operation.OpTypeBeforeFind:
-		c.beforeFind = append(c.beforeFind, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
-	case operation.OpTypeAfterFind:
-		c.afterFind = append(c.afterFind, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
-	case operation.OpTypeBeforeAny:
-		c.beforeInsert = append(c.beforeInsert, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
-		c.beforeUpdate = append(c.beforeUpdate, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
-		c.beforeDelete = append(c.beforeDelete, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
-		c.beforeUpsert = append(c.beforeUpsert, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
-		c.beforeFind = append(c.beforeFind, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
-	case operation.OpTypeAfterAny:
-		c.afterInsert = append(c.afterInsert, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
-		c.afterUpdate = append(c.afterUpdate, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
-		c.afterDelete = append(c.afterDelete, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
-		c.afterUpsert = append(c.afterUpsert, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
-		c.afterFind = append(c.afterFind, callbackHandler{
-			name: name,
-			fn:   fn,
-		})
+// Register adds the handler to the operation type, OpTypeBeforeAny and OpTypeAfterAny register it for every operation type.
+// The handlers are ordered by the Before and After constraints, then by the priority, then by the registration order,
+// ErrPluginCycle is returned and nothing is registered if the constraints can not be satisfied.
+func (c *Callback) Register(opType operation.OpType, name string, fn CbFn, opts ...RegisterOption) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	handler := callbackHandler{
+		name: name,
+		fn:   fn,
+		seq:  c.seq,
+	}
+	for _, opt := range opts {
+		opt(&handler)
 	}
+
+	return c.update(opType, func(handlers []callbackHandler) ([]callbackHandler, bool) {
+		return append(handlers, handler), true
+	})
 }
 
-func (c *Callback) Remove(opType operation.OpType, name string) {
-	switch opType {
-	case operation.OpTypeBeforeInsert:
-		c.beforeInsert = c.remove(c.beforeInsert, name)
-	case operation.OpTypeAfterInsert:
-		c.afterInsert = c.remove(c.afterInsert, name)
-	case operation.OpTypeBeforeUpdate:
-		c.beforeUpdate = c.remove(c.beforeUpdate, name)
-	case operation.OpTypeAfterUpdate:
-		c.afterUpdate = c.remove(c.afterUpdate, name)
-	case operation.OpTypeBeforeDelete:
-		c.beforeDelete = c.remove(c.beforeDelete, name)
-	case operation.OpTypeAfterDelete:
-		c.afterDelete = c.remove(c.afterDelete, name)
-	case operation.OpTypeBeforeUpsert:
-		c.beforeUpsert = c.remove(c.beforeUpsert, name)
-	case operation.OpTypeAfterUpsert:
-		c.afterUpsert = c.remove(c.afterUpsert, name)
-	case operation.OpTypeBeforeFind:
-		c.beforeFind = c.remove(c.beforeFind, name)
-	case operation.OpTypeAfterFind:
-		c.afterFind = c.remove(c.afterFind, name)
-	case operation.OpTypeBeforeAny:
-		c.beforeInsert = c.remove(c.beforeInsert, name)
-		c.beforeUpdate = c.remove(c.beforeUpdate, name)
-		c.beforeDelete = c.remove(c.beforeDelete, name)
-		c.beforeUpsert = c.remove(c.beforeUpsert, name)
-		c.beforeFind = c.remove(c.beforeFind, name)
-	case operation.OpTypeAfterAny:
-		c.afterInsert = c.remove(c.afterInsert, name)
-		c.afterUpdate = c.remove(c.afterUpdate, name)
-		c.afterDelete = c.remove(c.afterDelete, name)
-		c.afterUpsert = c.remove(c.afterUpsert, name)
-		c.afterFind = c.remove(c.afterFind, name)
+// Replace replaces the function of the handler registered with the name, the handler keeps its position.
+// The ordering constraints of the handler are replaced as well if options are given.
+// ErrPluginNotFound is returned if no handler is registered with the name.
+func (c *Callback) Replace(opType operation.OpType, name string, fn CbFn, opts ...RegisterOption) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	found := false
+	err := c.update(opType, func(handlers []callbackHandler) ([]callbackHandler, bool) {
+		replaced := false
+		for i := range handlers {
+			if handlers[i].name != name {
+				continue
+			}
+			handlers[i].fn = fn
+			if len(opts) > 0 {
+				handlers[i].before, handlers[i].after, handlers[i].priority = nil, nil, 0
+				for _, opt := range opts {
+					opt(&handlers[i])
+				}
+			}
+			replaced = true
+		}
+		found = found || replaced
+		return handlers, replaced
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", ErrPluginNotFound, name)
 	}
+	return nil
 }
 
-func (c *Callback) remove(callbackHandlers []callbackHandler, name string) []callbackHandler {
-	for i, handler := range callbackHandlers {
-		if handler.name == name {
-			callbackHandlers = append(callbackHandlers[:i], callbackHandlers[i+1:]...)
-			break
+func (c *Callback) Remove(opType operation.OpType, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.update(opType, func(handlers []callbackHandler) ([]callbackHandler, bool) {
+		for i, handler := range handlers {
+			if handler.name == name {
+				return append(handlers[:i], handlers[i+1:]...), true
+			}
+		}
+		return handlers, false
+	})
+}
+
+// update applies fn to a copy of the handlers of every operation type and sorts the changed ones,
+// a new snapshot is stored only if all of them can be sorted. The caller must hold c.mu.
+func (c *Callback) update(opType operation.OpType, fn func(handlers []callbackHandler) ([]callbackHandler, bool)) error {
+	current := c.load()
+	updated := make(handlerSet, len(current))
+	for t, handlers := range current {
+		updated[t] = handlers
+	}
+	changed := false
+	for _, t := range expandOpType(opType) {
+		handlers, ok := current[t]
+		if !ok {
+			continue
+		}
+		handlers, ok = fn(append([]callbackHandler(nil), handlers...))
+		if !ok {
+			continue
 		}
+		sorted, err := sortHandlers(handlers)
+		if err != nil {
+			return fmt.Errorf("%w (%s)", err, t)
+		}
+		updated[t] = sorted
+		changed = true
+	}
+	if changed {
+		c.snapshot.Store(updated)
+	}
+	return nil
+}
+
+var (
+	beforeOpTypes = []operation.OpType{
+		operation.OpTypeBeforeInsert, operation.OpTypeBeforeUpdate, operation.OpTypeBeforeDelete,
+		operation.OpTypeBeforeUpsert, operation.OpTypeBeforeFind, operation.OpTypeBeforeReplace,
+		operation.OpTypeBeforeAggregate, operation.OpTypeBeforeCount, operation.OpTypeBeforeDistinct,
+	}
+	afterOpTypes = []operation.OpType{
+		operation.OpTypeAfterInsert, operation.OpTypeAfterUpdate, operation.OpTypeAfterDelete,
+		operation.OpTypeAfterUpsert, operation.OpTypeAfterFind, operation.OpTypeAfterReplace,
+		operation.OpTypeAfterAggregate, operation.OpTypeAfterCount, operation.OpTypeAfterDistinct,
 	}
-	return callbackHandlers
+)
+
+// expandOpType returns the operation types covered by the operation type
+func expandOpType(opType operation.OpType) []operation.OpType {
+	switch opType {
+	case operation.OpTypeBeforeAny:
+		return beforeOpTypes
+	case operation.OpTypeAfterAny:
+		return afterOpTypes
+	}
+	return []operation.OpType{opType}
 }
 
 type callbackHandler struct {
 	name string
 	fn   CbFn
+
+	// names of the handlers which must run after this one
+	before []string
+	// names of the handlers which must run before this one
+	after    []string
+	priority int
+	// registration order, the built-in handlers are 0
+	seq int
 }
@@ -0,0 +1,131 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package callback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallback_ConcurrentChanges(t *testing.T) {
+	callbacks := InitializeCallbacks()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("plugin-%d", i)
+			require.NoError(t, callbacks.Register(operation.OpTypeBeforeAny, name, func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+				return nil
+			}, After(FieldsPlugin)))
+			callbacks.Remove(operation.OpTypeBeforeAny, name)
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				require.NoError(t, callbacks.Execute(context.Background(), operation.NewOpContext(nil), operation.OpTypeBeforeFind))
+				_ = callbacks.Plugins()
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, []string{SoftDeletePlugin}, handlerNames(callbacks.BeforeFind()))
+}
+
+func TestCallback_Snapshot(t *testing.T) {
+	callbacks := InitializeCallbacks()
+	var names []string
+	require.NoError(t, callbacks.Register(operation.OpTypeBeforeFind, "a", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+		names = append(names, "a")
+		// the running operation keeps the handlers it started with
+		callbacks.Remove(operation.OpTypeBeforeFind, "b")
+		return nil
+	}))
+	require.NoError(t, callbacks.Register(operation.OpTypeBeforeFind, "b", recorder(&names, "b")))
+
+	require.NoError(t, callbacks.Execute(context.Background(), operation.NewOpContext(nil), operation.OpTypeBeforeFind))
+	require.Equal(t, []string{"a", "b"}, names)
+
+	names = nil
+	require.NoError(t, callbacks.Execute(context.Background(), operation.NewOpContext(nil), operation.OpTypeBeforeFind))
+	require.Equal(t, []string{"a"}, names)
+}
+
+func TestCallback_Plugins(t *testing.T) {
+	callbacks := InitializeCallbacks()
+	require.NoError(t, callbacks.Register(operation.OpTypeBeforeFind, "tenancy", recorder(new([]string), "tenancy"), Before(SoftDeletePlugin), Priority(1)))
+
+	plugins := callbacks.Plugins()
+	require.Len(t, plugins, 19)
+	require.Equal(t, []PluginInfo{
+		{Name: "tenancy", Before: []string{SoftDeletePlugin}, Priority: 1},
+		{Name: SoftDeletePlugin},
+	}, plugins[operation.OpTypeBeforeFind])
+	require.Empty(t, plugins[operation.OpTypeAfterFind])
+
+	// the result is a copy
+	plugins[operation.OpTypeBeforeFind][0].Before[0] = "changed"
+	require.Equal(t, []string{SoftDeletePlugin}, callbacks.Plugins()[operation.OpTypeBeforeFind][0].Before)
+}
+
+func TestCallback_OnError(t *testing.T) {
+	errDuplicated := errors.New("user already exists")
+	driverErr := errors.New("E11000 duplicate key error")
+	testCases := []struct {
+		name    string
+		fn      CbFn
+		wantErr error
+	}{
+		{
+			name: "no change",
+			fn: func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+				return nil
+			},
+			wantErr: driverErr,
+		},
+		{
+			name: "replace the error",
+			fn: func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+				if opCtx.Err == driverErr {
+					opCtx.Err = errDuplicated
+				}
+				return nil
+			},
+			wantErr: errDuplicated,
+		},
+		{
+			name: "handler fails",
+			fn: func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+				return errors.New("handler error")
+			},
+			wantErr: errors.New("handler error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			callbacks := NewScope(InitializeCallbacks())
+			require.NoError(t, callbacks.Register(operation.OpTypeOnError, "mapper", tc.fn))
+			opCtx := operation.NewOpContext(nil)
+			err := callbacks.OnError(context.Background(), opCtx, driverErr)
+			require.Equal(t, tc.wantErr, err)
+		})
+	}
+}
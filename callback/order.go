@@ -0,0 +1,127 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package callback
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrPluginCycle is returned when the Before and After constraints of the handlers form a cycle
+	ErrPluginCycle = errors.New("mongox: plugin ordering has a cycle")
+	// ErrPluginNotFound is returned when replacing a handler which is not registered
+	ErrPluginNotFound = errors.New("mongox: plugin not found")
+)
+
+// RegisterOption is used to set the position of a handler among the handlers of the same operation type
+type RegisterOption func(handler *callbackHandler)
+
+// Before makes the handler run before the handlers with the names, names which are not registered are ignored
+func Before(names ...string) RegisterOption {
+	return func(handler *callbackHandler) {
+		handler.before = append(handler.before, names...)
+	}
+}
+
+// After makes the handler run after the handlers with the names, names which are not registered are ignored
+func After(names ...string) RegisterOption {
+	return func(handler *callbackHandler) {
+		handler.after = append(handler.after, names...)
+	}
+}
+
+// Priority sets the priority of the handler, 0 by default.
+// The handlers with a higher priority run first unless the Before and After constraints say otherwise.
+func Priority(priority int) RegisterOption {
+	return func(handler *callbackHandler) {
+		handler.priority = priority
+	}
+}
+
+// sortHandlers orders the handlers by the Before and After constraints, the other handlers keep the order
+// of their priority and then of their registration. A handler is emitted as soon as the handlers which
+// must run before it are emitted, so the constraints move as few handlers as possible.
+func sortHandlers(handlers []callbackHandler) ([]callbackHandler, error) {
+	sort.SliceStable(handlers, func(i, j int) bool {
+		if handlers[i].priority != handlers[j].priority {
+			return handlers[i].priority > handlers[j].priority
+		}
+		return handlers[i].seq < handlers[j].seq
+	})
+
+	indexes := make(map[string][]int, len(handlers))
+	for i, handler := range handlers {
+		indexes[handler.name] = append(indexes[handler.name], i)
+	}
+	// predecessors[i] are the handlers which must run before handlers[i]
+	predecessors := make([][]int, len(handlers))
+	for i, handler := range handlers {
+		for _, name := range handler.before {
+			for _, j := range indexes[name] {
+				if j != i {
+					predecessors[j] = append(predecessors[j], i)
+				}
+			}
+		}
+		for _, name := range handler.after {
+			for _, j := range indexes[name] {
+				if j != i {
+					predecessors[i] = append(predecessors[i], j)
+				}
+			}
+		}
+	}
+	for i := range predecessors {
+		sort.Ints(predecessors[i])
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make([]int, len(handlers))
+	sorted := make([]callbackHandler, 0, len(handlers))
+	var path []string
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch states[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %s -> %s", ErrPluginCycle, strings.Join(path, " -> "), handlers[i].name)
+		}
+		states[i] = visiting
+		path = append(path, handlers[i].name)
+		for _, j := range predecessors[i] {
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		states[i] = visited
+		sorted = append(sorted, handlers[i])
+		return nil
+	}
+	for i := range handlers {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package callback
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"github.com/stretchr/testify/require"
+)
+
+func recorder(names *[]string, name string) CbFn {
+	return func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+		*names = append(*names, name)
+		return nil
+	}
+}
+
+func handlerNames(handlers []callbackHandler) []string {
+	names := make([]string, 0, len(handlers))
+	for _, handler := range handlers {
+		names = append(names, handler.name)
+	}
+	return names
+}
+
+func TestCallback_Register_Order(t *testing.T) {
+	type registration struct {
+		name string
+		opts []RegisterOption
+	}
+	testCases := []struct {
+		name          string
+		registrations []registration
+		want          []string
+		wantErr       error
+	}{
+		{
+			name:          "registration order",
+			registrations: []registration{{name: "a"}, {name: "b"}},
+			want:          []string{FieldsPlugin, "a", "b"},
+		},
+		{
+			name:          "before a built-in plugin",
+			registrations: []registration{{name: "a"}, {name: "b", opts: []RegisterOption{Before(FieldsPlugin)}}},
+			want:          []string{"b", FieldsPlugin, "a"},
+		},
+		{
+			name: "after a plugin registered later",
+			registrations: []registration{
+				{name: "cache", opts: []RegisterOption{After("tenancy")}},
+				{name: "tenancy"},
+			},
+			want: []string{FieldsPlugin, "tenancy", "cache"},
+		},
+		{
+			name: "priority",
+			registrations: []registration{
+				{name: "low", opts: []RegisterOption{Priority(-1)}},
+				{name: "high", opts: []RegisterOption{Priority(10)}},
+				{name: "default"},
+			},
+			want: []string{"high", FieldsPlugin, "default", "low"},
+		},
+		{
+			name: "constraints take precedence over priority",
+			registrations: []registration{
+				{name: "a", opts: []RegisterOption{Priority(10), After("b")}},
+				{name: "b"},
+			},
+			// b is pulled forward to run before a
+			want: []string{"b", "a", FieldsPlugin},
+		},
+		{
+			name: "cycle",
+			registrations: []registration{
+				{name: "a", opts: []RegisterOption{Before("b")}},
+				{name: "b", opts: []RegisterOption{Before("a")}},
+			},
+			want:    []string{FieldsPlugin, "a"},
+			wantErr: ErrPluginCycle,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			callbacks := InitializeCallbacks()
+			var err error
+			for _, r := range tc.registrations {
+				if err = callbacks.Register(operation.OpTypeBeforeInsert, r.name, recorder(new([]string), r.name), r.opts...); err != nil {
+					break
+				}
+			}
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.want, handlerNames(callbacks.BeforeInsert()))
+		})
+	}
+}
+
+func TestCallback_Register_Any(t *testing.T) {
+	callbacks := InitializeCallbacks()
+	require.NoError(t, callbacks.Register(operation.OpTypeBeforeAny, "tenancy", recorder(new([]string), "tenancy"), Before(SoftDeletePlugin)))
+	require.Equal(t, []string{FieldsPlugin, "tenancy", SoftDeletePlugin}, handlerNames(callbacks.BeforeUpdate()))
+	require.Equal(t, []string{"tenancy", SoftDeletePlugin}, handlerNames(callbacks.BeforeCount()))
+
+	// a cycle registers the handler for none of the operation types
+	err := callbacks.Register(operation.OpTypeBeforeAny, "cache", recorder(new([]string), "cache"), Before("tenancy"), After(SoftDeletePlugin))
+	require.ErrorIs(t, err, ErrPluginCycle)
+	require.Equal(t, []string{FieldsPlugin, "tenancy", SoftDeletePlugin}, handlerNames(callbacks.BeforeUpdate()))
+	require.Equal(t, []string{"tenancy", SoftDeletePlugin}, handlerNames(callbacks.BeforeCount()))
+}
+
+func TestCallback_Replace(t *testing.T) {
+	callbacks := InitializeCallbacks()
+	var names []string
+	require.NoError(t, callbacks.Register(operation.OpTypeBeforeInsert, "a", recorder(&names, "a")))
+	require.NoError(t, callbacks.Replace(operation.OpTypeBeforeInsert, FieldsPlugin, recorder(&names, "fields")))
+
+	require.NoError(t, callbacks.Execute(context.Background(), operation.NewOpContext(nil), operation.OpTypeBeforeInsert))
+	require.Equal(t, []string{"fields", "a"}, names)
+
+	names = nil
+	require.NoError(t, callbacks.Replace(operation.OpTypeBeforeInsert, FieldsPlugin, recorder(&names, "fields"), After("a")))
+	require.NoError(t, callbacks.Execute(context.Background(), operation.NewOpContext(nil), operation.OpTypeBeforeInsert))
+	require.Equal(t, []string{"a", "fields"}, names)
+
+	err := callbacks.Replace(operation.OpTypeBeforeInsert, "unknown", recorder(&names, "unknown"))
+	require.True(t, errors.Is(err, ErrPluginNotFound))
+}
+
+func TestCallback_Remove_Any(t *testing.T) {
+	callbacks := InitializeCallbacks()
+	callbacks.Remove(operation.OpTypeBeforeAny, SoftDeletePlugin)
+	for _, opType := range beforeOpTypes {
+		require.NotContains(t, handlerNames(callbacks.load()[opType]), SoftDeletePlugin, opType)
+	}
+	require.Equal(t, []string{FieldsPlugin}, handlerNames(callbacks.BeforeReplace()))
+}
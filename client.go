@@ -17,21 +17,64 @@ package mongox
 import (
 	"context"
 
+	"github.com/chenmingyong0423/go-mongox/v2/callback"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"github.com/chenmingyong0423/go-mongox/v2/watcher"
+	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
 type Client struct {
 	client *mongo.Client
 	cfg    *Config
+	// callbacks inherited by all the databases of the client
+	callbacks *callback.Callback
+	// commits holds the functions to run once the transactions of the sessions started by the client are over
+	commits *commitHooks
 }
 
 func NewClient(client *mongo.Client, config *Config) *Client {
+	if config == nil {
+		config = &Config{}
+	}
 	return &Client{
-		client: client,
-		cfg:    config,
+		client:    client,
+		cfg:       config,
+		callbacks: callback.NewScope(nil),
+		commits:   newCommitHooks(),
 	}
 }
 
+// Connect connects to the MongoDB deployment of the uri and verifies the connection.
+// The read concern, write concern and read preference of the config are applied to the mongo client,
+// opts are applied after them and take precedence.
+func Connect(ctx context.Context, uri string, config *Config, opts ...*options.ClientOptions) (*Client, error) {
+	if config == nil {
+		config = &Config{}
+	}
+	clientOpts := options.Client().ApplyURI(uri)
+	if config.ReadConcern != nil {
+		clientOpts.SetReadConcern(config.ReadConcern)
+	}
+	if config.WriteConcern != nil {
+		clientOpts.SetWriteConcern(config.WriteConcern)
+	}
+	if config.ReadPreference != nil {
+		clientOpts.SetReadPreference(config.ReadPreference)
+	}
+
+	client, err := mongo.Connect(append([]*options.ClientOptions{clientOpts}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	if err = client.Ping(ctx, nil); err != nil {
+		_ = client.Disconnect(ctx)
+		return nil, err
+	}
+	return NewClient(client, config), nil
+}
+
 // Client returns the mongo client
 func (c *Client) Client() *mongo.Client {
 	return c.client
@@ -48,3 +91,62 @@ func (c *Client) Disconnect(ctx context.Context) error {
 func (c *Client) NewDatabase(database string) *Database {
 	return newDatabase(c, database)
 }
+
+// RegisterPlugin registers the plugin for all the databases of the client, it runs before the plugins of the databases
+func (c *Client) RegisterPlugin(name string, cb callback.CbFn, opType operation.OpType, opts ...callback.RegisterOption) error {
+	return c.callbacks.Register(opType, name, cb, opts...)
+}
+
+func (c *Client) RemovePlugin(name string, opType operation.OpType) {
+	c.callbacks.Remove(opType, name)
+}
+
+// Plugins returns the plugins registered on the client for every operation type in the execution order
+func (c *Client) Plugins() map[operation.OpType][]callback.PluginInfo {
+	return c.callbacks.Plugins()
+}
+
+// Use initializes the plugins on the client
+func (c *Client) Use(plugins ...Plugin) error {
+	return use(c, plugins)
+}
+
+// Watcher returns a watcher of the change events of the whole deployment
+func (c *Client) Watcher() *watcher.Watcher[bson.M] {
+	return watcher.NewWatcher[bson.M](c.client)
+}
+
+// Watch opens a change stream on the whole deployment
+func (c *Client) Watch(ctx context.Context, pipeline any, opts ...options.Lister[options.ChangeStreamOptions]) (*watcher.ChangeStream[bson.M], error) {
+	return c.Watcher().Pipeline(pipeline).Watch(ctx, opts...)
+}
+
+// WithTransaction runs fn inside a multi-document transaction. The context passed to fn carries the session,
+// so every Finder, Creator, Updater, Deleter and Aggregator called with it takes part in the transaction.
+// The whole transaction is retried on TransientTransactionError and the commit is retried on
+// UnknownTransactionCommitResult, so fn may be executed more than once.
+func (c *Client) WithTransaction(ctx context.Context, fn func(ctx context.Context) error, opts ...options.Lister[options.TransactionOptions]) error {
+	session, err := c.client.StartSession()
+	if err != nil {
+		return err
+	}
+	c.commits.begin(session)
+	defer c.commits.end(ctx, session)
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(ctx context.Context) (any, error) {
+		return nil, fn(ctx)
+	}, opts...)
+	return err
+}
+
+// StartSession starts a new session for manually managed transactions.
+// The caller must call EndSession when the session is no longer needed.
+func (c *Client) StartSession(opts ...options.Lister[options.SessionOptions]) (*Session, error) {
+	session, err := c.client.StartSession(opts...)
+	if err != nil {
+		return nil, err
+	}
+	c.commits.begin(session)
+	return &Session{session: session, commits: c.commits}, nil
+}
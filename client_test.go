@@ -29,3 +29,8 @@ func TestNewClient(t *testing.T) {
 
 	require.NotNil(t, client.NewDatabase("db-test"))
 }
+
+func TestNewClient_NilConfig(t *testing.T) {
+	client := NewClient(&mongo.Client{}, nil)
+	require.NotNil(t, client.config())
+}
@@ -15,53 +15,230 @@
 package mongox
 
 import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
 	"github.com/chenmingyong0423/go-mongox/v2/aggregator"
+	"github.com/chenmingyong0423/go-mongox/v2/bulkwriter"
+	"github.com/chenmingyong0423/go-mongox/v2/cache"
 	"github.com/chenmingyong0423/go-mongox/v2/callback"
 	"github.com/chenmingyong0423/go-mongox/v2/creator"
 	"github.com/chenmingyong0423/go-mongox/v2/deleter"
 	"github.com/chenmingyong0423/go-mongox/v2/field"
 	"github.com/chenmingyong0423/go-mongox/v2/finder"
+	hookfield "github.com/chenmingyong0423/go-mongox/v2/internal/hook/field"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
 	"github.com/chenmingyong0423/go-mongox/v2/updater"
+	"github.com/chenmingyong0423/go-mongox/v2/watcher"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
-func NewCollection[T any](db *Database, collection string) *Collection[T] {
+// NewCollection returns the collection of the documents of type T,
+// it panics if a mongox tag of T is malformed, field.Parse reports the error without panicking
+func NewCollection[T any](db *Database, collection string, opts ...CollectionOption) *Collection[T] {
+	collectionOpts := &collectionOptions{}
+	for _, opt := range opts {
+		opt(collectionOpts)
+	}
+
+	fields := field.ParseFields(new(T))
+	if collectionOpts.softDelete && field.SoftDeleteField(fields) == nil {
+		enableSoftDelete(fields)
+	}
+
+	callbacks := callback.NewScope(db.callbacks)
+	if encrypted := field.EncryptedFields(fields); len(encrypted) > 0 {
+		keyProvider := collectionOpts.keyProvider
+		if keyProvider == nil {
+			keyProvider = db.client.config().KeyProvider
+		}
+		for _, fd := range encrypted {
+			fd.KeyProvider = keyProvider
+		}
+		// the scope is new, the registration can not conflict with other handlers
+		_ = registerEncryption(callbacks)
+	}
+	if queryCache := db.client.config().Cache; queryCache != nil {
+		_ = registerCacheInvalidation(callbacks, queryCache, db.client.commits)
+	}
+
 	return &Collection[T]{
 		db:         db,
 		collection: db.Database().Collection(collection),
-		callbacks:  db.callbacks,
-		fields:     field.ParseFields(new(T)),
+		callbacks:  callbacks,
+		fields:     fields,
 	}
 }
 
+const (
+	// EncryptPlugin is the name of the handler of the fields tagged with mongox:"encrypt" registered on the collections
+	EncryptPlugin = "mongox:encrypt"
+	// CachePlugin is the name of the handler dropping the cached results of the queries registered on the collections
+	// when Config.Cache is set
+	CachePlugin = "mongox:cache"
+)
+
+// registerEncryption registers the handler of the fields tagged with mongox:"encrypt", it encrypts the equality filters
+// on the deterministic fields, restores the plaintexts of the written documents and decrypts the documents which are read.
+// The written values are encrypted by the callback.FieldsPlugin handler.
+func registerEncryption(callbacks *callback.Callback) error {
+	opTypes := []operation.OpType{
+		operation.OpTypeBeforeFind, operation.OpTypeBeforeUpdate, operation.OpTypeBeforeUpsert, operation.OpTypeBeforeReplace,
+		operation.OpTypeBeforeDelete, operation.OpTypeBeforeCount, operation.OpTypeBeforeDistinct,
+		operation.OpTypeAfterInsert, operation.OpTypeAfterReplace, operation.OpTypeAfterFind, operation.OpTypeAfterAggregate,
+		operation.OpTypeOnError,
+	}
+	for _, opType := range opTypes {
+		opType := opType
+		err := callbacks.Register(opType, EncryptPlugin, func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+			return hookfield.Encrypt(ctx, opCtx, opType, opts...)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerCacheInvalidation registers the handler which drops the results of the queries cached in the cache
+// once the writes changing them succeeded, see cache.Invalidate. The results are dropped again once the transaction
+// of the write is over, so that a query outside the transaction can not cache the value read before the commit.
+// It is only possible for the transactions of WithTransaction and StartSession.
+func registerCacheInvalidation(callbacks *callback.Callback, queryCache cache.Cache, commits *commitHooks) error {
+	opTypes := []operation.OpType{
+		operation.OpTypeAfterInsert, operation.OpTypeAfterUpdate, operation.OpTypeAfterUpsert,
+		operation.OpTypeAfterReplace, operation.OpTypeAfterDelete,
+	}
+	for _, opType := range opTypes {
+		err := callbacks.Register(opType, CachePlugin, func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+			if err := cache.Invalidate(ctx, queryCache, opCtx); err != nil {
+				return fmt.Errorf("mongox: invalidate the cached queries: %w", err)
+			}
+			if opCtx.InTransaction() {
+				commits.add(opCtx.Session, func(ctx context.Context) {
+					// the write is committed or aborted, the error can not be returned by the write any more
+					_ = cache.Invalidate(ctx, queryCache, opCtx)
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type collectionOptions struct {
+	softDelete  bool
+	keyProvider field.KeyProvider
+}
+
+type CollectionOption func(*collectionOptions)
+
+// WithSoftDelete enables soft delete for the collection with the DeletedAt field of the model,
+// e.g. the one declared by mongox.Model. A model with a field tagged `mongox:"softDelete"` enables it without this option.
+func WithSoftDelete() CollectionOption {
+	return func(opts *collectionOptions) {
+		opts.softDelete = true
+	}
+}
+
+// WithKeyProvider sets the provider of the keys of the fields tagged with mongox:"encrypt", it overrides Config.KeyProvider.
+// The operations on a model with encrypted fields fail with field.ErrNoKeyProvider if no provider is set.
+func WithKeyProvider(keyProvider field.KeyProvider) CollectionOption {
+	return func(opts *collectionOptions) {
+		opts.keyProvider = keyProvider
+	}
+}
+
+// enableSoftDelete marks the time.Time field named DeletedAt as the soft-delete field
+func enableSoftDelete(fields []*field.Filed) bool {
+	for _, fd := range fields {
+		if fd.InlinedFields != nil {
+			if enableSoftDelete(fd.InlinedFields) {
+				return true
+			}
+		} else if fd.Name == field.DeletedAt && fd.FieldType == reflect.TypeOf(time.Time{}) {
+			fd.SoftDelete = field.UnixTime
+			return true
+		}
+	}
+	return false
+}
+
 type Collection[T any] struct {
 	db         *Database
 	collection *mongo.Collection
-	// callbacks inherited from database
+	// callbacks local to the collection, the callbacks of the database run first
 	callbacks *callback.Callback
 
 	fields []*field.Filed
 }
 
 func (c *Collection[T]) Finder() *finder.Finder[T] {
-	return finder.NewFinder[T](c.collection, c.callbacks, c.fields)
+	cfg := c.db.client.config()
+	return finder.NewFinder[T](c.collection, c.callbacks, c.fields).Clock(cfg.now).Timeout(cfg.FindTimeout).PageTokenSecret(cfg.PageTokenSecret).CacheStore(cfg.Cache)
 }
 
 func (c *Collection[T]) Creator() *creator.Creator[T] {
-	return creator.NewCreator[T](c.collection, c.callbacks, c.fields)
+	cfg := c.db.client.config()
+	return creator.NewCreator[T](c.collection, c.callbacks, c.fields).Clock(cfg.now).Timeout(cfg.InsertTimeout)
 }
 
 func (c *Collection[T]) Updater() *updater.Updater[T] {
-	return updater.NewUpdater[T](c.collection, c.callbacks, c.fields)
+	cfg := c.db.client.config()
+	return updater.NewUpdater[T](c.collection, c.callbacks, c.fields).Clock(cfg.now).Timeout(cfg.UpdateTimeout)
 }
 
 func (c *Collection[T]) Deleter() *deleter.Deleter[T] {
-	return deleter.NewDeleter[T](c.collection, c.callbacks, c.fields)
+	cfg := c.db.client.config()
+	return deleter.NewDeleter[T](c.collection, c.callbacks, c.fields).Clock(cfg.now).Timeout(cfg.DeleteTimeout)
 }
+
 func (c *Collection[T]) Aggregator() *aggregator.Aggregator[T] {
-	return aggregator.NewAggregator[T](c.collection, c.callbacks, c.fields)
+	cfg := c.db.client.config()
+	return aggregator.NewAggregator[T](c.collection, c.callbacks, c.fields).Clock(cfg.now).Timeout(cfg.AggregateTimeout)
+}
+
+func (c *Collection[T]) BulkWriter() *bulkwriter.BulkWriter[T] {
+	cfg := c.db.client.config()
+	return bulkwriter.NewBulkWriter[T](c.collection, c.callbacks, c.fields).Clock(cfg.now).Timeout(cfg.BulkWriteTimeout)
+}
+
+// Watcher returns a watcher of the change events of the collection, it can checkpoint the resume token
+func (c *Collection[T]) Watcher() *watcher.Watcher[T] {
+	return watcher.NewWatcher[T](c.collection)
+}
+
+// Watch opens a change stream on the collection whose documents are decoded into T,
+// the pipeline can be built by aggregation.StageBuilder.
+func (c *Collection[T]) Watch(ctx context.Context, pipeline any, opts ...options.Lister[options.ChangeStreamOptions]) (*watcher.ChangeStream[T], error) {
+	return c.Watcher().Pipeline(pipeline).Watch(ctx, opts...)
 }
 
 func (c *Collection[T]) Collection() *mongo.Collection {
 	return c.collection
 }
+
+// RegisterPlugin registers the plugin for the collection only, it runs after the plugins of the database
+func (c *Collection[T]) RegisterPlugin(name string, cb callback.CbFn, opType operation.OpType, opts ...callback.RegisterOption) error {
+	return c.callbacks.Register(opType, name, cb, opts...)
+}
+
+func (c *Collection[T]) RemovePlugin(name string, opType operation.OpType) {
+	c.callbacks.Remove(opType, name)
+}
+
+// Plugins returns the plugins registered on the collection for every operation type in the execution order
+func (c *Collection[T]) Plugins() map[operation.OpType][]callback.PluginInfo {
+	return c.callbacks.Plugins()
+}
+
+// Use initializes the plugins on the collection
+func (c *Collection[T]) Use(plugins ...Plugin) error {
+	return use(c, plugins)
+}
@@ -19,16 +19,22 @@ package mongox
 import (
 	"context"
 	"testing"
+	"time"
 
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
 
+	"github.com/chenmingyong0423/go-mongox/v2/aggregator"
+	"github.com/chenmingyong0423/go-mongox/v2/cache"
 	"github.com/chenmingyong0423/go-mongox/v2/creator"
+	"github.com/chenmingyong0423/go-mongox/v2/field"
 
 	"github.com/chenmingyong0423/go-mongox/v2/finder"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 func TestCollection_e2e_Deleter(t *testing.T) {
@@ -74,3 +80,341 @@ func getCollection[T any](t *testing.T) *Collection[T] {
 	collection := NewCollection[T](NewClient(client, &Config{}).NewDatabase("db-test"), "test_user")
 	return collection
 }
+
+func TestCollection_e2e_SoftDelete(t *testing.T) {
+	type User struct {
+		Model `bson:",inline"`
+		Name  string `bson:"name"`
+	}
+	client, err := mongo.Connect(options.Client().ApplyURI("mongodb://localhost:27017").SetAuth(options.Credential{
+		Username:   "test",
+		Password:   "test",
+		AuthSource: "db-test",
+	}))
+	require.NoError(t, err)
+	collection := NewCollection[User](NewClient(client, &Config{}).NewDatabase("db-test"), "test_user", WithSoftDelete())
+	ctx := context.Background()
+	defer func() {
+		_, err := collection.Collection().DeleteMany(ctx, bson.D{})
+		require.NoError(t, err)
+	}()
+
+	_, err = collection.Creator().InsertMany(ctx, []*User{{Name: "chenmingyong"}, {Name: "burt"}})
+	require.NoError(t, err)
+
+	deleteResult, err := collection.Deleter().Filter(bson.M{"name": "chenmingyong"}).DeleteOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleteResult.DeletedCount)
+
+	// the soft-deleted document is still stored
+	raw, err := collection.Collection().CountDocuments(ctx, bson.D{})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), raw)
+
+	count, err := collection.Finder().Count(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	count, err = collection.Finder().EstimatedCount(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	names := make([]string, 0)
+	err = collection.Finder().DistinctWithParse(ctx, "name", &names)
+	require.NoError(t, err)
+	require.Equal(t, []string{"burt"}, names)
+
+	_, err = collection.Finder().Filter(bson.M{"name": "chenmingyong"}).FindOne(ctx)
+	require.ErrorIs(t, err, mongo.ErrNoDocuments)
+
+	user, err := collection.Finder().Unscoped().Filter(bson.M{"name": "chenmingyong"}).FindOne(ctx)
+	require.NoError(t, err)
+	require.False(t, user.DeletedAt.IsZero())
+
+	users, err := collection.Aggregator().Pipeline(mongo.Pipeline{}).Aggregate(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+
+	updateResult, err := collection.Updater().Filter(bson.M{"name": "chenmingyong"}).Restore(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), updateResult.ModifiedCount)
+
+	count, err = collection.Finder().Count(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	deleteResult, err = collection.Deleter().ForceDelete().Filter(bson.M{"name": "burt"}).DeleteMany(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleteResult.DeletedCount)
+	raw, err = collection.Collection().CountDocuments(ctx, bson.D{})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), raw)
+
+	// the soft delete of FindOneAndDelete keeps the sort and the projection
+	_, err = collection.Creator().InsertMany(ctx, []*User{{Name: "burt"}, {Name: "cmy"}})
+	require.NoError(t, err)
+	deleted, err := collection.Finder().FindOneAndDelete(ctx, options.FindOneAndDelete().SetSort(bson.M{"name": -1}).SetProjection(bson.M{"name": 1}))
+	require.NoError(t, err)
+	require.Equal(t, "cmy", deleted.Name)
+	require.True(t, deleted.CreatedAt.IsZero())
+	raw, err = collection.Collection().CountDocuments(ctx, bson.D{})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), raw)
+}
+
+func TestCollection_e2e_SoftDeleteCollation(t *testing.T) {
+	type User struct {
+		Model `bson:",inline"`
+		Name  string `bson:"name"`
+	}
+	client, err := mongo.Connect(options.Client().ApplyURI("mongodb://localhost:27017").SetAuth(options.Credential{
+		Username:   "test",
+		Password:   "test",
+		AuthSource: "db-test",
+	}))
+	require.NoError(t, err)
+	collection := NewCollection[User](NewClient(client, &Config{}).NewDatabase("db-test"), "test_user", WithSoftDelete())
+	ctx := context.Background()
+	defer func() {
+		_, err := collection.Collection().DeleteMany(ctx, bson.D{})
+		require.NoError(t, err)
+	}()
+
+	_, err = collection.Creator().InsertMany(ctx, []*User{{Name: "Burt"}, {Name: "BURT"}, {Name: "cmy"}, {Name: "CMY"}})
+	require.NoError(t, err)
+
+	// the case-insensitive collation of the caller selects the documents which are soft-deleted
+	caseInsensitive := &options.Collation{Locale: "en", Strength: 2}
+	deleteResult, err := collection.Deleter().Filter(bson.M{"name": "burt"}).DeleteOne(ctx, options.DeleteOne().SetCollation(caseInsensitive))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleteResult.DeletedCount)
+
+	deleteResult, err = collection.Deleter().Filter(bson.M{"name": "cmy"}).DeleteMany(ctx, options.DeleteMany().SetCollation(caseInsensitive))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), deleteResult.DeletedCount)
+
+	count, err := collection.Finder().Count(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+	raw, err := collection.Collection().CountDocuments(ctx, bson.D{})
+	require.NoError(t, err)
+	require.Equal(t, int64(4), raw)
+}
+
+func TestCollection_e2e_Encrypt(t *testing.T) {
+	type User struct {
+		ID    bson.ObjectID `bson:"_id,omitempty" mongox:"autoID"`
+		Name  string        `bson:"name"`
+		Email string        `bson:"email" mongox:"encrypt:deterministic"`
+		Phone string        `bson:"phone" mongox:"encrypt"`
+	}
+	client, err := mongo.Connect(options.Client().ApplyURI("mongodb://localhost:27017").SetAuth(options.Credential{
+		Username:   "test",
+		Password:   "test",
+		AuthSource: "db-test",
+	}))
+	require.NoError(t, err)
+	collection := NewCollection[User](NewClient(client, &Config{}).NewDatabase("db-test"), "test_user", WithKeyProvider(field.StaticKey("0123456789abcdef0123456789abcdef")))
+	ctx := context.Background()
+	defer func() {
+		_, err := collection.Collection().DeleteMany(ctx, bson.D{})
+		require.NoError(t, err)
+	}()
+
+	user := &User{Name: "chenmingyong", Email: "chenmingyong@example.com", Phone: "13800000000"}
+	_, err = collection.Creator().InsertOne(ctx, user)
+	require.NoError(t, err)
+	// the plaintexts are restored after the insert
+	require.Equal(t, "chenmingyong@example.com", user.Email)
+	require.Equal(t, "13800000000", user.Phone)
+
+	// the server only stores the ciphertexts
+	raw := bson.M{}
+	require.NoError(t, collection.Collection().FindOne(ctx, bson.M{"_id": user.ID}).Decode(&raw))
+	require.Equal(t, "chenmingyong", raw["name"])
+	require.NotEqual(t, "chenmingyong@example.com", raw["email"])
+	require.NotEqual(t, "13800000000", raw["phone"])
+
+	// the deterministic field can be queried by its plaintext
+	found, err := collection.Finder().Filter(bson.M{"email": "chenmingyong@example.com"}).FindOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, user, found)
+
+	_, err = collection.Updater().Filter(bson.M{"email": "chenmingyong@example.com"}).Updates(bson.M{"$set": bson.M{"phone": "13900000000"}}).UpdateOne(ctx)
+	require.NoError(t, err)
+	require.NoError(t, collection.Collection().FindOne(ctx, bson.M{"_id": user.ID}).Decode(&raw))
+	require.NotEqual(t, "13900000000", raw["phone"])
+
+	users, err := collection.Finder().Find(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, "chenmingyong@example.com", users[0].Email)
+	require.Equal(t, "13900000000", users[0].Phone)
+
+	// the results decoded into another type than the model are decrypted by the keys of their fields
+	type Contact struct {
+		Email string `bson:"email"`
+		Phone string `bson:"phone"`
+	}
+	contact, err := finder.FindOneAs[User, Contact](ctx, collection.Finder().Filter(bson.M{"_id": user.ID}))
+	require.NoError(t, err)
+	require.Equal(t, &Contact{Email: "chenmingyong@example.com", Phone: "13900000000"}, contact)
+
+	contacts, err := finder.FindAs[User, Contact](ctx, collection.Finder())
+	require.NoError(t, err)
+	require.Equal(t, []*Contact{{Email: "chenmingyong@example.com", Phone: "13900000000"}}, contacts)
+
+	contacts, err = aggregator.AggregateAs[User, Contact](ctx, collection.Aggregator().Pipeline(mongo.Pipeline{{{Key: "$project", Value: bson.M{"email": 1, "phone": 1}}}}))
+	require.NoError(t, err)
+	require.Equal(t, []*Contact{{Email: "chenmingyong@example.com", Phone: "13900000000"}}, contacts)
+}
+
+func TestCollection_e2e_Cache(t *testing.T) {
+	type User struct {
+		ID   bson.ObjectID `bson:"_id,omitempty" mongox:"autoID"`
+		Name string        `bson:"name"`
+		Age  int           `bson:"age"`
+	}
+	client, err := mongo.Connect(options.Client().ApplyURI("mongodb://localhost:27017").SetAuth(options.Credential{
+		Username:   "test",
+		Password:   "test",
+		AuthSource: "db-test",
+	}))
+	require.NoError(t, err)
+	queryCache := cache.NewLRU(100)
+	collection := NewCollection[User](NewClient(client, &Config{Cache: queryCache}).NewDatabase("db-test"), "test_user")
+	ctx := context.Background()
+	defer func() {
+		_, err := collection.Collection().DeleteMany(ctx, bson.D{})
+		require.NoError(t, err)
+	}()
+
+	users := []*User{{Name: "chenmingyong", Age: 24}, {Name: "burt", Age: 25}}
+	_, err = collection.Creator().InsertMany(ctx, users)
+	require.NoError(t, err)
+
+	found, err := collection.Finder().Cache(time.Minute).Filter(bson.M{"_id": users[0].ID}).FindOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, users[0], found)
+	all, err := collection.Finder().Cache(time.Minute).Sort(bson.M{"age": 1}).Find(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	count, err := collection.Finder().Cache(time.Minute).Count(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+	require.Equal(t, 3, queryCache.Len())
+
+	// the cached results are read while the server is bypassed
+	_, err = collection.Collection().UpdateOne(ctx, bson.M{"_id": users[0].ID}, bson.M{"$set": bson.M{"age": 30}})
+	require.NoError(t, err)
+	found, err = collection.Finder().Cache(time.Minute).Filter(bson.M{"_id": users[0].ID}).FindOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 24, found.Age)
+	all, err = collection.Finder().Cache(time.Minute).Sort(bson.M{"age": 1}).Find(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []*User{users[0], users[1]}, all)
+	// the queries not opted in are not cached
+	found, err = collection.Finder().Filter(bson.M{"_id": users[0].ID}).FindOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 30, found.Age)
+
+	// the write of the other document keeps the result of the query on the first one
+	_, err = collection.Updater().Filter(bson.M{"_id": users[1].ID}).Updates(bson.M{"$set": bson.M{"age": 26}}).UpdateOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, queryCache.Len())
+	found, err = collection.Finder().Cache(time.Minute).Filter(bson.M{"_id": users[0].ID}).FindOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 24, found.Age)
+	count, err = collection.Finder().Cache(time.Minute).Count(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	// the write of the first document drops its result
+	_, err = collection.Updater().Filter(bson.M{"_id": users[0].ID}).Updates(bson.M{"$set": bson.M{"age": 31}}).UpdateOne(ctx)
+	require.NoError(t, err)
+	found, err = collection.Finder().Cache(time.Minute).Filter(bson.M{"_id": users[0].ID}).FindOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 31, found.Age)
+
+	// the writes on unknown documents drop all the results of the collection
+	_, err = collection.Deleter().Filter(bson.M{"name": "burt"}).DeleteOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, queryCache.Len())
+	count, err = collection.Finder().Cache(time.Minute).Count(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	_, err = collection.Creator().InsertOne(ctx, &User{Name: "Mingyong Chen"})
+	require.NoError(t, err)
+	count, err = collection.Finder().Cache(time.Minute).Count(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	// the result cached by a query outside the transaction before the commit is dropped once the transaction is committed
+	mongoxClient := NewClient(client, &Config{Cache: queryCache})
+	err = mongoxClient.WithTransaction(ctx, func(txCtx context.Context) error {
+		collection := NewCollection[User](mongoxClient.NewDatabase("db-test"), "test_user")
+		if _, err := collection.Updater().Filter(bson.M{"_id": users[0].ID}).Updates(bson.M{"$set": bson.M{"age": 32}}).UpdateOne(txCtx); err != nil {
+			return err
+		}
+		found, err := collection.Finder().Cache(time.Minute).Filter(bson.M{"_id": users[0].ID}).FindOne(ctx)
+		if err != nil {
+			return err
+		}
+		require.Equal(t, 31, found.Age)
+		return nil
+	})
+	require.NoError(t, err)
+	found, err = collection.Finder().Cache(time.Minute).Filter(bson.M{"_id": users[0].ID}).FindOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 32, found.Age)
+}
+
+func TestCollection_e2e_Defaults(t *testing.T) {
+	type User struct {
+		ID     bson.ObjectID `bson:"_id,omitempty" mongox:"autoID"`
+		Name   string        `bson:"name"`
+		Role   string        `bson:"role" mongox:"default:member"`
+		Quota  int           `bson:"quota" mongox:"default:10"`
+		Invite string        `bson:"invite" mongox:"default:fn=e2e-invite"`
+	}
+	field.RegisterDefault("e2e-invite", func() any { return "INVITE" })
+	collection := getCollection[User](t)
+	ctx := context.Background()
+	defer func() {
+		_, err := collection.Collection().DeleteMany(ctx, bson.D{})
+		require.NoError(t, err)
+	}()
+
+	user := &User{Name: "chenmingyong", Quota: 20}
+	_, err := collection.Creator().InsertOne(ctx, user)
+	require.NoError(t, err)
+	require.Equal(t, "member", user.Role)
+	require.Equal(t, 20, user.Quota)
+	require.Equal(t, "INVITE", user.Invite)
+
+	_, err = collection.Updater().Filter(bson.M{"name": "burt"}).Updates(bson.M{"$set": bson.M{"name": "burt", "role": "admin"}}).Upsert(ctx)
+	require.NoError(t, err)
+	found, err := collection.Finder().Filter(bson.M{"name": "burt"}).FindOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "admin", found.Role)
+	require.Equal(t, 10, found.Quota)
+	require.Equal(t, "INVITE", found.Invite)
+
+	// the defaults are only set on insert
+	_, err = collection.Updater().Filter(bson.M{"name": "burt"}).Updates(bson.M{"$set": bson.M{"quota": 5}}).Upsert(ctx)
+	require.NoError(t, err)
+	found, err = collection.Finder().Filter(bson.M{"name": "burt"}).FindOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 5, found.Quota)
+	require.Equal(t, "admin", found.Role)
+
+	// the equalities of the filter are kept and the defaults are set without $set
+	_, err = collection.Updater().Filter(bson.M{"name": "cmy", "role": "owner"}).Updates(bson.M{"$inc": bson.M{"quota": 1}}).Upsert(ctx)
+	require.NoError(t, err)
+	found, err = collection.Finder().Filter(bson.M{"name": "cmy"}).FindOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "owner", found.Role)
+	require.Equal(t, 1, found.Quota)
+	require.Equal(t, "INVITE", found.Invite)
+}
@@ -19,8 +19,12 @@ import (
 
 	"github.com/chenmingyong0423/go-mongox/v2/updater"
 
+	"github.com/chenmingyong0423/go-mongox/v2/cache"
+	"github.com/chenmingyong0423/go-mongox/v2/callback"
 	"github.com/chenmingyong0423/go-mongox/v2/creator"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
 
+	"github.com/chenmingyong0423/go-mongox/v2/field"
 	"github.com/chenmingyong0423/go-mongox/v2/finder"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -62,3 +66,32 @@ func TestCollection_Collection(t *testing.T) {
 	a := NewCollection[any](NewClient(&mongo.Client{}, &Config{}).NewDatabase("db-test"), "collection-test")
 	assert.NotNil(t, a.Collection(), "Expected non-nil *mongo.Collection")
 }
+
+func TestCollection_WithSoftDelete(t *testing.T) {
+	type User struct {
+		Model `bson:",inline"`
+		Name  string `bson:"name"`
+	}
+	db := NewClient(&mongo.Client{}, &Config{}).NewDatabase("db-test")
+
+	c := NewCollection[User](db, "collection-test")
+	assert.Nil(t, field.SoftDeleteField(c.fields))
+
+	c = NewCollection[User](db, "collection-test", WithSoftDelete())
+	fd := field.SoftDeleteField(c.fields)
+	assert.NotNil(t, fd)
+	assert.Equal(t, "deleted_at", fd.MongoField)
+}
+
+func TestCollection_Cache(t *testing.T) {
+	c := NewCollection[any](NewClient(&mongo.Client{}, &Config{}).NewDatabase("db-test"), "collection-test")
+	assert.Empty(t, c.Plugins()[operation.OpTypeAfterInsert])
+
+	c = NewCollection[any](NewClient(&mongo.Client{}, &Config{Cache: cache.NewLRU(10)}).NewDatabase("db-test"), "collection-test")
+	for _, opType := range []operation.OpType{
+		operation.OpTypeAfterInsert, operation.OpTypeAfterUpdate, operation.OpTypeAfterUpsert, operation.OpTypeAfterReplace, operation.OpTypeAfterDelete,
+	} {
+		assert.Equal(t, []callback.PluginInfo{{Name: CachePlugin}}, c.Plugins()[opType])
+	}
+	assert.Empty(t, c.Plugins()[operation.OpTypeAfterFind])
+}
@@ -14,5 +14,58 @@
 
 package mongox
 
+import (
+	"time"
+
+	"github.com/chenmingyong0423/go-mongox/v2/cache"
+	"github.com/chenmingyong0423/go-mongox/v2/field"
+	"go.mongodb.org/mongo-driver/v2/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+	"go.mongodb.org/mongo-driver/v2/mongo/writeconcern"
+)
+
+// Config holds the client-wide defaults applied to every database, collection and operator created from the client
 type Config struct {
+	// ReadConcern, WriteConcern and ReadPreference are the defaults of the databases created by the client,
+	// nil inherits the ones of the mongo client
+	ReadConcern    *readconcern.ReadConcern
+	WriteConcern   *writeconcern.WriteConcern
+	ReadPreference *readpref.ReadPref
+
+	// The timeouts of each operation type, 0 means no timeout.
+	// They are ignored if the context of the operation already has a deadline.
+	FindTimeout      time.Duration
+	InsertTimeout    time.Duration
+	UpdateTimeout    time.Duration
+	DeleteTimeout    time.Duration
+	AggregateTimeout time.Duration
+	BulkWriteTimeout time.Duration
+
+	// PageTokenSecret signs the tokens of Finder.PageAfter, the tokens are only valid in the current process if it is not set
+	PageTokenSecret []byte
+
+	// Clock returns the current time used for the automatic timestamps, time.Now by default
+	Clock func() time.Time
+	// UTC stores the automatic timestamps in UTC instead of the local time zone
+	UTC bool
+
+	// KeyProvider provides the keys of the fields tagged with mongox:"encrypt", see WithKeyProvider
+	KeyProvider field.KeyProvider
+
+	// Cache caches the results of the queries opted in with Finder.Cache, e.g. cache.NewLRU(1000).
+	// The writes through the collections of the client drop the cached results they may change,
+	// the writes of the transactions of Client.WithTransaction and Client.StartSession drop them again once they are committed.
+	Cache cache.Cache
+}
+
+// now returns the current time according to the clock and the time zone policy
+func (c *Config) now() time.Time {
+	now := time.Now
+	if c.Clock != nil {
+		now = c.Clock
+	}
+	if c.UTC {
+		return now().UTC()
+	}
+	return now()
 }
@@ -0,0 +1,60 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongox
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_now(t *testing.T) {
+	fixed := time.Date(2025, 1, 1, 8, 0, 0, 0, time.FixedZone("UTC+8", 8*60*60))
+
+	testCases := []struct {
+		name string
+		cfg  *Config
+		want func(t *testing.T, now time.Time)
+	}{
+		{
+			name: "default clock",
+			cfg:  &Config{},
+			want: func(t *testing.T, now time.Time) {
+				require.WithinDuration(t, time.Now(), now, time.Second)
+			},
+		},
+		{
+			name: "custom clock",
+			cfg:  &Config{Clock: func() time.Time { return fixed }},
+			want: func(t *testing.T, now time.Time) {
+				require.Equal(t, fixed, now)
+			},
+		},
+		{
+			name: "utc",
+			cfg:  &Config{Clock: func() time.Time { return fixed }, UTC: true},
+			want: func(t *testing.T, now time.Time) {
+				require.Equal(t, time.UTC, now.Location())
+				require.True(t, fixed.Equal(now))
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.want(t, tc.cfg.now())
+		})
+	}
+}
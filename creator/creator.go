@@ -49,6 +49,9 @@ type Creator[T any] struct {
 	afterHooks  []hookFn[T]
 
 	fields []*field.Filed
+
+	clock   func() time.Time
+	timeout time.Duration
 }
 
 func NewCreator[T any](collection *mongo.Collection, dbCallbacks *callback.Callback, fields []*field.Filed) *Creator[T] {
@@ -56,6 +59,7 @@ func NewCreator[T any](collection *mongo.Collection, dbCallbacks *callback.Callb
 		collection:  collection,
 		dbCallbacks: dbCallbacks,
 		fields:      fields,
+		clock:       time.Now,
 	}
 }
 
@@ -64,6 +68,18 @@ func (c *Creator[T]) ModelHook(modelHook any) *Creator[T] {
 	return c
 }
 
+// Clock is used to set the function which returns the current time of the insert, time.Now by default
+func (c *Creator[T]) Clock(clock func() time.Time) *Creator[T] {
+	c.clock = clock
+	return c
+}
+
+// Timeout is used to set the timeout of the insert, it is ignored if the context already has a deadline
+func (c *Creator[T]) Timeout(timeout time.Duration) *Creator[T] {
+	c.timeout = timeout
+	return c
+}
+
 // RegisterBeforeHooks is used to set the after hooks of the insert operation
 // If you register the hook for InsertOne, the opContext.Docs will be nil
 // If you register the hook for InsertMany, the opContext.Doc will be nil
@@ -77,15 +93,16 @@ func (c *Creator[T]) RegisterAfterHooks(hooks ...hookFn[T]) *Creator[T] {
 	return c
 }
 
+// preActionHandler runs the before callbacks and hooks, the onError callbacks are run with the error if one of them fails
 func (c *Creator[T]) preActionHandler(ctx context.Context, globalOpContext *operation.OpContext, opContext *OpContext[T], opType operation.OpType) error {
 	err := c.dbCallbacks.Execute(ctx, globalOpContext, opType)
 	if err != nil {
-		return err
+		return c.dbCallbacks.OnError(globalOpContext.Context(ctx), globalOpContext, err)
 	}
 	for _, beforeHook := range c.beforeHooks {
 		err = beforeHook(ctx, opContext)
 		if err != nil {
-			return err
+			return c.dbCallbacks.OnError(globalOpContext.Context(ctx), globalOpContext, err)
 		}
 	}
 	return nil
@@ -106,20 +123,25 @@ func (c *Creator[T]) postActionHandler(ctx context.Context, globalOpContext *ope
 }
 
 func (c *Creator[T]) InsertOne(ctx context.Context, doc *T, opts ...options.Lister[options.InsertOneOptions]) (*mongo.InsertOneResult, error) {
-	currentTime := time.Now()
+	ctx, cancel := utils.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	currentTime := c.clock()
 	docValue := reflect.ValueOf(doc)
 
-	globalOpContext := operation.NewOpContext(c.collection, operation.WithDoc(doc), operation.WithReflectValue(docValue), operation.WithMongoOptions(opts), operation.WithModelHook(c.modelHook), operation.WithStartTime(currentTime), operation.WithFields(c.fields))
+	globalOpContext := operation.NewOpContext(c.collection, operation.WithDoc(doc), operation.WithReflectValue(docValue), operation.WithMongoOptions(opts), operation.WithModelHook(c.modelHook), operation.WithStartTime(currentTime), operation.WithFields(c.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameInsertOne), operation.WithLabels(operation.LabelsFromContext(ctx)))
+	defer globalOpContext.Release()
 	opContext := NewOpContext(c.collection, WithDoc(doc), WithReflectValue[T](docValue), WithStartTime[T](currentTime), WithMongoOptions[T](opts), WithModelHook[T](c.modelHook), WithFields[T](c.fields))
 
 	err := c.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeInsert)
 	if err != nil {
 		return nil, err
 	}
+	ctx = globalOpContext.Context(ctx)
 
 	result, err := c.collection.InsertOne(ctx, doc, opts...)
+	globalOpContext.Duration = c.clock().Sub(currentTime)
 	if err != nil {
-		return nil, err
+		return nil, c.dbCallbacks.OnError(ctx, globalOpContext, err)
 	}
 
 	globalOpContext.Result = result
@@ -133,20 +155,25 @@ func (c *Creator[T]) InsertOne(ctx context.Context, doc *T, opts ...options.List
 }
 
 func (c *Creator[T]) InsertMany(ctx context.Context, docs []*T, opts ...options.Lister[options.InsertManyOptions]) (*mongo.InsertManyResult, error) {
-	currentTime := time.Now()
+	ctx, cancel := utils.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	currentTime := c.clock()
 	docsValue := reflect.ValueOf(docs)
 
-	globalOpContext := operation.NewOpContext(c.collection, operation.WithDoc(docs), operation.WithReflectValue(docsValue), operation.WithStartTime(currentTime), operation.WithMongoOptions(opts), operation.WithModelHook(c.modelHook), operation.WithFields(c.fields))
+	globalOpContext := operation.NewOpContext(c.collection, operation.WithDoc(docs), operation.WithReflectValue(docsValue), operation.WithStartTime(currentTime), operation.WithMongoOptions(opts), operation.WithModelHook(c.modelHook), operation.WithFields(c.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameInsertMany), operation.WithMulti(true), operation.WithLabels(operation.LabelsFromContext(ctx)))
+	defer globalOpContext.Release()
 	opContext := NewOpContext(c.collection, WithDocs(docs), WithReflectValue[T](docsValue), WithStartTime[T](currentTime), WithMongoOptions[T](opts), WithModelHook[T](c.modelHook), WithFields[T](c.fields))
 
 	err := c.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeInsert)
 	if err != nil {
 		return nil, err
 	}
+	ctx = globalOpContext.Context(ctx)
 
 	result, err := c.collection.InsertMany(ctx, utils.ToAnySlice(docs...), opts...)
+	globalOpContext.Duration = c.clock().Sub(currentTime)
 	if err != nil {
-		return nil, err
+		return nil, c.dbCallbacks.OnError(ctx, globalOpContext, err)
 	}
 
 	globalOpContext.Result = result
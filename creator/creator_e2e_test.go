@@ -756,3 +756,82 @@ func TestCreator_e2e_Many(t *testing.T) {
 		})
 	}
 }
+
+func TestCreator_e2e_OnError(t *testing.T) {
+	type Account struct {
+		ID   string `bson:"_id"`
+		Name string `bson:"name"`
+	}
+	errAccountExists := errors.New("account already exists")
+	collection := newCollection(t)
+	callbacks := callback.InitializeCallbacks()
+	var afterInserts int
+	require.NoError(t, callbacks.Register(operation.OpTypeAfterInsert, "count", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+		afterInserts++
+		return nil
+	}))
+	var duration time.Duration = -1
+	require.NoError(t, callbacks.Register(operation.OpTypeOnError, "duplicate key", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+		duration = opCtx.Duration
+		if mongo.IsDuplicateKeyError(opCtx.Err) {
+			opCtx.Err = errAccountExists
+		}
+		return nil
+	}))
+	creator := NewCreator[Account](collection, callbacks, field.ParseFields(Account{}))
+	defer func() {
+		_, err := collection.DeleteOne(context.Background(), query.Id("cmy"))
+		require.NoError(t, err)
+	}()
+
+	_, err := creator.InsertOne(context.Background(), &Account{ID: "cmy", Name: "chenmingyong"})
+	require.NoError(t, err)
+	require.Equal(t, 1, afterInserts)
+	require.Equal(t, time.Duration(-1), duration)
+
+	_, err = creator.InsertOne(context.Background(), &Account{ID: "cmy", Name: "chenmingyong"})
+	require.Equal(t, errAccountExists, err)
+	require.Equal(t, 1, afterInserts)
+	require.GreaterOrEqual(t, duration, time.Duration(0))
+}
+
+func TestCreator_e2e_OnErrorBeforeHook(t *testing.T) {
+	type Account struct {
+		ID   string `bson:"_id"`
+		Name string `bson:"name"`
+	}
+	errRejected := errors.New("rejected")
+	collection := newCollection(t)
+	callbacks := callback.InitializeCallbacks()
+	var onErrors []error
+	require.NoError(t, callbacks.Register(operation.OpTypeOnError, "errors", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+		onErrors = append(onErrors, opCtx.Err)
+		return nil
+	}))
+
+	t.Run("before callback", func(t *testing.T) {
+		onErrors = nil
+		require.NoError(t, callbacks.Register(operation.OpTypeBeforeInsert, "reject", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+			return errRejected
+		}))
+		defer callbacks.Remove(operation.OpTypeBeforeInsert, "reject")
+
+		_, err := NewCreator[Account](collection, callbacks, field.ParseFields(Account{})).InsertOne(context.Background(), &Account{ID: "cmy"})
+		require.Equal(t, errRejected, err)
+		require.Equal(t, []error{errRejected}, onErrors)
+	})
+	t.Run("before hook", func(t *testing.T) {
+		onErrors = nil
+		_, err := NewCreator[Account](collection, callbacks, field.ParseFields(Account{})).
+			RegisterBeforeHooks(func(ctx context.Context, opContext *OpContext[Account], opts ...any) error {
+				return errRejected
+			}).
+			InsertMany(context.Background(), []*Account{{ID: "cmy"}})
+		require.Equal(t, errRejected, err)
+		require.Equal(t, []error{errRejected}, onErrors)
+	})
+
+	count, err := collection.CountDocuments(context.Background(), query.Id("cmy"))
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
@@ -0,0 +1,109 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cursor
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// DecodeHook is executed for each document decoded by the cursor, e.g. the after-find callbacks
+type DecodeHook[T any] func(ctx context.Context, doc *T) error
+
+// Cursor iterates the result of a query one document at a time instead of loading all of them in memory
+type Cursor[T any] struct {
+	cursor     *mongo.Cursor
+	decodeHook DecodeHook[T]
+
+	// ctx is the context of the last Next or TryNext call, it is passed to the decode hook
+	ctx context.Context
+}
+
+func NewCursor[T any](cursor *mongo.Cursor, decodeHook DecodeHook[T]) *Cursor[T] {
+	return &Cursor[T]{cursor: cursor, decodeHook: decodeHook, ctx: context.Background()}
+}
+
+// Next gets the next document, it returns false when the cursor is exhausted or an error occurs
+func (c *Cursor[T]) Next(ctx context.Context) bool {
+	c.ctx = ctx
+	return c.cursor.Next(ctx)
+}
+
+// TryNext is the non-blocking version of Next, it is used with tailable cursors
+func (c *Cursor[T]) TryNext(ctx context.Context) bool {
+	c.ctx = ctx
+	return c.cursor.TryNext(ctx)
+}
+
+// Decode decodes the current document into doc and executes the decode hook
+func (c *Cursor[T]) Decode(doc *T) error {
+	if err := c.cursor.Decode(doc); err != nil {
+		return err
+	}
+	if c.decodeHook != nil {
+		return c.decodeHook(c.ctx, doc)
+	}
+	return nil
+}
+
+// Current returns the raw current document
+func (c *Cursor[T]) Current() bson.Raw {
+	return c.cursor.Current
+}
+
+// ForEach decodes the documents one by one and calls fn with each of them until the cursor is exhausted
+// or fn returns an error, the cursor is closed when it returns
+func (c *Cursor[T]) ForEach(ctx context.Context, fn func(doc *T) error) error {
+	defer c.cursor.Close(ctx)
+	for c.Next(ctx) {
+		doc := new(T)
+		if err := c.Decode(doc); err != nil {
+			return err
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+	return c.cursor.Err()
+}
+
+// SetBatchSize sets the number of documents returned by each of the following getMore commands
+func (c *Cursor[T]) SetBatchSize(batchSize int32) {
+	c.cursor.SetBatchSize(batchSize)
+}
+
+// RemainingBatchLength returns the number of documents left in the current batch
+func (c *Cursor[T]) RemainingBatchLength() int {
+	return c.cursor.RemainingBatchLength()
+}
+
+func (c *Cursor[T]) ID() int64 {
+	return c.cursor.ID()
+}
+
+func (c *Cursor[T]) Err() error {
+	return c.cursor.Err()
+}
+
+func (c *Cursor[T]) Close(ctx context.Context) error {
+	return c.cursor.Close(ctx)
+}
+
+// Cursor returns the underlying mongo cursor
+func (c *Cursor[T]) Cursor() *mongo.Cursor {
+	return c.cursor
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cursor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type user struct {
+	Name string `bson:"name"`
+	Age  int    `bson:"age"`
+}
+
+func newMongoCursor(t *testing.T) *mongo.Cursor {
+	mongoCursor, err := mongo.NewCursorFromDocuments([]any{
+		user{Name: "chenmingyong", Age: 24},
+		user{Name: "burt", Age: 25},
+		user{Name: "gopher", Age: 26},
+	}, nil, nil)
+	require.NoError(t, err)
+	return mongoCursor
+}
+
+func TestCursor_Next(t *testing.T) {
+	decoded := make([]string, 0)
+	c := NewCursor[user](newMongoCursor(t), func(ctx context.Context, doc *user) error {
+		decoded = append(decoded, doc.Name)
+		return nil
+	})
+	defer c.Close(context.Background())
+
+	users := make([]*user, 0)
+	for c.Next(context.Background()) {
+		u := new(user)
+		require.NoError(t, c.Decode(u))
+		users = append(users, u)
+	}
+	require.NoError(t, c.Err())
+	require.Equal(t, []*user{{Name: "chenmingyong", Age: 24}, {Name: "burt", Age: 25}, {Name: "gopher", Age: 26}}, users)
+	require.Equal(t, []string{"chenmingyong", "burt", "gopher"}, decoded)
+}
+
+func TestCursor_ForEach(t *testing.T) {
+	testCases := []struct {
+		name       string
+		decodeHook DecodeHook[user]
+		fn         func(names *[]string) func(doc *user) error
+
+		wantNames []string
+		wantErr   error
+	}{
+		{
+			name: "all documents",
+			fn: func(names *[]string) func(doc *user) error {
+				return func(doc *user) error {
+					*names = append(*names, doc.Name)
+					return nil
+				}
+			},
+			wantNames: []string{"chenmingyong", "burt", "gopher"},
+		},
+		{
+			name: "fn error",
+			fn: func(names *[]string) func(doc *user) error {
+				return func(doc *user) error {
+					*names = append(*names, doc.Name)
+					if doc.Name == "burt" {
+						return errors.New("stop")
+					}
+					return nil
+				}
+			},
+			wantNames: []string{"chenmingyong", "burt"},
+			wantErr:   errors.New("stop"),
+		},
+		{
+			name: "decode hook error",
+			decodeHook: func(ctx context.Context, doc *user) error {
+				return errors.New("hook error")
+			},
+			fn: func(names *[]string) func(doc *user) error {
+				return func(doc *user) error {
+					*names = append(*names, doc.Name)
+					return nil
+				}
+			},
+			wantNames: []string{},
+			wantErr:   errors.New("hook error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			names := make([]string, 0)
+			err := NewCursor[user](newMongoCursor(t), tc.decodeHook).ForEach(context.Background(), tc.fn(&names))
+			require.Equal(t, tc.wantErr, err)
+			require.Equal(t, tc.wantNames, names)
+		})
+	}
+}
@@ -15,23 +15,39 @@
 package mongox
 
 import (
+	"context"
+
 	"github.com/chenmingyong0423/go-mongox/v2/callback"
 	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"github.com/chenmingyong0423/go-mongox/v2/watcher"
+	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
 type Database struct {
 	client *Client
 	db     *mongo.Database
-	// callbacks for database
+	// callbacks for database, the built-in ones included, the callbacks of the client run first
 	callbacks *callback.Callback
 }
 
 func newDatabase(c *Client, database string) *Database {
+	cfg := c.config()
+	opts := options.Database()
+	if cfg.ReadConcern != nil {
+		opts.SetReadConcern(cfg.ReadConcern)
+	}
+	if cfg.WriteConcern != nil {
+		opts.SetWriteConcern(cfg.WriteConcern)
+	}
+	if cfg.ReadPreference != nil {
+		opts.SetReadPreference(cfg.ReadPreference)
+	}
 	return &Database{
 		client:    c,
-		db:        c.client.Database(database),
-		callbacks: callback.InitializeCallbacks(),
+		db:        c.client.Database(database, opts),
+		callbacks: callback.InitializeCallbacks().Inherit(c.callbacks),
 	}
 }
 
@@ -39,10 +55,43 @@ func (d *Database) Database() *mongo.Database {
 	return d.db
 }
 
-func (d *Database) RegisterPlugin(name string, cb callback.CbFn, opType operation.OpType) {
-	d.callbacks.Register(opType, name, cb)
+// Watcher returns a watcher of the change events of all the collections in the database
+func (d *Database) Watcher() *watcher.Watcher[bson.M] {
+	return watcher.NewWatcher[bson.M](d.db)
+}
+
+// Watch opens a change stream on all the collections in the database
+func (d *Database) Watch(ctx context.Context, pipeline any, opts ...options.Lister[options.ChangeStreamOptions]) (*watcher.ChangeStream[bson.M], error) {
+	return d.Watcher().Pipeline(pipeline).Watch(ctx, opts...)
+}
+
+// RegisterPlugin registers the plugin for the operation type, its position among the plugins of the operation type
+// is set by callback.Before, callback.After and callback.Priority. An error is returned if the position can not be satisfied.
+func (d *Database) RegisterPlugin(name string, cb callback.CbFn, opType operation.OpType, opts ...callback.RegisterOption) error {
+	return d.callbacks.Register(opType, name, cb, opts...)
+}
+
+// ReplacePlugin replaces the function of a registered plugin, including the built-in ones such as callback.FieldsPlugin
+func (d *Database) ReplacePlugin(name string, cb callback.CbFn, opType operation.OpType, opts ...callback.RegisterOption) error {
+	return d.callbacks.Replace(opType, name, cb, opts...)
+}
+
+// Plugins returns the registered plugins of every operation type in the execution order
+func (d *Database) Plugins() map[operation.OpType][]callback.PluginInfo {
+	return d.callbacks.Plugins()
+}
+
+// DisablePlugin removes the plugin from every operation type, it is used to disable the built-in plugins
+func (d *Database) DisablePlugin(name string) {
+	d.callbacks.Remove(operation.OpTypeBeforeAny, name)
+	d.callbacks.Remove(operation.OpTypeAfterAny, name)
 }
 
 func (d *Database) RemovePlugin(name string, opType operation.OpType) {
 	d.callbacks.Remove(opType, name)
 }
+
+// Use initializes the plugins on the database
+func (d *Database) Use(plugins ...Plugin) error {
+	return use(d, plugins)
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/v2/bson"
 
+	"github.com/chenmingyong0423/go-mongox/v2/callback"
 	"github.com/chenmingyong0423/go-mongox/v2/operation"
 
 	"github.com/stretchr/testify/require"
@@ -380,3 +381,29 @@ func TestRegisterPlugin_BeforeAny(t *testing.T) {
 		assert.False(t, isCalled)
 	})
 }
+
+func TestRegisterPlugin_Order(t *testing.T) {
+	c := getMongoClient(t)
+	db := newDatabase(NewClient(c, &Config{}), "db-test")
+	var names []string
+	record := func(name string) callback.CbFn {
+		return func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+			names = append(names, name)
+			return nil
+		}
+	}
+
+	require.NoError(t, db.RegisterPlugin("cache", record("cache"), operation.OpTypeBeforeFind, callback.After("tenancy")))
+	require.NoError(t, db.RegisterPlugin("tenancy", record("tenancy"), operation.OpTypeBeforeFind, callback.Before(callback.SoftDeletePlugin)))
+	require.NoError(t, db.ReplacePlugin(callback.SoftDeletePlugin, record("softDelete"), operation.OpTypeBeforeFind))
+	err := db.RegisterPlugin("audit", record("audit"), operation.OpTypeBeforeFind, callback.Before("tenancy"), callback.After("cache"))
+	require.ErrorIs(t, err, callback.ErrPluginCycle)
+
+	require.NoError(t, db.callbacks.Execute(context.Background(), operation.NewOpContext(nil), operation.OpTypeBeforeFind))
+	require.Equal(t, []string{"tenancy", "softDelete", "cache"}, names)
+
+	names = nil
+	db.DisablePlugin(callback.SoftDeletePlugin)
+	require.NoError(t, db.callbacks.Execute(context.Background(), operation.NewOpContext(nil), operation.OpTypeBeforeFind))
+	require.Equal(t, []string{"tenancy", "cache"}, names)
+}
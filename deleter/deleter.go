@@ -22,6 +22,7 @@ import (
 
 	"github.com/chenmingyong0423/go-mongox/v2/callback"
 
+	"github.com/chenmingyong0423/go-mongox/v2/internal/pkg/utils"
 	"github.com/chenmingyong0423/go-mongox/v2/operation"
 
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -37,19 +38,23 @@ type IDeleter[T any] interface {
 var _ IDeleter[any] = (*Deleter[any])(nil)
 
 func NewDeleter[T any](collection *mongo.Collection, dbCallbacks *callback.Callback, fields []*field.Filed) *Deleter[T] {
-	return &Deleter[T]{collection: collection, dbCallbacks: dbCallbacks, fields: fields}
+	return &Deleter[T]{collection: collection, dbCallbacks: dbCallbacks, fields: fields, clock: time.Now}
 }
 
 type Deleter[T any] struct {
 	collection *mongo.Collection
 	fields     []*field.Filed
 
-	filter    any
-	modelHook any
+	filter      any
+	modelHook   any
+	forceDelete bool
 
 	dbCallbacks *callback.Callback
 	beforeHooks []beforeHookFn
 	afterHooks  []afterHookFn
+
+	clock   func() time.Time
+	timeout time.Duration
 }
 
 func (d *Deleter[T]) RegisterBeforeHooks(hooks ...beforeHookFn) *Deleter[T] {
@@ -62,15 +67,16 @@ func (d *Deleter[T]) RegisterAfterHooks(hooks ...afterHookFn) *Deleter[T] {
 	return d
 }
 
+// preActionHandler runs the before callbacks and hooks, the onError callbacks are run with the error if one of them fails
 func (d *Deleter[T]) preActionHandler(ctx context.Context, globalOpContext *operation.OpContext, opContext *OpContext, opType operation.OpType) error {
 	err := d.dbCallbacks.Execute(ctx, globalOpContext, opType)
 	if err != nil {
-		return err
+		return d.dbCallbacks.OnError(globalOpContext.Context(ctx), globalOpContext, err)
 	}
 	for _, beforeHook := range d.beforeHooks {
 		err = beforeHook(ctx, opContext)
 		if err != nil {
-			return err
+			return d.dbCallbacks.OnError(globalOpContext.Context(ctx), globalOpContext, err)
 		}
 	}
 	return nil
@@ -96,23 +102,56 @@ func (d *Deleter[T]) Filter(filter any) *Deleter[T] {
 	return d
 }
 
+// ForceDelete is used to delete the documents permanently even if soft delete is enabled,
+// the soft-deleted documents are matched as well
+func (d *Deleter[T]) ForceDelete() *Deleter[T] {
+	d.forceDelete = true
+	return d
+}
+
 func (d *Deleter[T]) ModelHook(modelHook any) *Deleter[T] {
 	d.modelHook = modelHook
 	return d
 }
 
+// Clock is used to set the function which returns the current time of the delete, time.Now by default
+func (d *Deleter[T]) Clock(clock func() time.Time) *Deleter[T] {
+	d.clock = clock
+	return d
+}
+
+// Timeout is used to set the timeout of the delete, it is ignored if the context already has a deadline
+func (d *Deleter[T]) Timeout(timeout time.Duration) *Deleter[T] {
+	d.timeout = timeout
+	return d
+}
+
 func (d *Deleter[T]) DeleteOne(ctx context.Context, opts ...options.Lister[options.DeleteOneOptions]) (*mongo.DeleteResult, error) {
-	currentTime := time.Now()
-	globalOpContext := operation.NewOpContext(d.collection, operation.WithFilter(d.filter), operation.WithMongoOptions(opts), operation.WithModelHook(d.modelHook), operation.WithFields(d.fields), operation.WithStartTime(currentTime))
+	ctx, cancel := utils.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	currentTime := d.clock()
+	globalOpContext := operation.NewOpContext(d.collection, operation.WithFilter(d.filter), operation.WithMongoOptions(opts), operation.WithModelHook(d.modelHook), operation.WithFields(d.fields), operation.WithStartTime(currentTime), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameDeleteOne), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(d.forceDelete))
 	opContext := NewOpContext(d.collection, d.filter, WithMongoOptions(opts), WithModelHook(d.modelHook), WithFields(d.fields), WithStartTime(currentTime))
 	err := d.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeDelete)
 	if err != nil {
 		return nil, err
 	}
-
-	result, err := d.collection.DeleteOne(ctx, d.filter, opts...)
+	ctx = globalOpContext.Context(ctx)
+
+	var result *mongo.DeleteResult
+	if globalOpContext.Updates != nil {
+		// soft delete
+		var updateResult *mongo.UpdateResult
+		updateResult, err = d.collection.UpdateOne(ctx, globalOpContext.Filter, globalOpContext.Updates, deleteOneToUpdateOptions(opts)...)
+		if err == nil {
+			result = &mongo.DeleteResult{DeletedCount: updateResult.ModifiedCount, Acknowledged: updateResult.Acknowledged}
+		}
+	} else {
+		result, err = d.collection.DeleteOne(ctx, globalOpContext.Filter, opts...)
+	}
+	globalOpContext.Duration = d.clock().Sub(currentTime)
 	if err != nil {
-		return nil, err
+		return nil, d.dbCallbacks.OnError(ctx, globalOpContext, err)
 	}
 
 	globalOpContext.Result = result
@@ -126,17 +165,31 @@ func (d *Deleter[T]) DeleteOne(ctx context.Context, opts ...options.Lister[optio
 }
 
 func (d *Deleter[T]) DeleteMany(ctx context.Context, opts ...options.Lister[options.DeleteManyOptions]) (*mongo.DeleteResult, error) {
-	currentTime := time.Now()
-	globalOpContext := operation.NewOpContext(d.collection, operation.WithFilter(d.filter), operation.WithMongoOptions(opts), operation.WithModelHook(d.modelHook), operation.WithFields(d.fields), operation.WithStartTime(currentTime))
+	ctx, cancel := utils.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	currentTime := d.clock()
+	globalOpContext := operation.NewOpContext(d.collection, operation.WithFilter(d.filter), operation.WithMongoOptions(opts), operation.WithModelHook(d.modelHook), operation.WithFields(d.fields), operation.WithStartTime(currentTime), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameDeleteMany), operation.WithMulti(true), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(d.forceDelete))
 	opContext := NewOpContext(d.collection, d.filter, WithMongoOptions(opts), WithModelHook(d.modelHook), WithFields(d.fields), WithStartTime(currentTime))
 	err := d.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeDelete)
 	if err != nil {
 		return nil, err
 	}
-
-	result, err := d.collection.DeleteMany(ctx, d.filter, opts...)
+	ctx = globalOpContext.Context(ctx)
+
+	var result *mongo.DeleteResult
+	if globalOpContext.Updates != nil {
+		// soft delete
+		var updateResult *mongo.UpdateResult
+		updateResult, err = d.collection.UpdateMany(ctx, globalOpContext.Filter, globalOpContext.Updates, deleteManyToUpdateOptions(opts)...)
+		if err == nil {
+			result = &mongo.DeleteResult{DeletedCount: updateResult.ModifiedCount, Acknowledged: updateResult.Acknowledged}
+		}
+	} else {
+		result, err = d.collection.DeleteMany(ctx, globalOpContext.Filter, opts...)
+	}
+	globalOpContext.Duration = d.clock().Sub(currentTime)
 	if err != nil {
-		return nil, err
+		return nil, d.dbCallbacks.OnError(ctx, globalOpContext, err)
 	}
 
 	globalOpContext.Result = result
@@ -148,3 +201,51 @@ func (d *Deleter[T]) DeleteMany(ctx context.Context, opts ...options.Lister[opti
 
 	return result, nil
 }
+
+// deleteOneToUpdateOptions converts the options of DeleteOne into the ones of the UpdateOne of a soft delete
+func deleteOneToUpdateOptions(opts []options.Lister[options.DeleteOneOptions]) []options.Lister[options.UpdateOneOptions] {
+	deleteOpts := &options.DeleteOneOptions{}
+	for _, opt := range opts {
+		for _, fn := range opt.List() {
+			_ = fn(deleteOpts)
+		}
+	}
+	updateOpts := options.UpdateOne()
+	if deleteOpts.Collation != nil {
+		updateOpts.SetCollation(deleteOpts.Collation)
+	}
+	if deleteOpts.Comment != nil {
+		updateOpts.SetComment(deleteOpts.Comment)
+	}
+	if deleteOpts.Hint != nil {
+		updateOpts.SetHint(deleteOpts.Hint)
+	}
+	if deleteOpts.Let != nil {
+		updateOpts.SetLet(deleteOpts.Let)
+	}
+	return []options.Lister[options.UpdateOneOptions]{updateOpts}
+}
+
+// deleteManyToUpdateOptions converts the options of DeleteMany into the ones of the UpdateMany of a soft delete
+func deleteManyToUpdateOptions(opts []options.Lister[options.DeleteManyOptions]) []options.Lister[options.UpdateManyOptions] {
+	deleteOpts := &options.DeleteManyOptions{}
+	for _, opt := range opts {
+		for _, fn := range opt.List() {
+			_ = fn(deleteOpts)
+		}
+	}
+	updateOpts := options.UpdateMany()
+	if deleteOpts.Collation != nil {
+		updateOpts.SetCollation(deleteOpts.Collation)
+	}
+	if deleteOpts.Comment != nil {
+		updateOpts.SetComment(deleteOpts.Comment)
+	}
+	if deleteOpts.Hint != nil {
+		updateOpts.SetHint(deleteOpts.Hint)
+	}
+	if deleteOpts.Let != nil {
+		updateOpts.SetLet(deleteOpts.Let)
+	}
+	return []options.Lister[options.UpdateManyOptions]{updateOpts}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"go.uber.org/mock/gomock"
 )
 
@@ -183,3 +184,45 @@ func TestDeleter_DeleteMany(t *testing.T) {
 		})
 	}
 }
+
+func Test_deleteOneToUpdateOptions(t *testing.T) {
+	collation := &options.Collation{Locale: "en", Strength: 2}
+	opts := deleteOneToUpdateOptions([]options.Lister[options.DeleteOneOptions]{
+		options.DeleteOne().SetCollation(collation).SetHint("name_1"),
+		options.DeleteOne().SetComment("soft delete").SetLet(bson.M{"x": 1}),
+	})
+
+	got := &options.UpdateOneOptions{}
+	for _, opt := range opts {
+		for _, fn := range opt.List() {
+			assert.NoError(t, fn(got))
+		}
+	}
+	assert.Equal(t, &options.UpdateOneOptions{
+		Collation: collation,
+		Hint:      "name_1",
+		Comment:   "soft delete",
+		Let:       bson.M{"x": 1},
+	}, got)
+}
+
+func Test_deleteManyToUpdateOptions(t *testing.T) {
+	collation := &options.Collation{Locale: "en", Strength: 2}
+	opts := deleteManyToUpdateOptions([]options.Lister[options.DeleteManyOptions]{
+		options.DeleteMany().SetCollation(collation).SetHint("name_1"),
+		options.DeleteMany().SetComment("soft delete").SetLet(bson.M{"x": 1}),
+	})
+
+	got := &options.UpdateManyOptions{}
+	for _, opt := range opts {
+		for _, fn := range opt.List() {
+			assert.NoError(t, fn(got))
+		}
+	}
+	assert.Equal(t, &options.UpdateManyOptions{
+		Collation: collation,
+		Hint:      "name_1",
+		Comment:   "soft delete",
+		Let:       bson.M{"x": 1},
+	}, got)
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package field
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultFunc generates the default value of a field tagged with mongox:"default:fn=<name>", see RegisterDefault
+type DefaultFunc func() any
+
+var (
+	defaultFuncsMu sync.RWMutex
+	defaultFuncs   = make(map[string]DefaultFunc)
+)
+
+// RegisterDefault registers the generator of the default values of the fields tagged with mongox:"default:fn=<name>",
+// e.g. RegisterDefault("uuid", func() any { return uuid.NewString() }). It replaces the generator registered with the same name.
+// The value returned must be assignable to the field, or to the element of a pointer field.
+func RegisterDefault(name string, fn DefaultFunc) {
+	defaultFuncsMu.Lock()
+	defer defaultFuncsMu.Unlock()
+	defaultFuncs[name] = fn
+}
+
+// DefaultValue returns the default value of the field, false if the field has no default.
+// The value of a pointer field is the one of the element.
+func DefaultValue(fd *Filed) (any, bool, error) {
+	if fd.DefaultFn == "" {
+		return fd.Default, fd.Default != nil, nil
+	}
+	defaultFuncsMu.RLock()
+	fn, ok := defaultFuncs[fd.DefaultFn]
+	defaultFuncsMu.RUnlock()
+	if !ok {
+		return nil, false, fmt.Errorf("mongox: the default function %q of field %s is not registered", fd.DefaultFn, fd.MongoField)
+	}
+	return fn(), true, nil
+}
+
+// parseDefault parses the value of the mongox:"default:<value>" tag for the type of the field,
+// an error is returned if the value is invalid or if the type is not a string, a bool or a number
+func parseDefault(value string, fd *Filed) (any, error) {
+	fieldType := fd.FieldType
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	var v any
+	var err error
+	switch fieldType.Kind() {
+	case reflect.String:
+		v = value
+	case reflect.Bool:
+		v, err = strconv.ParseBool(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if fieldType == reflect.TypeOf(time.Duration(0)) {
+			v, err = time.ParseDuration(value)
+			break
+		}
+		v, err = strconv.ParseInt(value, 10, fieldType.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err = strconv.ParseUint(value, 10, fieldType.Bits())
+	case reflect.Float32, reflect.Float64:
+		v, err = strconv.ParseFloat(value, fieldType.Bits())
+	default:
+		return nil, fmt.Errorf("mongox: the default value of field %s is not supported for the type %s, use default:fn=<name>", fd.MongoField, fd.FieldType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mongox: the default value %q of field %s is invalid: %w", value, fd.MongoField, err)
+	}
+	return reflect.ValueOf(v).Convert(fieldType).Interface(), nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package field
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDefault(t *testing.T) {
+	type Status string
+	type model struct {
+		Name    string        `bson:"name" mongox:"default:anonymous"`
+		Status  Status        `bson:"status" mongox:"default:active"`
+		Active  bool          `bson:"active" mongox:"default:true"`
+		Age     int8          `bson:"age" mongox:"default:18"`
+		Count   uint32        `bson:"count" mongox:"default:7"`
+		Score   *float64      `bson:"score" mongox:"default:1.5"`
+		Timeout time.Duration `bson:"timeout" mongox:"default:1m30s"`
+		Code    string        `bson:"code" mongox:"default:fn=code"`
+		Plain   string        `bson:"plain"`
+	}
+
+	fields := ParseFields(model{})
+	want := []any{"anonymous", Status("active"), true, int8(18), uint32(7), 1.5, 90 * time.Second, nil, nil}
+	for i, fd := range fields {
+		require.Equal(t, want[i], fd.Default, fd.Name)
+	}
+	require.Equal(t, "code", fields[7].DefaultFn)
+	require.Equal(t, reflect.TypeOf(Status("")), reflect.TypeOf(fields[1].Default))
+}
+
+func TestParseDefault_invalid(t *testing.T) {
+	type overflow struct {
+		Age int8 `bson:"age" mongox:"default:300"`
+	}
+	type invalid struct {
+		Active bool `bson:"active" mongox:"default:yes"`
+	}
+	type unsupported struct {
+		Tags []string `bson:"tags" mongox:"default:a"`
+	}
+	require.PanicsWithError(t, `mongox: the default value "300" of field age is invalid: strconv.ParseInt: parsing "300": value out of range`, func() {
+		ParseFields(overflow{})
+	})
+	require.PanicsWithError(t, `mongox: the default value "yes" of field active is invalid: strconv.ParseBool: parsing "yes": invalid syntax`, func() {
+		ParseFields(invalid{})
+	})
+	require.PanicsWithError(t, "mongox: the default value of field tags is not supported for the type []string, use default:fn=<name>", func() {
+		ParseFields(unsupported{})
+	})
+}
+
+func TestDefaultValue(t *testing.T) {
+	value, ok, err := DefaultValue(&Filed{MongoField: "plain"})
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Nil(t, value)
+
+	value, ok, err = DefaultValue(&Filed{MongoField: "name", Default: "anonymous"})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "anonymous", value)
+
+	_, _, err = DefaultValue(&Filed{MongoField: "code", DefaultFn: "test-missing"})
+	require.EqualError(t, err, `mongox: the default function "test-missing" of field code is not registered`)
+
+	n := 0
+	RegisterDefault("test-counter", func() any {
+		n++
+		return n
+	})
+	for i := 1; i <= 2; i++ {
+		value, ok, err = DefaultValue(&Filed{MongoField: "code", DefaultFn: "test-counter"})
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, i, value)
+	}
+}
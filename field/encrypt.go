@@ -0,0 +1,59 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package field
+
+import (
+	"context"
+	"errors"
+)
+
+// EncryptMode is the mode of a field tagged with mongox:"encrypt"
+type EncryptMode int
+
+const (
+	// Randomized encrypts the same value into different ciphertexts, the field can not be queried
+	Randomized EncryptMode = 1
+	// Deterministic encrypts the same value into the same ciphertext, the equality filters on the field are encrypted as well
+	Deterministic EncryptMode = 2
+)
+
+// ErrNoKeyProvider is returned when a model has encrypted fields but no KeyProvider is set, see mongox.WithKeyProvider
+var ErrNoKeyProvider = errors.New("mongox: no key provider for the encrypted fields")
+
+// KeyProvider provides the AES keys of the encrypted fields, the values are encrypted with AES-GCM
+type KeyProvider interface {
+	// Key returns the 16, 24 or 32 bytes key of the field, the name of the field in mongo is given so that the fields may use different keys
+	Key(ctx context.Context, mongoField string) ([]byte, error)
+}
+
+// StaticKey is a KeyProvider which returns the same local key for all the fields
+type StaticKey []byte
+
+func (k StaticKey) Key(context.Context, string) ([]byte, error) {
+	return k, nil
+}
+
+// EncryptedFields returns the fields tagged with mongox:"encrypt", including the ones of the inlined structs
+func EncryptedFields(fields []*Filed) []*Filed {
+	var encrypted []*Filed
+	for _, fd := range fields {
+		if fd.InlinedFields != nil {
+			encrypted = append(encrypted, EncryptedFields(fd.InlinedFields)...)
+		} else if fd.Encrypt != 0 {
+			encrypted = append(encrypted, fd)
+		}
+	}
+	return encrypted
+}
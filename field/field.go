@@ -15,7 +15,10 @@
 package field
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -28,10 +31,45 @@ type Filed struct {
 	FieldType      reflect.Type
 	AutoCreateTime TimeType
 	AutoUpdateTime TimeType
+	// SoftDelete is the time type of the deletion time, the field marks a document as soft-deleted when it is set
+	SoftDelete TimeType
+	// Version marks the field used for optimistic concurrency control, it is incremented by every update.
+	// Parse returns an error if the field is not an integer.
+	Version bool
+	// Index is the index declared by the mongox tag, nil if the field is not indexed
+	Index *Index
+	// Sensitive marks the field whose values must not be logged
+	Sensitive bool
+	// Encrypt is the mode of the client-side encryption of the field, 0 if the field is not encrypted
+	Encrypt EncryptMode
+	// KeyProvider provides the key of the encrypted field, it is set by the collection
+	KeyProvider KeyProvider
+	// Default is the value of the mongox:"default:<value>" tag parsed for the type of the field, nil if the field has none,
+	// the value can not contain a comma. Parse returns an error if the value is invalid for the type.
+	// DefaultFn is the name of the generator of the mongox:"default:fn=<name>" tag. See DefaultValue.
+	Default   any
+	DefaultFn string
 
 	InlinedFields []*Filed
 }
 
+// Index describes the index declared on a field.
+// Fields sharing the same index name are grouped into a compound index.
+type Index struct {
+	Name string
+	// Order is 1 for ascending and -1 for descending
+	Order  int
+	Unique bool
+	Sparse bool
+	// TTL is the expiration of the documents, 0 means no expiration
+	TTL  time.Duration
+	Text bool
+}
+
+// ErrVersionConflict is returned when the version of the document does not match the expected one,
+// the document has been modified by someone else since it was read
+var ErrVersionConflict = errors.New("mongox: version conflict")
+
 type (
 	// TimeType MONGOX time type
 	TimeType int64
@@ -48,20 +86,48 @@ const (
 const (
 	CreatedAt      = "CreatedAt"
 	UpdatedAt      = "UpdatedAt"
+	DeletedAt      = "DeletedAt"
 	AutoCreateTime = "autoCreateTime"
 	AutoUpdateTime = "autoUpdateTime"
+	SoftDelete     = "softDelete"
+	VersionTag     = "version"
+	SensitiveTag   = "sensitive"
+	EncryptTag     = "encrypt"
+	DefaultTag     = "default"
+
+	IndexTag  = "index"
+	UniqueTag = "unique"
+	SparseTag = "sparse"
+	TTLTag    = "ttl"
+	OrderTag  = "order"
+	TextTag   = "text"
 )
 
+// ParseFields is like Parse but panics if a mongox tag is malformed, the model can not be used then
 func ParseFields[T any](doc T) []*Filed {
+	fields, err := Parse(doc)
+	if err != nil {
+		panic(err)
+	}
+	return fields
+}
+
+// Parse parses the fields of the struct and their mongox tags,
+// an error is returned if a tag is malformed, e.g. an unknown time type or a default value invalid for the type of the field
+func Parse[T any](doc T) ([]*Filed, error) {
 	docType := reflect.TypeOf(doc)
 	if docType == nil {
-		return nil
+		return nil, nil
 	}
 	if docType.Kind() == reflect.Ptr {
 		docType = docType.Elem()
 	}
+	return parseFields(docType)
+}
+
+func parseFields(docType reflect.Type) ([]*Filed, error) {
 	if docType.Kind() != reflect.Struct {
-		return nil
+		return nil, nil
 	}
 	numField := docType.NumField()
 	fields := make([]*Filed, 0, numField)
@@ -73,27 +139,31 @@ func ParseFields[T any](doc T) []*Filed {
 		bsonTag := structField.Tag.Get("bson")
 		if structField.Anonymous {
 			if bsonTag == ",inline" {
-				fields = append(fields, &Filed{Name: structField.Name, FieldType: structField.Type, InlinedFields: ParseFields(reflect.New(structField.Type).Elem().Interface())})
+				inlined, err := parseFields(structField.Type)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, &Filed{Name: structField.Name, FieldType: structField.Type, InlinedFields: inlined})
 				continue
 			}
 		}
 
 		fd.MongoField = getMongoField(bsonTag, structField.Name)
 
+		if tag := structField.Tag.Get("mongox"); tag != "" {
+			if err := parseTag(tag, fd); err != nil {
+				return nil, err
+			}
+		}
 		if structField.Name == CreatedAt && structField.Type == reflect.TypeOf(time.Time{}) {
-			fd.AutoCreateTime = UnixTime
+			fd.AutoCreateTime, fd.AutoUpdateTime = UnixTime, 0
 		} else if structField.Name == UpdatedAt && structField.Type == reflect.TypeOf(time.Time{}) {
-			fd.AutoUpdateTime = UnixTime
-		} else {
-			tag := structField.Tag.Get("mongox")
-			if tag != "" {
-				parseTag(tag, fd)
-			}
+			fd.AutoCreateTime, fd.AutoUpdateTime = 0, UnixTime
 		}
 		fields = append(fields, fd)
 	}
 
-	return fields
+	return fields, nil
 }
 
 func getMongoField(bsonTag string, defaultValue string) string {
@@ -107,31 +177,149 @@ func getMongoField(bsonTag string, defaultValue string) string {
 	return split[0]
 }
 
-func parseTag(tag string, fd *Filed) {
+func parseTag(tag string, fd *Filed) (err error) {
 	split := strings.Split(tag, ",")
 	for _, s := range split {
 		switch {
 		case s == "autoID":
 			fd.AutoID = true
-		case strings.HasPrefix(s, AutoCreateTime):
-			fd.AutoCreateTime = parseTimeType(s)
-		case strings.HasPrefix(s, AutoUpdateTime):
-			fd.AutoUpdateTime = parseTimeType(s)
+		case s == AutoCreateTime, strings.HasPrefix(s, AutoCreateTime+":"):
+			fd.AutoCreateTime, err = parseTimeType(s, fd)
+		case s == AutoUpdateTime, strings.HasPrefix(s, AutoUpdateTime+":"):
+			fd.AutoUpdateTime, err = parseTimeType(s, fd)
+		case s == SoftDelete:
+			fd.SoftDelete = UnixTime
+		case strings.HasPrefix(s, SoftDelete+":"):
+			fd.SoftDelete, err = parseTimeType(s, fd)
+		case s == VersionTag:
+			if !isInteger(fd.FieldType) {
+				return fmt.Errorf("mongox: the version field %s must be an integer, not a %s", fd.MongoField, fd.FieldType)
+			}
+			fd.Version = true
+		case s == SensitiveTag:
+			fd.Sensitive = true
+		case s == EncryptTag, s == EncryptTag+":randomized":
+			fd.Encrypt = Randomized
+		case s == EncryptTag+":deterministic":
+			fd.Encrypt = Deterministic
+		case strings.HasPrefix(s, EncryptTag+":"):
+			return fmt.Errorf("mongox: the encryption mode %q of field %s is invalid, use randomized or deterministic", strings.TrimPrefix(s, EncryptTag+":"), fd.MongoField)
+		case strings.HasPrefix(s, DefaultTag+":fn="):
+			fd.DefaultFn = strings.TrimPrefix(s, DefaultTag+":fn=")
+		case strings.HasPrefix(s, DefaultTag+":"):
+			fd.Default, err = parseDefault(strings.TrimPrefix(s, DefaultTag+":"), fd)
+		case s == IndexTag:
+			index(fd)
+		case strings.HasPrefix(s, IndexTag+":"):
+			index(fd).Name = strings.TrimPrefix(s, IndexTag+":")
+		case s == UniqueTag:
+			index(fd).Unique = true
+		case s == SparseTag:
+			index(fd).Sparse = true
+		case s == TextTag:
+			index(fd).Text = true
+		case strings.HasPrefix(s, TTLTag+":"):
+			index(fd).TTL, err = parseTTL(strings.TrimPrefix(s, TTLTag+":"), fd)
+		case strings.HasPrefix(s, OrderTag+":"):
+			switch order := strings.TrimPrefix(s, OrderTag+":"); order {
+			case "1":
+				index(fd).Order = 1
+			case "-1":
+				index(fd).Order = -1
+			default:
+				return fmt.Errorf("mongox: the index order %q of field %s is invalid, use 1 or -1", order, fd.MongoField)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// index returns the index of the field, it is created if the field has none
+func index(fd *Filed) *Index {
+	if fd.Index == nil {
+		fd.Index = &Index{Order: 1}
+	}
+	return fd.Index
+}
+
+func isInteger(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// parseTTL parses a duration such as 24h, a plain number is taken as seconds
+func parseTTL(ttl string, fd *Filed) (time.Duration, error) {
+	duration, err := time.ParseDuration(ttl)
+	if seconds, intErr := strconv.ParseInt(ttl, 10, 64); intErr == nil {
+		duration, err = time.Duration(seconds)*time.Second, nil
+	}
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("mongox: the ttl %q of field %s is invalid, use a positive number of seconds or a duration such as 24h", ttl, fd.MongoField)
+	}
+	return duration, nil
+}
+
+// parseTimeType parses the time type of the tag, e.g. autoCreateTime:milli, the tag without a time type gives 0
+func parseTimeType(tag string, fd *Filed) (TimeType, error) {
+	if !strings.Contains(tag, ":") {
+		return 0, nil
+	}
+	switch timeType := strings.SplitN(tag, ":", 2)[1]; timeType {
+	case "second":
+		return UnixSecond, nil
+	case "milli":
+		return UnixMillisecond, nil
+	case "nano":
+		return UnixNanosecond, nil
+	default:
+		return 0, fmt.Errorf("mongox: the time type %q of field %s is invalid, use second, milli or nano", timeType, fd.MongoField)
+	}
+}
+
+// SoftDeleteField returns the field which holds the deletion time, nil if soft delete is not enabled
+func SoftDeleteField(fields []*Filed) *Filed {
+	for _, fd := range fields {
+		if fd.InlinedFields != nil {
+			if inlined := SoftDeleteField(fd.InlinedFields); inlined != nil {
+				return inlined
+			}
+		} else if fd.SoftDelete != 0 {
+			return fd
+		}
+	}
+	return nil
+}
+
+// VersionField returns the field used for optimistic concurrency control, nil if the model has none
+func VersionField(fields []*Filed) *Filed {
+	for _, fd := range fields {
+		if fd.InlinedFields != nil {
+			if inlined := VersionField(fd.InlinedFields); inlined != nil {
+				return inlined
+			}
+		} else if fd.Version {
+			return fd
 		}
 	}
+	return nil
 }
 
-func parseTimeType(tag string) TimeType {
-	if strings.Contains(tag, ":") {
-		timeType := strings.Split(tag, ":")[1]
-		switch timeType {
-		case "second":
-			return UnixSecond
-		case "milli":
-			return UnixMillisecond
-		case "nano":
-			return UnixNanosecond
+// SensitiveFields returns the fields whose values must not be logged, including the ones of the inlined structs
+func SensitiveFields(fields []*Filed) []*Filed {
+	var sensitive []*Filed
+	for _, fd := range fields {
+		if fd.InlinedFields != nil {
+			sensitive = append(sensitive, SensitiveFields(fd.InlinedFields)...)
+		} else if fd.Sensitive {
+			sensitive = append(sensitive, fd)
 		}
 	}
-	return 0
+	return sensitive
 }
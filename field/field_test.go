@@ -68,8 +68,7 @@ func TestParseFields(t *testing.T) {
 				UpdateNanoTime   int64         `bson:"update_nano_time" mongox:"autoUpdateTime:nano"`
 
 				NoneBsonTagField    string
-				InvalidBsonTagField string    `bson:",omitempty"`
-				InvalidTimeTagField time.Time `bson:"invalid_time_tag_field" mongox:"autoCreateTime:time"`
+				InvalidBsonTagField string `bson:",omitempty"`
 			}{},
 			want: []*Filed{
 				{
@@ -146,12 +145,6 @@ func TestParseFields(t *testing.T) {
 					MongoField: "InvalidBsonTagField",
 					FieldType:  reflect.TypeOf(""),
 				},
-				{
-					Name:           "InvalidTimeTagField",
-					MongoField:     "invalid_time_tag_field",
-					FieldType:      reflect.TypeOf(time.Time{}),
-					AutoCreateTime: 0,
-				},
 			},
 		},
 		{
@@ -239,6 +232,84 @@ func TestParseFields(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "soft delete",
+			doc: struct {
+				DeletedAt       time.Time `bson:"deleted_at,omitempty" mongox:"softDelete"`
+				DeleteMilliTime int64     `bson:"delete_milli_time" mongox:"softDelete:milli"`
+				Version         int64     `bson:"version" mongox:"version"`
+			}{},
+			want: []*Filed{
+				{
+					Name:       "DeletedAt",
+					MongoField: "deleted_at",
+					FieldType:  reflect.TypeOf(time.Time{}),
+					SoftDelete: UnixTime,
+				},
+				{
+					Name:       "DeleteMilliTime",
+					MongoField: "delete_milli_time",
+					FieldType:  reflect.TypeOf(int64(0)),
+					SoftDelete: UnixMillisecond,
+				},
+				{
+					Name:       "Version",
+					MongoField: "version",
+					FieldType:  reflect.TypeOf(int64(0)),
+					Version:    true,
+				},
+			},
+		},
+		{
+			name: "index",
+			doc: struct {
+				Name      string    `bson:"name" mongox:"index,unique,sparse"`
+				Age       int       `bson:"age" mongox:"index:idx_age_city"`
+				City      string    `bson:"city" mongox:"index:idx_age_city,order:-1"`
+				Bio       string    `bson:"bio" mongox:"text"`
+				ExpiredAt time.Time `bson:"expired_at" mongox:"ttl:24h"`
+				CreatedAt time.Time `bson:"created_at" mongox:"index,order:-1,ttl:3600"`
+			}{},
+			want: []*Filed{
+				{
+					Name:       "Name",
+					MongoField: "name",
+					FieldType:  reflect.TypeOf(""),
+					Index:      &Index{Order: 1, Unique: true, Sparse: true},
+				},
+				{
+					Name:       "Age",
+					MongoField: "age",
+					FieldType:  reflect.TypeOf(0),
+					Index:      &Index{Name: "idx_age_city", Order: 1},
+				},
+				{
+					Name:       "City",
+					MongoField: "city",
+					FieldType:  reflect.TypeOf(""),
+					Index:      &Index{Name: "idx_age_city", Order: -1},
+				},
+				{
+					Name:       "Bio",
+					MongoField: "bio",
+					FieldType:  reflect.TypeOf(""),
+					Index:      &Index{Order: 1, Text: true},
+				},
+				{
+					Name:       "ExpiredAt",
+					MongoField: "expired_at",
+					FieldType:  reflect.TypeOf(time.Time{}),
+					Index:      &Index{Order: 1, TTL: 24 * time.Hour},
+				},
+				{
+					Name:           "CreatedAt",
+					MongoField:     "created_at",
+					FieldType:      reflect.TypeOf(time.Time{}),
+					AutoCreateTime: UnixTime,
+					Index:          &Index{Order: -1, TTL: time.Hour},
+				},
+			},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -247,3 +318,189 @@ func TestParseFields(t *testing.T) {
 		})
 	}
 }
+
+func TestParse_malformedTags(t *testing.T) {
+	type model struct {
+		Name string `bson:"name" mongox:"encrypt:aes"`
+	}
+	testCases := []struct {
+		name    string
+		doc     any
+		wantErr string
+	}{
+		{
+			name: "time type",
+			doc: struct {
+				CreatedAt time.Time `bson:"created_at" mongox:"autoCreateTime:time"`
+			}{},
+			wantErr: `mongox: the time type "time" of field created_at is invalid, use second, milli or nano`,
+		},
+		{
+			name: "soft delete time type",
+			doc: struct {
+				DeletedAt int64 `bson:"deleted_at" mongox:"softDelete:seconds"`
+			}{},
+			wantErr: `mongox: the time type "seconds" of field deleted_at is invalid, use second, milli or nano`,
+		},
+		{
+			name: "ttl",
+			doc: struct {
+				ExpiredAt time.Time `bson:"expired_at" mongox:"ttl:1day"`
+			}{},
+			wantErr: `mongox: the ttl "1day" of field expired_at is invalid, use a positive number of seconds or a duration such as 24h`,
+		},
+		{
+			name: "negative ttl",
+			doc: struct {
+				ExpiredAt time.Time `bson:"expired_at" mongox:"ttl:-60"`
+			}{},
+			wantErr: `mongox: the ttl "-60" of field expired_at is invalid, use a positive number of seconds or a duration such as 24h`,
+		},
+		{
+			name: "order",
+			doc: struct {
+				Age int `bson:"age" mongox:"index,order:desc"`
+			}{},
+			wantErr: `mongox: the index order "desc" of field age is invalid, use 1 or -1`,
+		},
+		{
+			name: "inlined encryption mode",
+			doc: struct {
+				model `bson:",inline"`
+			}{},
+			wantErr: `mongox: the encryption mode "aes" of field name is invalid, use randomized or deterministic`,
+		},
+		{
+			name: "version",
+			doc: struct {
+				Version string `bson:"version" mongox:"version"`
+			}{},
+			wantErr: "mongox: the version field version must be an integer, not a string",
+		},
+		{
+			name: "default",
+			doc: struct {
+				Age int8 `bson:"age" mongox:"default:300"`
+			}{},
+			wantErr: `mongox: the default value "300" of field age is invalid: strconv.ParseInt: parsing "300": value out of range`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fields, err := Parse(tc.doc)
+			require.EqualError(t, err, tc.wantErr)
+			require.Nil(t, fields)
+			require.PanicsWithError(t, tc.wantErr, func() {
+				ParseFields(tc.doc)
+			})
+		})
+	}
+}
+
+func TestSoftDeleteField(t *testing.T) {
+	type model struct {
+		ID        bson.ObjectID `bson:"_id,omitempty" mongox:"autoID"`
+		DeletedAt time.Time     `bson:"deleted_at,omitempty" mongox:"softDelete"`
+	}
+
+	testCases := []struct {
+		name string
+		doc  any
+		want string
+	}{
+		{
+			name: "not enabled",
+			doc: struct {
+				DeletedAt time.Time `bson:"deleted_at,omitempty"`
+			}{},
+		},
+		{
+			name: "enabled",
+			doc: struct {
+				DeletedAt time.Time `bson:"deleted_at,omitempty" mongox:"softDelete"`
+			}{},
+			want: "deleted_at",
+		},
+		{
+			name: "inlined struct",
+			doc: struct {
+				model `bson:",inline"`
+				Name  string `bson:"name"`
+			}{},
+			want: "deleted_at",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fd := SoftDeleteField(ParseFields(tc.doc))
+			if tc.want == "" {
+				require.Nil(t, fd)
+				return
+			}
+			require.Equal(t, tc.want, fd.MongoField)
+		})
+	}
+}
+
+func TestVersionField(t *testing.T) {
+	type model struct {
+		ID      bson.ObjectID `bson:"_id,omitempty" mongox:"autoID"`
+		Version int64         `bson:"version" mongox:"version"`
+	}
+
+	require.Nil(t, VersionField(ParseFields(struct {
+		Version int64 `bson:"version"`
+	}{})))
+	require.Equal(t, "version", VersionField(ParseFields(struct {
+		model `bson:",inline"`
+		Name  string `bson:"name"`
+	}{})).MongoField)
+	require.NotNil(t, VersionField(ParseFields(struct {
+		Version uint32 `bson:"version" mongox:"version"`
+	}{})))
+
+	require.PanicsWithError(t, "mongox: the version field version must be an integer, not a string", func() {
+		ParseFields(struct {
+			Version string `bson:"version" mongox:"version"`
+		}{})
+	})
+}
+
+func TestSensitiveFields(t *testing.T) {
+	type contact struct {
+		Phone string `bson:"phone" mongox:"sensitive"`
+	}
+
+	require.Empty(t, SensitiveFields(ParseFields(struct {
+		Name string `bson:"name"`
+	}{})))
+
+	fields := SensitiveFields(ParseFields(struct {
+		contact `bson:",inline"`
+		Name    string `bson:"name"`
+		IDCard  string `bson:"id_card" mongox:"sensitive"`
+	}{}))
+	require.Len(t, fields, 2)
+	require.Equal(t, "phone", fields[0].MongoField)
+	require.Equal(t, "id_card", fields[1].MongoField)
+}
+
+func TestEncryptedFields(t *testing.T) {
+	require.Empty(t, EncryptedFields(ParseFields(struct {
+		Name string `bson:"name"`
+	}{})))
+
+	fields := EncryptedFields(ParseFields(struct {
+		Name  string `bson:"name"`
+		Phone string `bson:"phone" mongox:"encrypt"`
+		Email string `bson:"email" mongox:"encrypt:deterministic"`
+		SSN   string `bson:"ssn" mongox:"encrypt:randomized"`
+	}{}))
+	require.Len(t, fields, 3)
+	require.Equal(t, "phone", fields[0].MongoField)
+	require.Equal(t, Randomized, fields[0].Encrypt)
+	require.Equal(t, "email", fields[1].MongoField)
+	require.Equal(t, Deterministic, fields[1].Encrypt)
+	require.Equal(t, "ssn", fields[2].MongoField)
+	require.Equal(t, Randomized, fields[2].Encrypt)
+}
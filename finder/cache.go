@@ -0,0 +1,148 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/chenmingyong0423/go-mongox/v2/cache"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// cacheKey returns the key of the result of the query, false if the result is not cached.
+// The results are not cached in a transaction, where the query may see the writes not committed yet.
+func (f *Finder[T]) cacheKey(opCtx *operation.OpContext, queryOptions bson.D) (string, bool) {
+	if f.cacheStore == nil || f.cacheTTL <= 0 || opCtx.InTransaction() {
+		return "", false
+	}
+	key, err := cache.Key(opCtx, queryOptions)
+	return key, err == nil
+}
+
+// cacheGet returns the cached value of the key, the errors of the cache are ignored so that the query is run instead
+func (f *Finder[T]) cacheGet(ctx context.Context, opCtx *operation.OpContext, key string) ([]byte, bool) {
+	value, ok, err := f.cacheStore.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, false
+	}
+	opCtx.Set(cache.HitKey, true)
+	return value, true
+}
+
+func (f *Finder[T]) cacheSet(ctx context.Context, opCtx *operation.OpContext, key string, value []byte) {
+	_ = f.cacheStore.Set(ctx, key, value, f.cacheTTL, cache.Tags(opCtx)...)
+}
+
+// findOneCached reads the document from the cache, or runs the query and caches the document.
+// No document is cached if none matches the filter.
+func (f *Finder[T]) findOneCached(ctx context.Context, opCtx *operation.OpContext, key string, opts []options.Lister[options.FindOneOptions]) *mongo.SingleResult {
+	if value, ok := f.cacheGet(ctx, opCtx, key); ok {
+		return mongo.NewSingleResultFromDocument(bson.Raw(value), nil, nil)
+	}
+	result := f.collection.FindOne(ctx, opCtx.Filter, opts...)
+	if doc, err := result.Raw(); err == nil {
+		f.cacheSet(ctx, opCtx, key, doc)
+	}
+	return result
+}
+
+// findCached reads the documents from the cache, or runs the query and caches the documents
+func (f *Finder[T]) findCached(ctx context.Context, opCtx *operation.OpContext, key string, opts []options.Lister[options.FindOptions]) (*mongo.Cursor, error) {
+	if value, ok := f.cacheGet(ctx, opCtx, key); ok {
+		values, err := bson.Raw(value).Lookup("documents").Array().Values()
+		if err == nil {
+			docs := make([]any, 0, len(values))
+			for _, v := range values {
+				docs = append(docs, v.Document())
+			}
+			return mongo.NewCursorFromDocuments(docs, nil, nil)
+		}
+	}
+
+	mongoCursor, err := f.collection.Find(ctx, opCtx.Filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer mongoCursor.Close(ctx)
+	raws := make([]bson.Raw, 0)
+	if err = mongoCursor.All(ctx, &raws); err != nil {
+		return nil, err
+	}
+	if value, err := bson.Marshal(bson.D{{Key: "documents", Value: raws}}); err == nil {
+		f.cacheSet(ctx, opCtx, key, value)
+	}
+	docs := make([]any, 0, len(raws))
+	for _, raw := range raws {
+		docs = append(docs, raw)
+	}
+	return mongo.NewCursorFromDocuments(docs, nil, nil)
+}
+
+// countCached reads the count from the cache, or runs the count and caches it
+func (f *Finder[T]) countCached(ctx context.Context, opCtx *operation.OpContext, key string, opts []options.Lister[options.CountOptions]) (int64, error) {
+	if value, ok := f.cacheGet(ctx, opCtx, key); ok && len(value) == 8 {
+		return int64(binary.BigEndian.Uint64(value)), nil
+	}
+	count, err := f.collection.CountDocuments(ctx, opCtx.Filter, opts...)
+	if err != nil {
+		return 0, err
+	}
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(count))
+	f.cacheSet(ctx, opCtx, key, value)
+	return count, nil
+}
+
+// findOneCacheOptions returns the options shaping the result of FindOne, they are part of the cache key
+func findOneCacheOptions(opts []options.Lister[options.FindOneOptions]) bson.D {
+	args := &options.FindOneOptions{}
+	for _, opt := range opts {
+		for _, set := range opt.List() {
+			_ = set(args)
+		}
+	}
+	return bson.D{
+		{Key: "projection", Value: args.Projection}, {Key: "sort", Value: args.Sort}, {Key: "skip", Value: args.Skip},
+		{Key: "collation", Value: args.Collation}, {Key: "hint", Value: args.Hint}, {Key: "min", Value: args.Min}, {Key: "max", Value: args.Max},
+	}
+}
+
+func findCacheOptions(opts []options.Lister[options.FindOptions]) bson.D {
+	args := &options.FindOptions{}
+	for _, opt := range opts {
+		for _, set := range opt.List() {
+			_ = set(args)
+		}
+	}
+	return bson.D{
+		{Key: "projection", Value: args.Projection}, {Key: "sort", Value: args.Sort}, {Key: "skip", Value: args.Skip}, {Key: "limit", Value: args.Limit},
+		{Key: "collation", Value: args.Collation}, {Key: "hint", Value: args.Hint}, {Key: "min", Value: args.Min}, {Key: "max", Value: args.Max},
+	}
+}
+
+func countCacheOptions(opts []options.Lister[options.CountOptions]) bson.D {
+	args := &options.CountOptions{}
+	for _, opt := range opts {
+		for _, set := range opt.List() {
+			_ = set(args)
+		}
+	}
+	return bson.D{{Key: "skip", Value: args.Skip}, {Key: "limit", Value: args.Limit}, {Key: "collation", Value: args.Collation}, {Key: "hint", Value: args.Hint}}
+}
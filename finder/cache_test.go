@@ -0,0 +1,45 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func TestCacheOptions(t *testing.T) {
+	skip, limit := int64(10), int64(20)
+
+	require.Equal(t, bson.D{
+		{Key: "projection", Value: bson.M{"name": 1}}, {Key: "sort", Value: bson.M{"age": -1}}, {Key: "skip", Value: &skip},
+		{Key: "collation", Value: (*options.Collation)(nil)}, {Key: "hint", Value: nil}, {Key: "min", Value: nil}, {Key: "max", Value: nil},
+	}, findOneCacheOptions([]options.Lister[options.FindOneOptions]{
+		options.FindOne().SetProjection(bson.M{"name": 1}).SetSkip(10), options.FindOne().SetSort(bson.M{"age": -1}).SetComment("ignored"),
+	}))
+
+	require.Equal(t, bson.D{
+		{Key: "projection", Value: nil}, {Key: "sort", Value: bson.D{{Key: "age", Value: -1}}}, {Key: "skip", Value: &skip}, {Key: "limit", Value: &limit},
+		{Key: "collation", Value: (*options.Collation)(nil)}, {Key: "hint", Value: "age_1"}, {Key: "min", Value: nil}, {Key: "max", Value: nil},
+	}, findCacheOptions([]options.Lister[options.FindOptions]{
+		options.Find().SetSort(bson.D{{Key: "age", Value: -1}}).SetSkip(10).SetLimit(20).SetHint("age_1").SetBatchSize(5),
+	}))
+
+	require.Equal(t, bson.D{
+		{Key: "skip", Value: (*int64)(nil)}, {Key: "limit", Value: &limit}, {Key: "collation", Value: &options.Collation{Locale: "en"}}, {Key: "hint", Value: nil},
+	}, countCacheOptions([]options.Lister[options.CountOptions]{options.Count().SetLimit(20).SetCollation(&options.Collation{Locale: "en"})}))
+}
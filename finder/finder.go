@@ -16,13 +16,18 @@ package finder
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/chenmingyong0423/go-mongox/v2/bsonx"
+	"github.com/chenmingyong0423/go-mongox/v2/cache"
+	"github.com/chenmingyong0423/go-mongox/v2/cursor"
 	"github.com/chenmingyong0423/go-mongox/v2/field"
 
 	"github.com/chenmingyong0423/go-mongox/v2/callback"
 	"go.mongodb.org/mongo-driver/v2/bson"
 
+	"github.com/chenmingyong0423/go-mongox/v2/internal/pkg/utils"
 	"github.com/chenmingyong0423/go-mongox/v2/operation"
 
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -37,16 +42,17 @@ type IFinder[T any] interface {
 }
 
 func NewFinder[T any](collection *mongo.Collection, callbacks *callback.Callback, fields []*field.Filed) *Finder[T] {
-	return &Finder[T]{collection: collection, filter: bson.D{}, dbCallbacks: callbacks, fields: fields}
+	return &Finder[T]{collection: collection, filter: bson.D{}, dbCallbacks: callbacks, fields: fields, clock: time.Now}
 }
 
 var _ IFinder[any] = (*Finder[any])(nil)
 
 type Finder[T any] struct {
-	collection *mongo.Collection
-	filter     any
-	updates    any
-	modelHook  any
+	collection  *mongo.Collection
+	filter      any
+	updates     any
+	replacement *T
+	modelHook   any
 
 	fields      []*field.Filed
 	dbCallbacks *callback.Callback
@@ -54,7 +60,21 @@ type Finder[T any] struct {
 	afterHooks  []afterHookFn[T]
 
 	skip, limit int64
+	batchSize   int32
 	sort        any
+	unscoped    bool
+	version     *int64
+	// returnDocument of the find-and-modify operations, nil keeps the default of the driver
+	returnDocument *options.ReturnDocument
+
+	pageTokenSecret []byte
+
+	// cacheStore caches the results of FindOne, Find and Count for cacheTTL, see Cache
+	cacheStore cache.Cache
+	cacheTTL   time.Duration
+
+	clock   func() time.Time
+	timeout time.Duration
 }
 
 func (f *Finder[T]) RegisterBeforeHooks(hooks ...beforeHookFn[T]) *Finder[T] {
@@ -86,32 +106,93 @@ func (f *Finder[T]) Skip(skip int64) *Finder[T] {
 	return f
 }
 
+// BatchSize is used to set the number of documents returned by each batch of the cursor
+func (f *Finder[T]) BatchSize(batchSize int32) *Finder[T] {
+	f.batchSize = batchSize
+	return f
+}
+
 func (f *Finder[T]) Sort(sort any) *Finder[T] {
 	f.sort = sort
 	return f
 }
 
+// Unscoped is used to include the soft-deleted documents in the query
+func (f *Finder[T]) Unscoped() *Finder[T] {
+	f.unscoped = true
+	return f
+}
+
+// Version is used to set the version the document is expected to have when the model has a version field,
+// FindOneAndUpdate and FindOneAndReplace return field.ErrVersionConflict if no document matches the filter and the version
+func (f *Finder[T]) Version(current int64) *Finder[T] {
+	f.version = &current
+	return f
+}
+
 func (f *Finder[T]) Updates(update any) *Finder[T] {
 	f.updates = update
 	return f
 }
 
+// Replacement is used to set the document which replaces the matched one in FindOneAndReplace,
+// the _id and the creation time of the stored document are kept if the replacement does not set them
+func (f *Finder[T]) Replacement(replacement *T) *Finder[T] {
+	f.replacement = replacement
+	return f
+}
+
+// ReturnDocument is used to set whether FindOneAndUpdate, FindOneAndReplace and FindOneAndUpsert
+// return the document before or after the modification, options.Before by default
+func (f *Finder[T]) ReturnDocument(returnDocument options.ReturnDocument) *Finder[T] {
+	f.returnDocument = &returnDocument
+	return f
+}
+
 func (f *Finder[T]) ModelHook(modelHook any) *Finder[T] {
 	f.modelHook = modelHook
 	return f
 }
 
+// Clock is used to set the function which returns the current time of the query, time.Now by default
+func (f *Finder[T]) Clock(clock func() time.Time) *Finder[T] {
+	f.clock = clock
+	return f
+}
+
+// Timeout is used to set the timeout of the query, it is ignored if the context already has a deadline
+func (f *Finder[T]) Timeout(timeout time.Duration) *Finder[T] {
+	f.timeout = timeout
+	return f
+}
+
+// Cache is used to read the result of FindOne, Find and Count from the cache of the collection, see CacheStore,
+// and to cache the result for the ttl when it is read from the server. The cached results are dropped once
+// the Creator, Updater and Deleter of the collection write the documents they depend on.
+// It has no effect if the collection has no cache or if the query runs in a transaction.
+func (f *Finder[T]) Cache(ttl time.Duration) *Finder[T] {
+	f.cacheTTL = ttl
+	return f
+}
+
+// CacheStore is used to set the cache of the results of the queries, it is set by the collection from Config.Cache
+func (f *Finder[T]) CacheStore(store cache.Cache) *Finder[T] {
+	f.cacheStore = store
+	return f
+}
+
+// preActionHandler runs the before callbacks and hooks, the onError callbacks are run with the error if one of them fails
 func (f *Finder[T]) preActionHandler(ctx context.Context, globalOpContext *operation.OpContext, opContext *OpContext[T], opTypes ...operation.OpType) (err error) {
 	for _, opType := range opTypes {
 		err = f.dbCallbacks.Execute(ctx, globalOpContext, opType)
 		if err != nil {
-			return
+			return f.dbCallbacks.OnError(globalOpContext.Context(ctx), globalOpContext, err)
 		}
 	}
 	for _, beforeHook := range f.beforeHooks {
 		err = beforeHook(ctx, opContext)
 		if err != nil {
-			return err
+			return f.dbCallbacks.OnError(globalOpContext.Context(ctx), globalOpContext, err)
 		}
 	}
 	return
@@ -134,24 +215,33 @@ func (f *Finder[T]) postActionHandler(ctx context.Context, globalOpContext *oper
 }
 
 func (f *Finder[T]) FindOne(ctx context.Context, opts ...options.Lister[options.FindOneOptions]) (*T, error) {
-	currentTime := time.Now()
+	ctx, cancel := utils.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	currentTime := f.clock()
 	if f.sort != nil {
 		opts = append(opts, options.FindOne().SetSort(f.sort))
 	}
 
 	t := new(T)
 
-	globalOpContext := operation.NewOpContext(f.collection, operation.WithFilter(f.filter), operation.WithMongoOptions(opts), operation.WithModelHook(f.modelHook), operation.WithStartTime(currentTime), operation.WithFields(f.fields))
+	globalOpContext := operation.NewOpContext(f.collection, operation.WithFilter(f.filter), operation.WithMongoOptions(opts), operation.WithModelHook(f.modelHook), operation.WithStartTime(currentTime), operation.WithFields(f.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameFindOne), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(f.unscoped))
 	opContext := NewOpContext(f.collection, f.filter, WithMongoOptions[T](opts), WithModelHook[T](f.modelHook), WithStartTime[T](currentTime), WithFields[T](f.fields))
 	err := f.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeFind)
 	if err != nil {
 		return nil, err
 	}
+	ctx = globalOpContext.Context(ctx)
 
-	result := f.collection.FindOne(ctx, f.filter, opts...)
+	var result *mongo.SingleResult
+	if key, ok := f.cacheKey(globalOpContext, findOneCacheOptions(opts)); ok {
+		result = f.findOneCached(ctx, globalOpContext, key, opts)
+	} else {
+		result = f.collection.FindOne(ctx, globalOpContext.Filter, opts...)
+	}
 	err = result.Decode(t)
+	globalOpContext.Duration = f.clock().Sub(currentTime)
 	if err != nil {
-		return nil, err
+		return nil, f.dbCallbacks.OnError(ctx, globalOpContext, err)
 	}
 
 	globalOpContext.Result = result
@@ -167,35 +257,36 @@ func (f *Finder[T]) FindOne(ctx context.Context, opts ...options.Lister[options.
 }
 
 func (f *Finder[T]) Find(ctx context.Context, opts ...options.Lister[options.FindOptions]) ([]*T, error) {
-	currentTime := time.Now()
-
-	if f.sort != nil {
-		opts = append(opts, options.Find().SetSort(f.sort))
-	}
-	if f.skip != 0 {
-		opts = append(opts, options.Find().SetSkip(f.skip))
-	}
-	if f.limit != 0 {
-		opts = append(opts, options.Find().SetLimit(f.limit))
-	}
+	ctx, cancel := utils.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	currentTime := f.clock()
+	opts = f.findOptions(opts)
 
 	t := make([]*T, 0)
 
-	globalOpContext := operation.NewOpContext(f.collection, operation.WithFilter(f.filter), operation.WithMongoOptions(opts), operation.WithModelHook(f.modelHook), operation.WithStartTime(currentTime), operation.WithFields(f.fields))
+	globalOpContext := operation.NewOpContext(f.collection, operation.WithFilter(f.filter), operation.WithMongoOptions(opts), operation.WithModelHook(f.modelHook), operation.WithStartTime(currentTime), operation.WithFields(f.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameFind), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(f.unscoped))
 	opContext := NewOpContext(f.collection, f.filter, WithMongoOptions[T](opts), WithModelHook[T](f.modelHook), WithStartTime[T](currentTime), WithFields[T](f.fields))
 	err := f.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeFind)
 	if err != nil {
 		return nil, err
 	}
+	ctx = globalOpContext.Context(ctx)
 
-	cursor, err := f.collection.Find(ctx, f.filter, opts...)
+	var cursor *mongo.Cursor
+	if key, ok := f.cacheKey(globalOpContext, findCacheOptions(opts)); ok {
+		cursor, err = f.findCached(ctx, globalOpContext, key, opts)
+	} else {
+		cursor, err = f.collection.Find(ctx, globalOpContext.Filter, opts...)
+	}
 	if err != nil {
-		return nil, err
+		globalOpContext.Duration = f.clock().Sub(currentTime)
+		return nil, f.dbCallbacks.OnError(ctx, globalOpContext, err)
 	}
 	defer cursor.Close(ctx)
 	err = cursor.All(ctx, &t)
+	globalOpContext.Duration = f.clock().Sub(currentTime)
 	if err != nil {
-		return nil, err
+		return nil, f.dbCallbacks.OnError(ctx, globalOpContext, err)
 	}
 
 	globalOpContext.Result = cursor
@@ -210,43 +301,226 @@ func (f *Finder[T]) Find(ctx context.Context, opts ...options.Lister[options.Fin
 	return t, nil
 }
 
+// Cursor is used to iterate the documents one by one instead of loading all of them in memory like Find does.
+// The after-find callbacks are executed once the query returns, with the cursor as Result and a nil Doc,
+// then the after-find callbacks and hooks are executed for each decoded document. The timeout only applies to the initial query.
+func (f *Finder[T]) Cursor(ctx context.Context, opts ...options.Lister[options.FindOptions]) (*cursor.Cursor[T], error) {
+	queryCtx, cancel := utils.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	currentTime := f.clock()
+	opts = f.findOptions(opts)
+
+	globalOpContext := operation.NewOpContext(f.collection, operation.WithFilter(f.filter), operation.WithMongoOptions(opts), operation.WithModelHook(f.modelHook), operation.WithStartTime(currentTime), operation.WithFields(f.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameFind), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(f.unscoped))
+	opContext := NewOpContext(f.collection, f.filter, WithMongoOptions[T](opts), WithModelHook[T](f.modelHook), WithStartTime[T](currentTime), WithFields[T](f.fields))
+	err := f.preActionHandler(queryCtx, globalOpContext, opContext, operation.OpTypeBeforeFind)
+	if err != nil {
+		return nil, err
+	}
+	queryCtx = globalOpContext.Context(queryCtx)
+
+	mongoCursor, err := f.collection.Find(queryCtx, globalOpContext.Filter, opts...)
+	globalOpContext.Duration = f.clock().Sub(currentTime)
+	if err != nil {
+		return nil, f.dbCallbacks.OnError(queryCtx, globalOpContext, err)
+	}
+
+	globalOpContext.Result = mongoCursor
+	opContext.Result = mongoCursor
+	err = f.dbCallbacks.Execute(queryCtx, globalOpContext, operation.OpTypeAfterFind)
+	if err != nil {
+		_ = mongoCursor.Close(queryCtx)
+		return nil, err
+	}
+	return cursor.NewCursor[T](mongoCursor, func(ctx context.Context, doc *T) error {
+		globalOpContext.Doc = doc
+		opContext.Doc = doc
+		return f.postActionHandler(ctx, globalOpContext, opContext, operation.OpTypeAfterFind)
+	}), nil
+}
+
+func (f *Finder[T]) findOptions(opts []options.Lister[options.FindOptions]) []options.Lister[options.FindOptions] {
+	if f.sort != nil {
+		opts = append(opts, options.Find().SetSort(f.sort))
+	}
+	if f.skip != 0 {
+		opts = append(opts, options.Find().SetSkip(f.skip))
+	}
+	if f.limit != 0 {
+		opts = append(opts, options.Find().SetLimit(f.limit))
+	}
+	if f.batchSize != 0 {
+		opts = append(opts, options.Find().SetBatchSize(f.batchSize))
+	}
+	return opts
+}
+
 func (f *Finder[T]) Count(ctx context.Context, opts ...options.Lister[options.CountOptions]) (int64, error) {
-	return f.collection.CountDocuments(ctx, f.filter, opts...)
+	ctx, cancel := utils.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	currentTime := f.clock()
+	globalOpContext := operation.NewOpContext(f.collection, operation.WithFilter(f.filter), operation.WithMongoOptions(opts), operation.WithModelHook(f.modelHook), operation.WithStartTime(currentTime), operation.WithFields(f.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameCount), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(f.unscoped))
+	err := f.dbCallbacks.Execute(ctx, globalOpContext, operation.OpTypeBeforeCount)
+	if err != nil {
+		return 0, f.dbCallbacks.OnError(globalOpContext.Context(ctx), globalOpContext, err)
+	}
+	ctx = globalOpContext.Context(ctx)
+
+	var count int64
+	if key, ok := f.cacheKey(globalOpContext, countCacheOptions(opts)); ok {
+		count, err = f.countCached(ctx, globalOpContext, key, opts)
+	} else {
+		count, err = f.collection.CountDocuments(ctx, globalOpContext.Filter, opts...)
+	}
+	globalOpContext.Duration = f.clock().Sub(currentTime)
+	if err != nil {
+		return 0, f.dbCallbacks.OnError(ctx, globalOpContext, err)
+	}
+
+	globalOpContext.Result = count
+	err = f.dbCallbacks.Execute(ctx, globalOpContext, operation.OpTypeAfterCount)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
 }
 
+// EstimatedCount returns an estimate of the number of documents in the collection using the collection metadata,
+// the filter of the finder is ignored. It runs the count callbacks with an empty filter, and falls back to an
+// exact count if a callback restricts the filter, e.g. to exclude the soft-deleted documents.
+func (f *Finder[T]) EstimatedCount(ctx context.Context, opts ...options.Lister[options.EstimatedDocumentCountOptions]) (int64, error) {
+	ctx, cancel := utils.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	currentTime := f.clock()
+	globalOpContext := operation.NewOpContext(f.collection, operation.WithFilter(bson.D{}), operation.WithMongoOptions(opts), operation.WithModelHook(f.modelHook), operation.WithStartTime(currentTime), operation.WithFields(f.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameEstimatedCount), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(f.unscoped))
+	err := f.dbCallbacks.Execute(ctx, globalOpContext, operation.OpTypeBeforeCount)
+	if err != nil {
+		return 0, f.dbCallbacks.OnError(globalOpContext.Context(ctx), globalOpContext, err)
+	}
+	ctx = globalOpContext.Context(ctx)
+
+	var count int64
+	if filter, ok := globalOpContext.Filter.(bson.D); ok && len(filter) == 0 {
+		count, err = f.collection.EstimatedDocumentCount(ctx, opts...)
+	} else {
+		count, err = f.collection.CountDocuments(ctx, globalOpContext.Filter, estimatedToCountOptions(opts)...)
+	}
+	globalOpContext.Duration = f.clock().Sub(currentTime)
+	if err != nil {
+		return 0, f.dbCallbacks.OnError(ctx, globalOpContext, err)
+	}
+
+	globalOpContext.Result = count
+	err = f.dbCallbacks.Execute(ctx, globalOpContext, operation.OpTypeAfterCount)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func estimatedToCountOptions(opts []options.Lister[options.EstimatedDocumentCountOptions]) []options.Lister[options.CountOptions] {
+	estimated := &options.EstimatedDocumentCountOptions{}
+	for _, opt := range opts {
+		for _, fn := range opt.List() {
+			_ = fn(estimated)
+		}
+	}
+	if estimated.Comment == nil {
+		return nil
+	}
+	return []options.Lister[options.CountOptions]{options.Count().SetComment(estimated.Comment)}
+}
+
+// Distinct returns the distinct values of the field among the documents matched by the filter,
+// the error of the operation or of its callbacks is returned by the Err method of the result
 func (f *Finder[T]) Distinct(ctx context.Context, fieldName string, opts ...options.Lister[options.DistinctOptions]) *mongo.DistinctResult {
-	return f.collection.Distinct(ctx, fieldName, f.filter, opts...)
+	ctx, cancel := utils.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	distinctResult, err := f.distinct(ctx, fieldName, opts...)
+	if err != nil {
+		return f.distinctError(ctx, err)
+	}
+	return distinctResult
+}
+
+// distinctError returns a DistinctResult holding the error, the driver builds one without running the command
+// when an option fails to be applied. The error is wrapped, errors.Is and errors.As still find it.
+func (f *Finder[T]) distinctError(ctx context.Context, err error) *mongo.DistinctResult {
+	failing := &options.DistinctOptionsBuilder{Opts: []func(*options.DistinctOptions) error{
+		func(*options.DistinctOptions) error { return err },
+	}}
+	return f.collection.Distinct(ctx, "", bson.D{}, failing)
 }
 
 // DistinctWithParse is used to parse the result of Distinct
 // result must be a pointer
 func (f *Finder[T]) DistinctWithParse(ctx context.Context, fieldName string, result any, opts ...options.Lister[options.DistinctOptions]) error {
-	distinctResult := f.collection.Distinct(ctx, fieldName, f.filter, opts...)
-	if distinctResult.Err() != nil {
-		return distinctResult.Err()
+	ctx, cancel := utils.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	distinctResult, err := f.distinct(ctx, fieldName, opts...)
+	if err != nil {
+		return err
 	}
-	err := distinctResult.Decode(result)
+	err = distinctResult.Decode(result)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+func (f *Finder[T]) distinct(ctx context.Context, fieldName string, opts ...options.Lister[options.DistinctOptions]) (*mongo.DistinctResult, error) {
+	currentTime := f.clock()
+	globalOpContext := operation.NewOpContext(f.collection, operation.WithFilter(f.filter), operation.WithMongoOptions(opts), operation.WithModelHook(f.modelHook), operation.WithStartTime(currentTime), operation.WithFields(f.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameDistinct), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(f.unscoped))
+	err := f.dbCallbacks.Execute(ctx, globalOpContext, operation.OpTypeBeforeDistinct)
+	if err != nil {
+		return nil, f.dbCallbacks.OnError(globalOpContext.Context(ctx), globalOpContext, err)
+	}
+	ctx = globalOpContext.Context(ctx)
+
+	distinctResult := f.collection.Distinct(ctx, fieldName, globalOpContext.Filter, opts...)
+	globalOpContext.Duration = f.clock().Sub(currentTime)
+	if distinctResult.Err() != nil {
+		return nil, f.dbCallbacks.OnError(ctx, globalOpContext, distinctResult.Err())
+	}
+
+	globalOpContext.Result = distinctResult
+	err = f.dbCallbacks.Execute(ctx, globalOpContext, operation.OpTypeAfterDistinct)
+	if err != nil {
+		return nil, err
+	}
+	return distinctResult, nil
+}
+
 func (f *Finder[T]) FindOneAndUpdate(ctx context.Context, opts ...options.Lister[options.FindOneAndUpdateOptions]) (*T, error) {
-	currentTime := time.Now()
+	ctx, cancel := utils.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	currentTime := f.clock()
 	t := new(T)
-	globalOpContext := operation.NewOpContext(f.collection, operation.WithFilter(f.filter), operation.WithUpdates(f.updates), operation.WithMongoOptions(opts), operation.WithModelHook(f.modelHook), operation.WithStartTime(currentTime), operation.WithFields(f.fields))
+	if f.returnDocument != nil {
+		opts = append(opts, options.FindOneAndUpdate().SetReturnDocument(*f.returnDocument))
+	}
+
+	updates := bsonx.ToBsonM(f.updates)
+	if len(updates) != 0 {
+		f.updates = updates
+	}
+
+	globalOpContext := operation.NewOpContext(f.collection, operation.WithFilter(f.filter), operation.WithUpdates(f.updates), operation.WithMongoOptions(opts), operation.WithModelHook(f.modelHook), operation.WithStartTime(currentTime), operation.WithFields(f.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameFindOneAndUpdate), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(f.unscoped), operation.WithVersion(f.version))
 	opContext := NewOpContext(f.collection, f.filter, WithUpdates[T](f.updates), WithMongoOptions[T](opts), WithModelHook[T](f.modelHook), WithStartTime[T](currentTime), WithFields[T](f.fields))
 
 	err := f.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeFind, operation.OpTypeBeforeUpdate)
 	if err != nil {
 		return nil, err
 	}
+	ctx = globalOpContext.Context(ctx)
 
-	result := f.collection.FindOneAndUpdate(ctx, f.filter, f.updates, opts...)
+	result := f.collection.FindOneAndUpdate(ctx, globalOpContext.Filter, globalOpContext.Updates, opts...)
 	err = result.Decode(t)
+	globalOpContext.Duration = f.clock().Sub(currentTime)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, mongo.ErrNoDocuments) && globalOpContext.VersionChecked {
+			err = field.ErrVersionConflict
+		}
+		return nil, f.dbCallbacks.OnError(ctx, globalOpContext, err)
 	}
 
 	globalOpContext.Result = result
@@ -260,3 +534,169 @@ func (f *Finder[T]) FindOneAndUpdate(ctx context.Context, opts ...options.Lister
 
 	return t, nil
 }
+
+// FindOneAndUpsert is like FindOneAndUpdate but inserts the document if none matches the filter,
+// it runs the upsert callbacks so the _id and the creation time are set on insert.
+// Use ReturnDocument(options.After) to get the inserted document, ErrNoDocuments is returned otherwise.
+func (f *Finder[T]) FindOneAndUpsert(ctx context.Context, opts ...options.Lister[options.FindOneAndUpdateOptions]) (*T, error) {
+	ctx, cancel := utils.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	currentTime := f.clock()
+	t := new(T)
+	if f.returnDocument != nil {
+		opts = append(opts, options.FindOneAndUpdate().SetReturnDocument(*f.returnDocument))
+	}
+	opts = append(opts, options.FindOneAndUpdate().SetUpsert(true))
+
+	updates := bsonx.ToBsonM(f.updates)
+	if len(updates) != 0 {
+		f.updates = updates
+	}
+
+	globalOpContext := operation.NewOpContext(f.collection, operation.WithFilter(f.filter), operation.WithUpdates(f.updates), operation.WithMongoOptions(opts), operation.WithModelHook(f.modelHook), operation.WithStartTime(currentTime), operation.WithFields(f.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameFindOneAndUpsert), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(f.unscoped), operation.WithVersion(f.version))
+	opContext := NewOpContext(f.collection, f.filter, WithUpdates[T](f.updates), WithMongoOptions[T](opts), WithModelHook[T](f.modelHook), WithStartTime[T](currentTime), WithFields[T](f.fields))
+
+	err := f.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeFind, operation.OpTypeBeforeUpsert)
+	if err != nil {
+		return nil, err
+	}
+	ctx = globalOpContext.Context(ctx)
+
+	result := f.collection.FindOneAndUpdate(ctx, globalOpContext.Filter, globalOpContext.Updates, opts...)
+	err = result.Decode(t)
+	globalOpContext.Duration = f.clock().Sub(currentTime)
+	if err != nil {
+		if globalOpContext.VersionChecked && mongo.IsDuplicateKeyError(err) {
+			// the document exists with another version, the upsert tried to insert it again
+			err = field.ErrVersionConflict
+		}
+		return nil, f.dbCallbacks.OnError(ctx, globalOpContext, err)
+	}
+
+	globalOpContext.Result = result
+	globalOpContext.Doc = t
+	opContext.Result = result
+	opContext.Doc = t
+	err = f.postActionHandler(ctx, globalOpContext, opContext, operation.OpTypeAfterFind, operation.OpTypeAfterUpsert)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// FindOneAndReplace replaces the document matching the filter with the Replacement and returns the document
+// before the replacement unless ReturnDocument(options.After) is set. The update time of the replacement is set,
+// its _id and creation time are kept from the stored document if they are not set.
+func (f *Finder[T]) FindOneAndReplace(ctx context.Context, opts ...options.Lister[options.FindOneAndReplaceOptions]) (*T, error) {
+	ctx, cancel := utils.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	currentTime := f.clock()
+	t := new(T)
+	if f.returnDocument != nil {
+		opts = append(opts, options.FindOneAndReplace().SetReturnDocument(*f.returnDocument))
+	}
+
+	globalOpContext := operation.NewOpContext(f.collection, operation.WithFilter(f.filter), operation.WithReplacement(f.replacement), operation.WithMongoOptions(opts), operation.WithModelHook(f.modelHook), operation.WithStartTime(currentTime), operation.WithFields(f.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameFindOneAndReplace), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(f.unscoped), operation.WithVersion(f.version))
+	defer globalOpContext.Release()
+	opContext := NewOpContext(f.collection, f.filter, WithReplacement[T](f.replacement), WithMongoOptions[T](opts), WithModelHook[T](f.modelHook), WithStartTime[T](currentTime), WithFields[T](f.fields))
+
+	err := f.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeFind, operation.OpTypeBeforeReplace)
+	if err != nil {
+		return nil, err
+	}
+	ctx = globalOpContext.Context(ctx)
+
+	result := f.collection.FindOneAndReplace(ctx, globalOpContext.Filter, globalOpContext.Replacement, opts...)
+	err = result.Decode(t)
+	globalOpContext.Duration = f.clock().Sub(currentTime)
+	if err != nil {
+		if globalOpContext.VersionChecked && (errors.Is(err, mongo.ErrNoDocuments) || mongo.IsDuplicateKeyError(err)) {
+			err = field.ErrVersionConflict
+		}
+		return nil, f.dbCallbacks.OnError(ctx, globalOpContext, err)
+	}
+
+	globalOpContext.Result = result
+	globalOpContext.Doc = t
+	opContext.Result = result
+	opContext.Doc = t
+	err = f.postActionHandler(ctx, globalOpContext, opContext, operation.OpTypeAfterFind, operation.OpTypeAfterReplace)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// FindOneAndDelete deletes the document matching the filter and returns it,
+// the document is soft-deleted with the same options if the model has a soft-delete field.
+func (f *Finder[T]) FindOneAndDelete(ctx context.Context, opts ...options.Lister[options.FindOneAndDeleteOptions]) (*T, error) {
+	ctx, cancel := utils.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	currentTime := f.clock()
+	t := new(T)
+
+	globalOpContext := operation.NewOpContext(f.collection, operation.WithFilter(f.filter), operation.WithMongoOptions(opts), operation.WithModelHook(f.modelHook), operation.WithStartTime(currentTime), operation.WithFields(f.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameFindOneAndDelete), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(f.unscoped))
+	opContext := NewOpContext(f.collection, f.filter, WithMongoOptions[T](opts), WithModelHook[T](f.modelHook), WithStartTime[T](currentTime), WithFields[T](f.fields))
+
+	err := f.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeFind, operation.OpTypeBeforeDelete)
+	if err != nil {
+		return nil, err
+	}
+	ctx = globalOpContext.Context(ctx)
+
+	var result *mongo.SingleResult
+	if globalOpContext.Updates != nil {
+		// soft delete
+		result = f.collection.FindOneAndUpdate(ctx, globalOpContext.Filter, globalOpContext.Updates, deleteToUpdateOptions(opts)...)
+	} else {
+		result = f.collection.FindOneAndDelete(ctx, globalOpContext.Filter, opts...)
+	}
+	err = result.Decode(t)
+	globalOpContext.Duration = f.clock().Sub(currentTime)
+	if err != nil {
+		return nil, f.dbCallbacks.OnError(ctx, globalOpContext, err)
+	}
+
+	globalOpContext.Result = result
+	globalOpContext.Doc = t
+	opContext.Result = result
+	opContext.Doc = t
+	err = f.postActionHandler(ctx, globalOpContext, opContext, operation.OpTypeAfterFind, operation.OpTypeAfterDelete)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// deleteToUpdateOptions converts the options of FindOneAndDelete into the ones of the FindOneAndUpdate of a soft delete
+func deleteToUpdateOptions(opts []options.Lister[options.FindOneAndDeleteOptions]) []options.Lister[options.FindOneAndUpdateOptions] {
+	deleteOpts := &options.FindOneAndDeleteOptions{}
+	for _, opt := range opts {
+		for _, fn := range opt.List() {
+			_ = fn(deleteOpts)
+		}
+	}
+	updateOpts := options.FindOneAndUpdate()
+	if deleteOpts.Collation != nil {
+		updateOpts.SetCollation(deleteOpts.Collation)
+	}
+	if deleteOpts.Comment != nil {
+		updateOpts.SetComment(deleteOpts.Comment)
+	}
+	if deleteOpts.Projection != nil {
+		updateOpts.SetProjection(deleteOpts.Projection)
+	}
+	if deleteOpts.Sort != nil {
+		updateOpts.SetSort(deleteOpts.Sort)
+	}
+	if deleteOpts.Hint != nil {
+		updateOpts.SetHint(deleteOpts.Hint)
+	}
+	if deleteOpts.Let != nil {
+		updateOpts.SetLet(deleteOpts.Let)
+	}
+	return []options.Lister[options.FindOneAndUpdateOptions]{updateOpts}
+}
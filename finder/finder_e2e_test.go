@@ -1483,3 +1483,424 @@ func TestFinder_e2e_FindOneAndUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestFinder_e2e_Cursor(t *testing.T) {
+	collection := getCollection(t)
+	callbacks := callback.InitializeCallbacks()
+	afterFinds, queries := 0, 0
+	callbacks.Register(operation.OpTypeAfterFind, "count", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+		if _, ok := opCtx.Doc.(*TestUser); ok {
+			afterFinds++
+		} else if _, ok := opCtx.Result.(*mongo.Cursor); ok && opCtx.Doc == nil {
+			queries++
+		}
+		return nil
+	})
+	finder := NewFinder[TestUser](collection, callbacks, field.ParseFields(TestUser{}))
+
+	docs := make([]any, 0, 10)
+	for i := 0; i < 10; i++ {
+		docs = append(docs, &TestUser{Name: fmt.Sprintf("user%d", i), Age: int64(i)})
+	}
+	insertManyResult, err := collection.InsertMany(context.Background(), docs)
+	require.NoError(t, err)
+	require.Len(t, insertManyResult.InsertedIDs, 10)
+	defer func() {
+		_, err := collection.DeleteMany(context.Background(), query.In("_id", insertManyResult.InsertedIDs...))
+		require.NoError(t, err)
+	}()
+
+	t.Run("next and decode", func(t *testing.T) {
+		afterFinds, queries = 0, 0
+		hookedNames := make([]string, 0)
+		c, err := finder.Filter(query.Gte("age", 5)).Sort(bson.D{{Key: "age", Value: 1}}).BatchSize(2).
+			RegisterAfterHooks(func(ctx context.Context, opContext *OpContext[TestUser], opts ...any) error {
+				hookedNames = append(hookedNames, opContext.Doc.Name)
+				return nil
+			}).
+			Cursor(context.Background())
+		require.NoError(t, err)
+		defer c.Close(context.Background())
+
+		ages := make([]int64, 0)
+		for c.Next(context.Background()) {
+			user := new(TestUser)
+			require.NoError(t, c.Decode(user))
+			ages = append(ages, user.Age)
+		}
+		require.NoError(t, c.Err())
+		require.Equal(t, []int64{5, 6, 7, 8, 9}, ages)
+		require.Equal(t, []string{"user5", "user6", "user7", "user8", "user9"}, hookedNames)
+		require.Equal(t, 5, afterFinds)
+		require.Equal(t, 1, queries)
+	})
+
+	t.Run("for each", func(t *testing.T) {
+		afterFinds, queries = 0, 0
+		c, err := NewFinder[TestUser](collection, callbacks, field.ParseFields(TestUser{})).Filter(query.Lt("age", 3)).Cursor(context.Background())
+		require.NoError(t, err)
+
+		names := make([]string, 0)
+		err = c.ForEach(context.Background(), func(user *TestUser) error {
+			names = append(names, user.Name)
+			return nil
+		})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"user0", "user1", "user2"}, names)
+		require.Equal(t, 3, afterFinds)
+		require.Equal(t, 1, queries)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		afterFinds, queries = 0, 0
+		c, err := NewFinder[TestUser](collection, callbacks, field.ParseFields(TestUser{})).Filter(query.Gt("age", 100)).Cursor(context.Background())
+		require.NoError(t, err)
+		require.False(t, c.Next(context.Background()))
+		require.NoError(t, c.Close(context.Background()))
+		require.Equal(t, 0, afterFinds)
+		require.Equal(t, 1, queries)
+	})
+
+	t.Run("closed without decoding", func(t *testing.T) {
+		afterFinds, queries = 0, 0
+		c, err := NewFinder[TestUser](collection, callbacks, field.ParseFields(TestUser{})).Cursor(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, c.Close(context.Background()))
+		require.Equal(t, 0, afterFinds)
+		require.Equal(t, 1, queries)
+	})
+}
+
+func TestFinder_e2e_Paginate(t *testing.T) {
+	collection := getCollection(t)
+	finder := NewFinder[TestUser](collection, callback.InitializeCallbacks(), field.ParseFields(TestUser{}))
+
+	docs := make([]any, 0, 5)
+	for i := 0; i < 5; i++ {
+		docs = append(docs, &TestUser{Name: fmt.Sprintf("user%d", i), Age: int64(i)})
+	}
+	insertManyResult, err := collection.InsertMany(context.Background(), docs)
+	require.NoError(t, err)
+	defer func() {
+		_, err := collection.DeleteMany(context.Background(), query.In("_id", insertManyResult.InsertedIDs...))
+		require.NoError(t, err)
+	}()
+
+	testCases := []struct {
+		name string
+		page int64
+		size int64
+
+		wantAges    []int64
+		wantHasNext bool
+		wantErr     error
+	}{
+		{
+			name:        "first page",
+			page:        1,
+			size:        2,
+			wantAges:    []int64{4, 3},
+			wantHasNext: true,
+		},
+		{
+			name:        "last page",
+			page:        3,
+			size:        2,
+			wantAges:    []int64{0},
+			wantHasNext: false,
+		},
+		{
+			name:     "out of range",
+			page:     4,
+			size:     2,
+			wantAges: []int64{},
+		},
+		{
+			name:    "invalid page",
+			page:    0,
+			size:    2,
+			wantErr: ErrInvalidPage,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := finder.Filter(query.In("_id", insertManyResult.InsertedIDs...)).Sort(bson.D{{Key: "age", Value: -1}}).Paginate(context.Background(), tc.page, tc.size)
+			require.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			ages := make([]int64, 0, len(page.Items))
+			for _, item := range page.Items {
+				ages = append(ages, item.Age)
+			}
+			require.Equal(t, tc.wantAges, ages)
+			require.Equal(t, int64(5), page.Total)
+			require.Equal(t, tc.page, page.Page)
+			require.Equal(t, tc.size, page.Size)
+			require.Equal(t, tc.wantHasNext, page.HasNext)
+		})
+	}
+}
+
+func TestFinder_e2e_PageAfter(t *testing.T) {
+	collection := getCollection(t)
+
+	docs := make([]any, 0, 5)
+	for i := 0; i < 5; i++ {
+		// two users per age so that _id breaks the ties
+		docs = append(docs, &TestUser{Name: fmt.Sprintf("user%d", i), Age: int64(i / 2)})
+	}
+	insertManyResult, err := collection.InsertMany(context.Background(), docs)
+	require.NoError(t, err)
+	defer func() {
+		_, err := collection.DeleteMany(context.Background(), query.In("_id", insertManyResult.InsertedIDs...))
+		require.NoError(t, err)
+	}()
+
+	newFinder := func() *Finder[TestUser] {
+		return NewFinder[TestUser](collection, callback.InitializeCallbacks(), field.ParseFields(TestUser{})).
+			Filter(query.In("_id", insertManyResult.InsertedIDs...)).Sort(bson.D{{Key: "age", Value: 1}}).PageTokenSecret([]byte("secret"))
+	}
+
+	names := make([]string, 0, 5)
+	token := ""
+	for i := 0; i < 3; i++ {
+		page, err := newFinder().PageAfter(context.Background(), token, 2)
+		require.NoError(t, err)
+		for _, item := range page.Items {
+			names = append(names, item.Name)
+		}
+		require.Equal(t, i < 2, page.HasNext)
+		token = page.NextToken
+	}
+	require.Equal(t, []string{"user0", "user1", "user2", "user3", "user4"}, names)
+	require.Empty(t, token)
+
+	page, err := newFinder().PageAfter(context.Background(), "", 2)
+	require.NoError(t, err)
+	_, err = newFinder().Sort(bson.D{{Key: "age", Value: -1}}).PageAfter(context.Background(), page.NextToken, 2)
+	require.Equal(t, ErrInvalidPageToken, err)
+	_, err = newFinder().PageTokenSecret([]byte("other")).PageAfter(context.Background(), page.NextToken, 2)
+	require.Equal(t, ErrInvalidPageToken, err)
+}
+
+func TestFinder_e2e_PageAfterNull(t *testing.T) {
+	collection := getCollection(t)
+	insertManyResult, err := collection.InsertMany(context.Background(), []any{
+		bson.M{"name": "user0", "nickname": "b"},
+		bson.M{"name": "user1"},
+		bson.M{"name": "user2", "nickname": "a"},
+		bson.M{"name": "user3", "nickname": nil},
+	})
+	require.NoError(t, err)
+	defer func() {
+		_, err := collection.DeleteMany(context.Background(), query.In("_id", insertManyResult.InsertedIDs...))
+		require.NoError(t, err)
+	}()
+
+	// null and the missing nicknames are sorted first in ascending order and last in descending order
+	for order, want := range map[int][]string{1: {"user1", "user3", "user2", "user0"}, -1: {"user0", "user2", "user1", "user3"}} {
+		names := make([]string, 0, 4)
+		token := ""
+		for {
+			page, err := NewFinder[TestUser](collection, callback.InitializeCallbacks(), field.ParseFields(TestUser{})).
+				Filter(query.In("_id", insertManyResult.InsertedIDs...)).Sort(bson.D{{Key: "nickname", Value: order}}).PageAfter(context.Background(), token, 1)
+			require.NoError(t, err)
+			for _, item := range page.Items {
+				names = append(names, item.Name)
+			}
+			if !page.HasNext {
+				break
+			}
+			token = page.NextToken
+		}
+		require.Equal(t, want, names, order)
+	}
+}
+
+func TestFinder_e2e_FindAs(t *testing.T) {
+	collection := getCollection(t)
+	finder := NewFinder[TestUser](collection, callback.InitializeCallbacks(), field.ParseFields(TestUser{}))
+
+	insertManyResult, err := collection.InsertMany(context.Background(), []*TestUser{
+		{Name: "chenmingyong", Age: 24},
+		{Name: "burt", Age: 25},
+	})
+	require.NoError(t, err)
+	defer func() {
+		_, err := collection.DeleteMany(context.Background(), query.In("_id", insertManyResult.InsertedIDs...))
+		require.NoError(t, err)
+	}()
+
+	type UserAge struct {
+		Name string `bson:"name"`
+		Age  int64  `bson:"age"`
+	}
+
+	users, err := FindAs[TestUser, UserAge](context.Background(), finder.Filter(query.In("_id", insertManyResult.InsertedIDs...)).Sort(bson.D{{Key: "age", Value: 1}}))
+	require.NoError(t, err)
+	require.Equal(t, []*UserAge{{Name: "chenmingyong", Age: 24}, {Name: "burt", Age: 25}}, users)
+
+	user, err := FindOneAs[TestUser, UserName](context.Background(), NewFinder[TestUser](collection, callback.InitializeCallbacks(), field.ParseFields(TestUser{})).Filter(query.Eq("name", "burt")))
+	require.NoError(t, err)
+	require.Equal(t, &UserName{Name: "burt"}, user)
+
+	_, err = FindOneAs[TestUser, UserName](context.Background(), NewFinder[TestUser](collection, callback.InitializeCallbacks(), field.ParseFields(TestUser{})).Filter(query.Eq("name", "unknown")))
+	require.Equal(t, mongo.ErrNoDocuments, err)
+}
+
+func TestFinder_e2e_FindOneAndReplace(t *testing.T) {
+	collection := getCollection(t)
+	newFinder := func() *Finder[TestUser] {
+		return NewFinder[TestUser](collection, callback.InitializeCallbacks(), field.ParseFields(TestUser{}))
+	}
+
+	createdAt := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	insertOneResult, err := collection.InsertOne(context.Background(), &TestUser{Name: "chenmingyong", Age: 24, CreatedAt: createdAt, UpdatedAt: createdAt})
+	require.NoError(t, err)
+	defer func() {
+		_, err := collection.DeleteMany(context.Background(), query.In("name", "chenmingyong", "burt"))
+		require.NoError(t, err)
+	}()
+
+	before, err := newFinder().Filter(query.Eq("name", "chenmingyong")).Replacement(&TestUser{Name: "burt", Age: 25}).FindOneAndReplace(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "chenmingyong", before.Name)
+
+	after, err := newFinder().Filter(query.Eq("name", "burt")).Replacement(&TestUser{Name: "burt", Age: 26}).ReturnDocument(options.After).FindOneAndReplace(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, insertOneResult.InsertedID, after.ID)
+	require.Equal(t, int64(26), after.Age)
+	require.True(t, createdAt.Equal(after.CreatedAt))
+	require.True(t, after.UpdatedAt.After(createdAt))
+
+	_, err = newFinder().Filter(query.Eq("name", "unknown")).Replacement(&TestUser{Name: "unknown"}).FindOneAndReplace(context.Background())
+	require.Equal(t, mongo.ErrNoDocuments, err)
+}
+
+func TestFinder_e2e_FindOneAndReplaceVersion(t *testing.T) {
+	type VersionedUser struct {
+		ID      string `bson:"_id"`
+		Name    string `bson:"name"`
+		Version int64  `bson:"version" mongox:"version"`
+	}
+	collection := getCollection(t)
+	newFinder := func() *Finder[VersionedUser] {
+		return NewFinder[VersionedUser](collection, callback.InitializeCallbacks(), field.ParseFields(VersionedUser{}))
+	}
+	ctx := context.Background()
+	_, err := collection.InsertOne(ctx, VersionedUser{ID: "1", Name: "Mingyong Chen", Version: 1})
+	require.NoError(t, err)
+	defer func() {
+		_, err := collection.DeleteMany(ctx, query.Id("1"))
+		require.NoError(t, err)
+	}()
+
+	after, err := newFinder().Filter(query.Id("1")).Replacement(&VersionedUser{ID: "1", Name: "chenmingyong"}).Version(1).ReturnDocument(options.After).FindOneAndReplace(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &VersionedUser{ID: "1", Name: "chenmingyong", Version: 2}, after)
+
+	// the document has been modified since version 1
+	_, err = newFinder().Filter(query.Id("1")).Replacement(&VersionedUser{ID: "1", Name: "burt"}).Version(1).FindOneAndReplace(ctx)
+	require.ErrorIs(t, err, field.ErrVersionConflict)
+}
+
+func TestFinder_e2e_FindOneAndUpsert(t *testing.T) {
+	collection := getCollection(t)
+	newFinder := func() *Finder[TestUser] {
+		return NewFinder[TestUser](collection, callback.InitializeCallbacks(), field.ParseFields(TestUser{}))
+	}
+	defer func() {
+		_, err := collection.DeleteMany(context.Background(), query.Eq("name", "chenmingyong"))
+		require.NoError(t, err)
+	}()
+
+	inserted, err := newFinder().Filter(query.Eq("name", "chenmingyong")).Updates(update.Set("age", 24)).ReturnDocument(options.After).FindOneAndUpsert(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(24), inserted.Age)
+	require.False(t, inserted.CreatedAt.IsZero())
+	require.False(t, inserted.UpdatedAt.IsZero())
+
+	before, err := newFinder().Filter(query.Eq("name", "chenmingyong")).Updates(update.Set("age", 25)).FindOneAndUpsert(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(24), before.Age)
+	require.Equal(t, inserted.ID, before.ID)
+
+	count, err := collection.CountDocuments(context.Background(), query.Eq("name", "chenmingyong"))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+}
+
+func TestFinder_e2e_FindOneAndDelete(t *testing.T) {
+	collection := getCollection(t)
+	afterDeletes := 0
+	callbacks := callback.InitializeCallbacks()
+	callbacks.Register(operation.OpTypeAfterDelete, "count", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+		afterDeletes++
+		return nil
+	})
+
+	_, err := collection.InsertOne(context.Background(), &TestUser{Name: "chenmingyong", Age: 24})
+	require.NoError(t, err)
+
+	deleted, err := NewFinder[TestUser](collection, callbacks, field.ParseFields(TestUser{})).Filter(query.Eq("name", "chenmingyong")).FindOneAndDelete(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(24), deleted.Age)
+	require.Equal(t, 1, afterDeletes)
+
+	_, err = NewFinder[TestUser](collection, callbacks, field.ParseFields(TestUser{})).Filter(query.Eq("name", "chenmingyong")).FindOneAndDelete(context.Background())
+	require.Equal(t, mongo.ErrNoDocuments, err)
+}
+
+func TestFinder_e2e_CountAndDistinctCallbacks(t *testing.T) {
+	collection := getCollection(t)
+	callbacks := callback.InitializeCallbacks()
+	var opTypes []operation.OpType
+	for _, opType := range []operation.OpType{operation.OpTypeBeforeCount, operation.OpTypeAfterCount, operation.OpTypeBeforeDistinct, operation.OpTypeAfterDistinct} {
+		opType := opType
+		callbacks.Register(opType, "record", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+			opTypes = append(opTypes, opType)
+			if opType == operation.OpTypeBeforeCount || opType == operation.OpTypeBeforeDistinct {
+				// restrict the operation the way a tenancy plugin would
+				opCtx.Filter = query.Eq("age", 24)
+			}
+			return nil
+		})
+	}
+	finder := func() *Finder[TestUser] {
+		return NewFinder[TestUser](collection, callbacks, field.ParseFields(TestUser{}))
+	}
+
+	_, err := collection.InsertMany(context.Background(), []*TestUser{{Name: "chenmingyong", Age: 24}, {Name: "burt", Age: 25}})
+	require.NoError(t, err)
+	defer func() {
+		_, err := collection.DeleteMany(context.Background(), query.In("name", "chenmingyong", "burt"))
+		require.NoError(t, err)
+	}()
+
+	count, err := finder().Count(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	count, err = finder().EstimatedCount(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	names := make([]string, 0)
+	err = finder().DistinctWithParse(context.Background(), "name", &names)
+	require.NoError(t, err)
+	require.Equal(t, []string{"chenmingyong"}, names)
+
+	require.Equal(t, []operation.OpType{
+		operation.OpTypeBeforeCount, operation.OpTypeAfterCount,
+		operation.OpTypeBeforeCount, operation.OpTypeAfterCount,
+		operation.OpTypeBeforeDistinct, operation.OpTypeAfterDistinct,
+	}, opTypes)
+
+	errBefore := errors.New("before distinct error")
+	callbacks.Register(operation.OpTypeBeforeDistinct, "fail", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+		return errBefore
+	})
+	err = finder().Distinct(context.Background(), "name").Err()
+	require.ErrorIs(t, err, errBefore)
+}
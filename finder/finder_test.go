@@ -25,6 +25,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"go.uber.org/mock/gomock"
 )
 
@@ -216,3 +217,36 @@ func TestFinder_Count(t *testing.T) {
 		})
 	}
 }
+
+func Test_deleteToUpdateOptions(t *testing.T) {
+	collation := &options.Collation{Locale: "en"}
+	opts := deleteToUpdateOptions([]options.Lister[options.FindOneAndDeleteOptions]{
+		options.FindOneAndDelete().SetSort(bson.D{{Key: "age", Value: -1}}).SetProjection(bson.M{"name": 1}),
+		options.FindOneAndDelete().SetCollation(collation).SetHint("age_1").SetComment("soft delete").SetLet(bson.M{"x": 1}),
+	})
+
+	got := &options.FindOneAndUpdateOptions{}
+	for _, opt := range opts {
+		for _, fn := range opt.List() {
+			assert.NoError(t, fn(got))
+		}
+	}
+	assert.Equal(t, &options.FindOneAndUpdateOptions{
+		Sort:       bson.D{{Key: "age", Value: -1}},
+		Projection: bson.M{"name": 1},
+		Collation:  collation,
+		Hint:       "age_1",
+		Comment:    "soft delete",
+		Let:        bson.M{"x": 1},
+	}, got)
+}
+
+func TestFinder_distinctError(t *testing.T) {
+	client, err := mongo.Connect()
+	assert.NoError(t, err)
+	f := NewFinder[any](client.Database("db-test").Collection("test_user"), nil, nil)
+
+	errRejected := errors.New("rejected")
+	result := f.distinctError(context.Background(), errRejected)
+	assert.ErrorIs(t, result.Err(), errRejected)
+}
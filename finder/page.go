@@ -0,0 +1,402 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/chenmingyong0423/go-mongox/v2/internal/pkg/utils"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	ErrInvalidPage = errors.New("mongox: page and size must be positive")
+	// ErrInvalidPageToken is returned by PageAfter when the token has been tampered with,
+	// was signed with another secret or was produced with other sort keys
+	ErrInvalidPageToken = errors.New("mongox: invalid page token")
+	// ErrUnorderedSort is returned by PageAfter when the sort is a map with several keys, the order of the keys is undefined
+	ErrUnorderedSort = errors.New("mongox: the sort of a keyset pagination must be ordered, use bson.D")
+)
+
+// defaultPageTokenSecret signs the page tokens when no secret is set, the tokens are only valid in the current process
+var defaultPageTokenSecret = func() []byte {
+	secret := make([]byte, 32)
+	_, _ = rand.Read(secret)
+	return secret
+}()
+
+// Page is a page of the offset pagination
+type Page[T any] struct {
+	Items []*T
+	// Total is the number of documents matching the filter
+	Total   int64
+	Page    int64
+	Size    int64
+	HasNext bool
+}
+
+// KeysetPage is a page of the keyset pagination
+type KeysetPage[T any] struct {
+	Items []*T
+	// NextToken is passed to PageAfter to get the next page, it is empty if there is no next page
+	NextToken string
+	HasNext   bool
+}
+
+// PageTokenSecret is used to set the secret which signs the tokens of PageAfter.
+// Without a secret the tokens are signed with a random one and are only valid in the current process.
+func (f *Finder[T]) PageTokenSecret(secret []byte) *Finder[T] {
+	f.pageTokenSecret = secret
+	return f
+}
+
+// Paginate returns the page-th page of size documents, pages start from 1.
+// The documents and the total are fetched in a single aggregation with $facet, so the page must fit in 16MB.
+func (f *Finder[T]) Paginate(ctx context.Context, page, size int64, opts ...options.Lister[options.AggregateOptions]) (*Page[T], error) {
+	if page < 1 || size < 1 {
+		return nil, ErrInvalidPage
+	}
+	ctx, cancel := utils.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	currentTime := f.clock()
+
+	globalOpContext := operation.NewOpContext(f.collection, operation.WithFilter(f.filter), operation.WithMongoOptions(opts), operation.WithModelHook(f.modelHook), operation.WithStartTime(currentTime), operation.WithFields(f.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNamePaginate), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(f.unscoped))
+	opContext := NewOpContext(f.collection, f.filter, WithMongoOptions[T](opts), WithModelHook[T](f.modelHook), WithStartTime[T](currentTime), WithFields[T](f.fields))
+	err := f.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeFind)
+	if err != nil {
+		return nil, err
+	}
+	ctx = globalOpContext.Context(ctx)
+
+	// the documents are sorted before $facet, whose sub-pipelines can not use the indexes
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: matchFilter(globalOpContext.Filter)}}}
+	if f.sort != nil {
+		pipeline = append(pipeline, bson.D{{Key: "$sort", Value: f.sort}})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: bson.D{
+		{Key: "items", Value: mongo.Pipeline{{{Key: "$skip", Value: (page - 1) * size}}, {{Key: "$limit", Value: size}}}},
+		{Key: "total", Value: mongo.Pipeline{{{Key: "$count", Value: "count"}}}},
+	}}})
+
+	cursor, err := f.collection.Aggregate(ctx, pipeline, opts...)
+	if err != nil {
+		globalOpContext.Duration = f.clock().Sub(currentTime)
+		return nil, f.dbCallbacks.OnError(ctx, globalOpContext, err)
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		Items []*T `bson:"items"`
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+	}
+	if !cursor.Next(ctx) {
+		err = cursor.Err()
+		if err == nil {
+			err = mongo.ErrNoDocuments
+		}
+		globalOpContext.Duration = f.clock().Sub(currentTime)
+		return nil, f.dbCallbacks.OnError(ctx, globalOpContext, err)
+	}
+	if err = cursor.Decode(&result); err != nil {
+		globalOpContext.Duration = f.clock().Sub(currentTime)
+		return nil, f.dbCallbacks.OnError(ctx, globalOpContext, err)
+	}
+	globalOpContext.Duration = f.clock().Sub(currentTime)
+
+	p := &Page[T]{Items: result.Items, Page: page, Size: size}
+	if p.Items == nil {
+		p.Items = make([]*T, 0)
+	}
+	if len(result.Total) > 0 {
+		p.Total = result.Total[0].Count
+	}
+	p.HasNext = page*size < p.Total
+
+	globalOpContext.Result = cursor
+	globalOpContext.Doc = p.Items
+	opContext.Result = cursor
+	opContext.Docs = p.Items
+	err = f.postActionHandler(ctx, globalOpContext, opContext, operation.OpTypeAfterFind)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// PageAfter returns the size documents which follow the ones of the token in the order of the sort keys,
+// an empty token returns the first page. _id is appended to the sort keys as the tie-breaker,
+// so the pages are stable even if documents are inserted or deleted between two calls.
+// The sort must be a bson.D or a map with a single key, _id ascending is used if it is not set.
+// The token is signed, a modified token or a token produced with other sort keys returns ErrInvalidPageToken.
+func (f *Finder[T]) PageAfter(ctx context.Context, token string, size int64, opts ...options.Lister[options.FindOptions]) (*KeysetPage[T], error) {
+	if size < 1 {
+		return nil, ErrInvalidPage
+	}
+	keys, err := sortKeys(f.sort)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := utils.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	currentTime := f.clock()
+
+	filter := f.filter
+	if token != "" {
+		values, err := decodePageToken(token, keys, f.secret())
+		if err != nil {
+			return nil, err
+		}
+		filter = bson.D{{Key: "$and", Value: bson.A{matchFilter(f.filter), keysetFilter(keys, values)}}}
+	}
+
+	sort := make(bson.D, 0, len(keys))
+	for _, key := range keys {
+		sort = append(sort, bson.E{Key: key.name, Value: key.order})
+	}
+	// one more document is fetched to know whether there is a next page
+	opts = append(opts, options.Find().SetSort(sort).SetLimit(size+1))
+
+	globalOpContext := operation.NewOpContext(f.collection, operation.WithFilter(filter), operation.WithMongoOptions(opts), operation.WithModelHook(f.modelHook), operation.WithStartTime(currentTime), operation.WithFields(f.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNamePageAfter), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(f.unscoped))
+	opContext := NewOpContext(f.collection, filter, WithMongoOptions[T](opts), WithModelHook[T](f.modelHook), WithStartTime[T](currentTime), WithFields[T](f.fields))
+	err = f.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeFind)
+	if err != nil {
+		return nil, err
+	}
+	ctx = globalOpContext.Context(ctx)
+
+	cursor, err := f.collection.Find(ctx, globalOpContext.Filter, opts...)
+	if err != nil {
+		globalOpContext.Duration = f.clock().Sub(currentTime)
+		return nil, f.dbCallbacks.OnError(ctx, globalOpContext, err)
+	}
+	defer cursor.Close(ctx)
+
+	p := &KeysetPage[T]{Items: make([]*T, 0, size)}
+	var last bson.Raw
+	for cursor.Next(ctx) {
+		if int64(len(p.Items)) == size {
+			p.HasNext = true
+			break
+		}
+		t := new(T)
+		if err = cursor.Decode(t); err != nil {
+			globalOpContext.Duration = f.clock().Sub(currentTime)
+			return nil, f.dbCallbacks.OnError(ctx, globalOpContext, err)
+		}
+		p.Items = append(p.Items, t)
+		last = cursor.Current
+	}
+	if cursor.Err() != nil {
+		globalOpContext.Duration = f.clock().Sub(currentTime)
+		return nil, f.dbCallbacks.OnError(ctx, globalOpContext, cursor.Err())
+	}
+	globalOpContext.Duration = f.clock().Sub(currentTime)
+	if p.HasNext {
+		p.NextToken, err = encodePageToken(keys, last, f.secret())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	globalOpContext.Result = cursor
+	globalOpContext.Doc = p.Items
+	opContext.Result = cursor
+	opContext.Docs = p.Items
+	err = f.postActionHandler(ctx, globalOpContext, opContext, operation.OpTypeAfterFind)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (f *Finder[T]) secret() []byte {
+	if len(f.pageTokenSecret) == 0 {
+		return defaultPageTokenSecret
+	}
+	return f.pageTokenSecret
+}
+
+func matchFilter(filter any) any {
+	if filter == nil {
+		return bson.D{}
+	}
+	return filter
+}
+
+type sortKey struct {
+	name  string
+	order int32
+}
+
+// sortKeys returns the keys of the sort followed by _id
+func sortKeys(sort any) ([]sortKey, error) {
+	keys := make([]sortKey, 0)
+	switch s := sort.(type) {
+	case nil:
+	case bson.D:
+		for _, e := range s {
+			keys = append(keys, sortKey{name: e.Key, order: sortOrder(e.Value)})
+		}
+	case bson.M:
+		if len(s) > 1 {
+			return nil, ErrUnorderedSort
+		}
+		for k, v := range s {
+			keys = append(keys, sortKey{name: k, order: sortOrder(v)})
+		}
+	case map[string]any:
+		return sortKeys(bson.M(s))
+	default:
+		return nil, ErrUnorderedSort
+	}
+	for _, key := range keys {
+		if key.name == "_id" {
+			return keys, nil
+		}
+	}
+	return append(keys, sortKey{name: "_id", order: 1}), nil
+}
+
+func sortOrder(value any) int32 {
+	switch v := value.(type) {
+	case int:
+		if v < 0 {
+			return -1
+		}
+	case int32:
+		if v < 0 {
+			return -1
+		}
+	case int64:
+		if v < 0 {
+			return -1
+		}
+	case float64:
+		if v < 0 {
+			return -1
+		}
+	}
+	return 1
+}
+
+// keysetFilter matches the documents after the values in the order of the keys, e.g. for the keys a and _id:
+// {$or: [{a: {$gt: va}}, {a: va, _id: {$gt: vid}}]}.
+// MongoDB sorts null and the missing fields before the other values, the comparison operators do not match them.
+func keysetFilter(keys []sortKey, values []bson.RawValue) bson.D {
+	or := make(bson.A, 0, len(keys))
+	for i, key := range keys {
+		after, ok := afterValue(key, values[i])
+		if !ok {
+			continue
+		}
+		cond := make(bson.D, 0, i+1)
+		for j := 0; j < i; j++ {
+			// {a: null} matches null and the missing field
+			cond = append(cond, bson.E{Key: keys[j].name, Value: values[j]})
+		}
+		or = append(or, append(cond, after))
+	}
+	return bson.D{{Key: "$or", Value: or}}
+}
+
+// afterValue returns the condition on the key of the documents after the value, false if none can follow it
+func afterValue(key sortKey, value bson.RawValue) (bson.E, bool) {
+	null := value.Type == bson.TypeNull || value.Type == bson.TypeUndefined
+	switch {
+	case key.order > 0 && null:
+		// the non-null values follow null
+		return bson.E{Key: key.name, Value: bson.D{{Key: "$ne", Value: nil}}}, true
+	case key.order > 0:
+		return bson.E{Key: key.name, Value: bson.D{{Key: "$gt", Value: value}}}, true
+	case null:
+		// null is the last value in descending order
+		return bson.E{}, false
+	default:
+		return bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: key.name, Value: bson.D{{Key: "$lt", Value: value}}}},
+			bson.D{{Key: key.name, Value: nil}},
+		}}, true
+	}
+}
+
+type pageToken struct {
+	Keys   []string        `bson:"k"`
+	Orders []int32         `bson:"o"`
+	Values []bson.RawValue `bson:"v"`
+}
+
+// encodePageToken encodes the values of the keys of the document followed by the HMAC-SHA256 signature of them
+func encodePageToken(keys []sortKey, doc bson.Raw, secret []byte) (string, error) {
+	token := pageToken{Keys: make([]string, 0, len(keys)), Orders: make([]int32, 0, len(keys)), Values: make([]bson.RawValue, 0, len(keys))}
+	for _, key := range keys {
+		value, err := doc.LookupErr(strings.Split(key.name, ".")...)
+		if err != nil {
+			// the field is missing, it is sorted as null
+			value = bson.RawValue{Type: bson.TypeNull}
+		}
+		token.Keys = append(token.Keys, key.name)
+		token.Orders = append(token.Orders, key.order)
+		token.Values = append(token.Values, value)
+	}
+	payload, err := bson.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(sign(payload, secret)), nil
+}
+
+func decodePageToken(token string, keys []sortKey, secret []byte) ([]bson.RawValue, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidPageToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, sign(payload, secret)) {
+		return nil, ErrInvalidPageToken
+	}
+
+	var decoded pageToken
+	if err = bson.Unmarshal(payload, &decoded); err != nil || len(decoded.Keys) != len(keys) || len(decoded.Orders) != len(keys) || len(decoded.Values) != len(keys) {
+		return nil, ErrInvalidPageToken
+	}
+	for i, key := range keys {
+		if decoded.Keys[i] != key.name || decoded.Orders[i] != key.order {
+			return nil, ErrInvalidPageToken
+		}
+	}
+	return decoded.Values, nil
+}
+
+func sign(payload, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
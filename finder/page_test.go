@@ -0,0 +1,184 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestSortKeys(t *testing.T) {
+	testCases := []struct {
+		name string
+		sort any
+
+		want    []sortKey
+		wantErr error
+	}{
+		{
+			name: "nil",
+			sort: nil,
+			want: []sortKey{{name: "_id", order: 1}},
+		},
+		{
+			name: "bson.D",
+			sort: bson.D{{Key: "age", Value: -1}, {Key: "name", Value: 1}},
+			want: []sortKey{{name: "age", order: -1}, {name: "name", order: 1}, {name: "_id", order: 1}},
+		},
+		{
+			name: "with _id",
+			sort: bson.D{{Key: "age", Value: 1}, {Key: "_id", Value: -1}},
+			want: []sortKey{{name: "age", order: 1}, {name: "_id", order: -1}},
+		},
+		{
+			name: "bson.M with a single key",
+			sort: bson.M{"age": int64(-1)},
+			want: []sortKey{{name: "age", order: -1}, {name: "_id", order: 1}},
+		},
+		{
+			name:    "bson.M with several keys",
+			sort:    bson.M{"age": -1, "name": 1},
+			wantErr: ErrUnorderedSort,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			keys, err := sortKeys(tc.sort)
+			require.Equal(t, tc.wantErr, err)
+			require.Equal(t, tc.want, keys)
+		})
+	}
+}
+
+func TestKeysetFilter(t *testing.T) {
+	age := rawValue(t, int32(24))
+	id := rawValue(t, "1")
+
+	null := bson.RawValue{Type: bson.TypeNull}
+
+	testCases := []struct {
+		name   string
+		keys   []sortKey
+		values []bson.RawValue
+		want   bson.D
+	}{
+		{
+			name:   "ascending",
+			keys:   []sortKey{{name: "age", order: 1}, {name: "_id", order: 1}},
+			values: []bson.RawValue{age, id},
+			want: bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "age", Value: bson.D{{Key: "$gt", Value: age}}}},
+				bson.D{{Key: "age", Value: age}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: id}}}},
+			}}},
+		},
+		{
+			name:   "descending, null follows the values",
+			keys:   []sortKey{{name: "age", order: -1}, {name: "_id", order: 1}},
+			values: []bson.RawValue{age, id},
+			want: bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "$or", Value: bson.A{
+					bson.D{{Key: "age", Value: bson.D{{Key: "$lt", Value: age}}}},
+					bson.D{{Key: "age", Value: nil}},
+				}}},
+				bson.D{{Key: "age", Value: age}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: id}}}},
+			}}},
+		},
+		{
+			name:   "ascending null, the values follow it",
+			keys:   []sortKey{{name: "age", order: 1}, {name: "_id", order: 1}},
+			values: []bson.RawValue{null, id},
+			want: bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "age", Value: bson.D{{Key: "$ne", Value: nil}}}},
+				bson.D{{Key: "age", Value: null}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: id}}}},
+			}}},
+		},
+		{
+			name:   "descending null, nothing follows it",
+			keys:   []sortKey{{name: "age", order: -1}, {name: "_id", order: 1}},
+			values: []bson.RawValue{null, id},
+			want: bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "age", Value: null}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: id}}}},
+			}}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, keysetFilter(tc.keys, tc.values))
+		})
+	}
+}
+
+func rawValue(t *testing.T, value any) bson.RawValue {
+	typ, data, err := bson.MarshalValue(value)
+	require.NoError(t, err)
+	return bson.RawValue{Type: typ, Value: data}
+}
+
+func TestPageToken(t *testing.T) {
+	secret := []byte("secret")
+	keys := []sortKey{{name: "age", order: -1}, {name: "profile.city", order: 1}, {name: "_id", order: 1}}
+	doc, err := bson.Marshal(bson.D{{Key: "_id", Value: "1"}, {Key: "age", Value: 24}, {Key: "profile", Value: bson.D{{Key: "city", Value: "guangzhou"}}}})
+	require.NoError(t, err)
+
+	token, err := encodePageToken(keys, doc, secret)
+	require.NoError(t, err)
+
+	values, err := decodePageToken(token, keys, secret)
+	require.NoError(t, err)
+	require.Len(t, values, 3)
+	require.Equal(t, int32(24), values[0].Int32())
+	require.Equal(t, "guangzhou", values[1].StringValue())
+	require.Equal(t, "1", values[2].StringValue())
+
+	testCases := []struct {
+		name   string
+		token  string
+		keys   []sortKey
+		secret []byte
+	}{
+		{
+			name:   "malformed",
+			token:  "token",
+			keys:   keys,
+			secret: secret,
+		},
+		{
+			name:   "tampered",
+			token:  "A" + token[1:],
+			keys:   keys,
+			secret: secret,
+		},
+		{
+			name:   "other secret",
+			token:  token,
+			keys:   keys,
+			secret: []byte("other"),
+		},
+		{
+			name:   "other sort keys",
+			token:  token,
+			keys:   []sortKey{{name: "age", order: 1}, {name: "profile.city", order: 1}, {name: "_id", order: 1}},
+			secret: secret,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := decodePageToken(tc.token, tc.keys, tc.secret)
+			require.Equal(t, ErrInvalidPageToken, err)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/chenmingyong0423/go-mongox/v2/internal/pkg/utils"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// FindOneAs is like Finder.FindOne but decodes the document into R, only the fields of R are read from the server.
+// The after hooks of the finder are executed with a nil opContext.Doc, the db callbacks get the *R.
+func FindOneAs[T any, R any](ctx context.Context, f *Finder[T], opts ...options.Lister[options.FindOneOptions]) (*R, error) {
+	ctx, cancel := utils.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	currentTime := f.clock()
+	opts = append([]options.Lister[options.FindOneOptions]{options.FindOne().SetProjection(projection[R]())}, opts...)
+	if f.sort != nil {
+		opts = append(opts, options.FindOne().SetSort(f.sort))
+	}
+
+	r := new(R)
+
+	globalOpContext := operation.NewOpContext(f.collection, operation.WithFilter(f.filter), operation.WithMongoOptions(opts), operation.WithModelHook(f.modelHook), operation.WithStartTime(currentTime), operation.WithFields(f.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameFindOne), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(f.unscoped))
+	opContext := NewOpContext(f.collection, f.filter, WithMongoOptions[T](opts), WithModelHook[T](f.modelHook), WithStartTime[T](currentTime), WithFields[T](f.fields))
+	err := f.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeFind)
+	if err != nil {
+		return nil, err
+	}
+	ctx = globalOpContext.Context(ctx)
+
+	result := f.collection.FindOne(ctx, globalOpContext.Filter, opts...)
+	err = result.Decode(r)
+	globalOpContext.Duration = f.clock().Sub(currentTime)
+	if err != nil {
+		return nil, f.dbCallbacks.OnError(ctx, globalOpContext, err)
+	}
+
+	globalOpContext.Result = result
+	globalOpContext.Doc = r
+	opContext.Result = result
+	err = f.postActionHandler(ctx, globalOpContext, opContext, operation.OpTypeAfterFind)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// FindAs is like Finder.Find but decodes the documents into R, only the fields of R are read from the server.
+// The after hooks of the finder are executed with a nil opContext.Docs, the db callbacks get the []*R.
+func FindAs[T any, R any](ctx context.Context, f *Finder[T], opts ...options.Lister[options.FindOptions]) ([]*R, error) {
+	ctx, cancel := utils.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	currentTime := f.clock()
+	opts = f.findOptions(append([]options.Lister[options.FindOptions]{options.Find().SetProjection(projection[R]())}, opts...))
+
+	r := make([]*R, 0)
+
+	globalOpContext := operation.NewOpContext(f.collection, operation.WithFilter(f.filter), operation.WithMongoOptions(opts), operation.WithModelHook(f.modelHook), operation.WithStartTime(currentTime), operation.WithFields(f.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameFind), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(f.unscoped))
+	opContext := NewOpContext(f.collection, f.filter, WithMongoOptions[T](opts), WithModelHook[T](f.modelHook), WithStartTime[T](currentTime), WithFields[T](f.fields))
+	err := f.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeFind)
+	if err != nil {
+		return nil, err
+	}
+	ctx = globalOpContext.Context(ctx)
+
+	cursor, err := f.collection.Find(ctx, globalOpContext.Filter, opts...)
+	if err != nil {
+		globalOpContext.Duration = f.clock().Sub(currentTime)
+		return nil, f.dbCallbacks.OnError(ctx, globalOpContext, err)
+	}
+	defer cursor.Close(ctx)
+	err = cursor.All(ctx, &r)
+	globalOpContext.Duration = f.clock().Sub(currentTime)
+	if err != nil {
+		return nil, f.dbCallbacks.OnError(ctx, globalOpContext, err)
+	}
+
+	globalOpContext.Result = cursor
+	globalOpContext.Doc = r
+	opContext.Result = cursor
+	err = f.postActionHandler(ctx, globalOpContext, opContext, operation.OpTypeAfterFind)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// projection includes the fields of R the way the bson codec names them, _id is excluded if R has no _id field
+func projection[R any]() bson.D {
+	p := projectionOf(reflect.TypeOf((*R)(nil)).Elem())
+	for _, e := range p {
+		if e.Key == "_id" {
+			return p
+		}
+	}
+	return append(p, bson.E{Key: "_id", Value: 0})
+}
+
+func projectionOf(t reflect.Type) bson.D {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return bson.D{}
+	}
+	p := make(bson.D, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, inline := utils.BsonName(sf)
+		if name == "-" {
+			continue
+		}
+		if inline {
+			p = append(p, projectionOf(sf.Type)...)
+			continue
+		}
+		p = append(p, bson.E{Key: name, Value: 1})
+	}
+	return p
+}
@@ -0,0 +1,43 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type Base struct {
+	ID bson.ObjectID `bson:"_id"`
+}
+
+func TestProjection(t *testing.T) {
+	type UserName struct {
+		Name string `bson:"name"`
+	}
+	type UserSummary struct {
+		Base     `bson:",inline"`
+		Name     string `bson:"name,omitempty"`
+		Age      int64
+		Profile  struct{ City string } `bson:"profile"`
+		Ignored  string                `bson:"-"`
+		internal string
+	}
+
+	require.Equal(t, bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 0}}, projection[UserName]())
+	require.Equal(t, bson.D{{Key: "_id", Value: 1}, {Key: "name", Value: 1}, {Key: "age", Value: 1}, {Key: "profile", Value: 1}}, projection[UserSummary]())
+}
@@ -32,6 +32,7 @@ type OpContext[T any] struct {
 	Col          *mongo.Collection `opt:"-"`
 	Filter       any               `opt:"-"`
 	Updates      any
+	Replacement  *T
 	MongoOptions any
 	Fields       []*field.Filed
 	ModelHook    any
@@ -70,6 +71,12 @@ func WithUpdates[T any](updates any) OpContextOption[T] {
 	}
 }
 
+func WithReplacement[T any](replacement *T) OpContextOption[T] {
+	return func(opContext *OpContext[T]) {
+		opContext.Replacement = replacement
+	}
+}
+
 func WithMongoOptions[T any](mongoOptions any) OpContextOption[T] {
 	return func(opContext *OpContext[T]) {
 		opContext.MongoOptions = mongoOptions
@@ -0,0 +1,213 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongox
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/chenmingyong0423/go-mongox/v2/field"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// SyncIndexesResult reports the index names handled by SyncIndexes
+type SyncIndexesResult struct {
+	// Created are the indexes declared by the model which did not exist
+	Created []string
+	// Changed are the indexes which exist with a different definition, they are left untouched
+	Changed []string
+	// Unknown are the existing indexes which are not declared by the model
+	Unknown []string
+	// Dropped are the unknown indexes dropped because of WithDropUnknownIndexes
+	Dropped []string
+}
+
+type syncIndexesOptions struct {
+	dropUnknown bool
+}
+
+type SyncIndexesOption func(*syncIndexesOptions)
+
+// WithDropUnknownIndexes drops the indexes which are not declared by the model, the _id index is always kept
+func WithDropUnknownIndexes() SyncIndexesOption {
+	return func(opts *syncIndexesOptions) {
+		opts.dropUnknown = true
+	}
+}
+
+// SyncIndexes makes the indexes of the collection match the ones declared by the mongox tags of the model.
+// Missing indexes are created, indexes whose definition has changed are only reported because rebuilding
+// an index can be expensive, they have to be dropped manually.
+func (c *Collection[T]) SyncIndexes(ctx context.Context, opts ...SyncIndexesOption) (*SyncIndexesResult, error) {
+	syncOpts := &syncIndexesOptions{}
+	for _, opt := range opts {
+		opt(syncOpts)
+	}
+
+	specs, err := c.collection.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]mongo.IndexSpecification, len(specs))
+	for _, spec := range specs {
+		existing[spec.Name] = spec
+	}
+
+	result := &SyncIndexesResult{}
+	definitions := indexDefinitions(c.fields)
+	declared := make(map[string]struct{}, len(definitions))
+	models := make([]mongo.IndexModel, 0, len(definitions))
+	for _, def := range definitions {
+		declared[def.name] = struct{}{}
+		spec, ok := existing[def.name]
+		if !ok {
+			models = append(models, def.model())
+			result.Created = append(result.Created, def.name)
+			continue
+		}
+		if !def.matches(spec) {
+			result.Changed = append(result.Changed, def.name)
+		}
+	}
+
+	if len(models) > 0 {
+		if _, err = c.collection.Indexes().CreateMany(ctx, models); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, spec := range specs {
+		if _, ok := declared[spec.Name]; ok || spec.Name == "_id_" {
+			continue
+		}
+		result.Unknown = append(result.Unknown, spec.Name)
+		if syncOpts.dropUnknown {
+			if err = c.collection.Indexes().DropOne(ctx, spec.Name); err != nil {
+				return nil, err
+			}
+			result.Dropped = append(result.Dropped, spec.Name)
+		}
+	}
+	return result, nil
+}
+
+type indexDefinition struct {
+	name   string
+	keys   bson.D
+	unique bool
+	sparse bool
+	text   bool
+	// ttl in seconds, nil means no expiration
+	ttl *int32
+}
+
+func (d *indexDefinition) model() mongo.IndexModel {
+	opts := options.Index().SetName(d.name)
+	if d.unique {
+		opts.SetUnique(true)
+	}
+	if d.sparse {
+		opts.SetSparse(true)
+	}
+	if d.ttl != nil {
+		opts.SetExpireAfterSeconds(*d.ttl)
+	}
+	return mongo.IndexModel{Keys: d.keys, Options: opts}
+}
+
+// matches reports whether the existing index has the same definition
+func (d *indexDefinition) matches(spec mongo.IndexSpecification) bool {
+	if d.unique != (spec.Unique != nil && *spec.Unique) || d.sparse != (spec.Sparse != nil && *spec.Sparse) {
+		return false
+	}
+	if (d.ttl == nil) != (spec.ExpireAfterSeconds == nil) || d.ttl != nil && *d.ttl != *spec.ExpireAfterSeconds {
+		return false
+	}
+	if d.text {
+		// the keys of a text index are stored as _fts and _ftsx
+		return true
+	}
+	elements, err := spec.KeysDocument.Elements()
+	if err != nil || len(elements) != len(d.keys) {
+		return false
+	}
+	for i, element := range elements {
+		order, ok := element.Value().AsInt64OK()
+		if !ok || element.Key() != d.keys[i].Key || order != int64(d.keys[i].Value.(int32)) {
+			return false
+		}
+	}
+	return true
+}
+
+// indexDefinitions builds the index definitions from the fields in the order of declaration.
+// Fields with the same index name are grouped into a compound index, unnamed indexes
+// are named the way MongoDB names them by default, e.g. age_1 or name_text.
+func indexDefinitions(fields []*field.Filed) []*indexDefinition {
+	definitions := make([]*indexDefinition, 0)
+	named := make(map[string]*indexDefinition)
+	for _, fd := range flattenFields(fields) {
+		if fd.Index == nil {
+			continue
+		}
+		def, ok := named[fd.Index.Name]
+		if !ok {
+			def = &indexDefinition{name: fd.Index.Name}
+			definitions = append(definitions, def)
+			if fd.Index.Name != "" {
+				named[fd.Index.Name] = def
+			}
+		}
+
+		if fd.Index.Text {
+			def.text = true
+			def.keys = append(def.keys, bson.E{Key: fd.MongoField, Value: "text"})
+		} else {
+			def.keys = append(def.keys, bson.E{Key: fd.MongoField, Value: int32(fd.Index.Order)})
+		}
+		def.unique = def.unique || fd.Index.Unique
+		def.sparse = def.sparse || fd.Index.Sparse
+		if fd.Index.TTL > 0 {
+			ttl := int32(fd.Index.TTL.Seconds())
+			def.ttl = &ttl
+		}
+	}
+
+	for _, def := range definitions {
+		if def.name == "" {
+			parts := make([]string, 0, len(def.keys))
+			for _, key := range def.keys {
+				parts = append(parts, fmt.Sprintf("%s_%v", key.Key, key.Value))
+			}
+			def.name = strings.Join(parts, "_")
+		}
+	}
+	return definitions
+}
+
+func flattenFields(fields []*field.Filed) []*field.Filed {
+	result := make([]*field.Filed, 0, len(fields))
+	for _, fd := range fields {
+		if fd.InlinedFields != nil {
+			result = append(result, flattenFields(fd.InlinedFields)...)
+		} else {
+			result = append(result, fd)
+		}
+	}
+	return result
+}
@@ -0,0 +1,60 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build e2e
+
+package mongox
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func TestCollection_e2e_SyncIndexes(t *testing.T) {
+	type User struct {
+		ID   bson.ObjectID `bson:"_id,omitempty"`
+		Name string        `bson:"name" mongox:"index,unique"`
+		Age  int           `bson:"age" mongox:"index:idx_age_city"`
+		City string        `bson:"city" mongox:"index:idx_age_city,order:-1"`
+	}
+	db := NewClient(getMongoClient(t), &Config{}).NewDatabase("db-test")
+	collection := NewCollection[User](db, "test_index")
+	ctx := context.Background()
+	defer func() {
+		require.NoError(t, collection.Collection().Drop(ctx))
+	}()
+
+	_, err := collection.Collection().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "age", Value: 1}}, Options: options.Index().SetName("idx_age_city")},
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetName("email_1")},
+	})
+	require.NoError(t, err)
+
+	result, err := collection.SyncIndexes(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"name_1"}, result.Created)
+	require.Equal(t, []string{"idx_age_city"}, result.Changed)
+	require.Equal(t, []string{"email_1"}, result.Unknown)
+	require.Empty(t, result.Dropped)
+
+	result, err = collection.SyncIndexes(ctx, WithDropUnknownIndexes())
+	require.NoError(t, err)
+	require.Empty(t, result.Created)
+	require.Equal(t, []string{"email_1"}, result.Dropped)
+}
@@ -0,0 +1,85 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongox
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chenmingyong0423/go-mongox/v2/field"
+	"github.com/chenmingyong0423/go-mongox/v2/internal/pkg/utils"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func Test_indexDefinitions(t *testing.T) {
+	type User struct {
+		Model     `bson:",inline"`
+		Name      string    `bson:"name" mongox:"index,unique"`
+		Age       int       `bson:"age" mongox:"index:idx_age_city"`
+		City      string    `bson:"city" mongox:"index:idx_age_city,order:-1"`
+		Bio       string    `bson:"bio" mongox:"text"`
+		ExpiredAt time.Time `bson:"expired_at" mongox:"ttl:1h"`
+		Nickname  string    `bson:"nickname"`
+	}
+
+	got := indexDefinitions(field.ParseFields(User{}))
+	require.Equal(t, []*indexDefinition{
+		{name: "name_1", keys: bson.D{{Key: "name", Value: int32(1)}}, unique: true},
+		{name: "idx_age_city", keys: bson.D{{Key: "age", Value: int32(1)}, {Key: "city", Value: int32(-1)}}},
+		{name: "bio_text", keys: bson.D{{Key: "bio", Value: "text"}}, text: true},
+		{name: "expired_at_1", keys: bson.D{{Key: "expired_at", Value: int32(1)}}, ttl: utils.ToPtr(int32(3600))},
+	}, got)
+}
+
+func Test_indexDefinition_matches(t *testing.T) {
+	keys, err := bson.Marshal(bson.D{{Key: "age", Value: int32(1)}, {Key: "city", Value: int32(-1)}})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name string
+		def  *indexDefinition
+		spec mongo.IndexSpecification
+		want bool
+	}{
+		{
+			name: "same",
+			def:  &indexDefinition{name: "idx", keys: bson.D{{Key: "age", Value: int32(1)}, {Key: "city", Value: int32(-1)}}},
+			spec: mongo.IndexSpecification{Name: "idx", KeysDocument: keys},
+			want: true,
+		},
+		{
+			name: "different order",
+			def:  &indexDefinition{name: "idx", keys: bson.D{{Key: "age", Value: int32(1)}, {Key: "city", Value: int32(1)}}},
+			spec: mongo.IndexSpecification{Name: "idx", KeysDocument: keys},
+		},
+		{
+			name: "different unique",
+			def:  &indexDefinition{name: "idx", keys: bson.D{{Key: "age", Value: int32(1)}, {Key: "city", Value: int32(-1)}}, unique: true},
+			spec: mongo.IndexSpecification{Name: "idx", KeysDocument: keys},
+		},
+		{
+			name: "different ttl",
+			def:  &indexDefinition{name: "idx", keys: bson.D{{Key: "age", Value: int32(1)}, {Key: "city", Value: int32(-1)}}, ttl: utils.ToPtr(int32(60))},
+			spec: mongo.IndexSpecification{Name: "idx", KeysDocument: keys, ExpireAfterSeconds: utils.ToPtr(int32(30))},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, tc.def.matches(tc.spec))
+		})
+	}
+}
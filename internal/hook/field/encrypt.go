@@ -0,0 +1,513 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package field
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/chenmingyong0423/go-mongox/v2/field"
+	"github.com/chenmingyong0423/go-mongox/v2/internal/pkg/utils"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	// plaintextsKey holds the functions restoring the plaintexts of the documents encrypted in place
+	plaintextsKey = "mongox:encrypt:plaintexts"
+	// filterEncryptedKey marks the filter as encrypted, FindOneAndUpdate and the like run the handlers of two operation types
+	filterEncryptedKey = "mongox:encrypt:filter"
+
+	ciphertextVersion byte = 1
+
+	// cipherKeyInfo and nonceKeyInfo are the HKDF contexts of the subkeys of the key given by the KeyProvider
+	cipherKeyInfo = "mongox:encrypt:cipher"
+	nonceKeyInfo  = "mongox:encrypt:nonce"
+)
+
+var errMalformedCiphertext = errors.New("malformed ciphertext")
+
+// Encrypt handles the fields tagged with mongox:"encrypt" once the written values are encrypted by Execute:
+// it encrypts the equality filters on the deterministic fields, restores the plaintexts of the written documents
+// and decrypts the documents which are read
+func Encrypt(ctx context.Context, opCtx *operation.OpContext, opType operation.OpType, _ ...any) error {
+	switch opType {
+	case operation.OpTypeBeforeFind, operation.OpTypeBeforeUpdate, operation.OpTypeBeforeUpsert, operation.OpTypeBeforeReplace,
+		operation.OpTypeBeforeDelete, operation.OpTypeBeforeCount, operation.OpTypeBeforeDistinct:
+		if _, ok := opCtx.Get(filterEncryptedKey); ok {
+			return nil
+		}
+		filter, err := encryptFilter(ctx, opCtx.Filter, deterministicFields(opCtx.Fields))
+		if err != nil {
+			return err
+		}
+		opCtx.Filter = filter
+		opCtx.Set(filterEncryptedKey, true)
+	case operation.OpTypeAfterInsert, operation.OpTypeAfterReplace, operation.OpTypeOnError:
+		restorePlaintexts(opCtx)
+	case operation.OpTypeAfterFind, operation.OpTypeAfterAggregate:
+		return decryptDoc(ctx, reflect.ValueOf(opCtx.Doc), opCtx.Fields)
+	}
+	return nil
+}
+
+// encryptDoc encrypts the fields of the documents in place, the functions restoring the plaintexts are kept in the metadata
+// and run once the document is written, see OpContext.Defer
+func encryptDoc(ctx context.Context, opCtx *operation.OpContext, doc reflect.Value) error {
+	if len(field.EncryptedFields(opCtx.Fields)) == 0 {
+		return nil
+	}
+	for doc.Kind() == reflect.Ptr || doc.Kind() == reflect.Interface {
+		if doc.IsNil() {
+			return nil
+		}
+		doc = doc.Elem()
+	}
+	var restores []func()
+	var err error
+	switch doc.Kind() {
+	case reflect.Slice:
+		for i := 0; i < doc.Len() && err == nil; i++ {
+			v := doc.Index(i)
+			for v.Kind() == reflect.Ptr && !v.IsNil() {
+				v = v.Elem()
+			}
+			if v.Kind() == reflect.Struct {
+				err = encryptStruct(ctx, v, opCtx.Fields, &restores)
+			}
+		}
+	case reflect.Struct:
+		err = encryptStruct(ctx, doc, opCtx.Fields, &restores)
+	}
+	if value, ok := opCtx.Get(plaintextsKey); ok {
+		restores = append(value.([]func()), restores...)
+	} else {
+		// the plaintexts are restored by the after handlers, or by the operator if the operation fails before them
+		opCtx.Defer(func() {
+			restorePlaintexts(opCtx)
+		})
+	}
+	opCtx.Set(plaintextsKey, restores)
+	if err != nil {
+		restorePlaintexts(opCtx)
+	}
+	return err
+}
+
+func encryptStruct(ctx context.Context, dest reflect.Value, fields []*field.Filed, restores *[]func()) error {
+	for idx, fd := range fields {
+		fieldValue := dest.Field(idx)
+		if fd.InlinedFields != nil {
+			if err := encryptStruct(ctx, fieldValue, fd.InlinedFields, restores); err != nil {
+				return err
+			}
+			continue
+		}
+		if fd.Encrypt == 0 || !fieldValue.CanSet() || fieldValue.IsZero() {
+			continue
+		}
+		ciphertext, err := encryptValue(ctx, fd, fieldValue.Interface())
+		if err != nil {
+			return err
+		}
+		plaintext := reflect.ValueOf(fieldValue.Interface())
+		fieldValue.Set(reflect.ValueOf(ciphertext).Convert(fieldValue.Type()))
+		*restores = append(*restores, func() {
+			fieldValue.Set(plaintext)
+		})
+	}
+	return nil
+}
+
+func restorePlaintexts(opCtx *operation.OpContext) {
+	value, ok := opCtx.Get(plaintextsKey)
+	if !ok {
+		return
+	}
+	delete(opCtx.Metadata, plaintextsKey)
+	for _, restore := range value.([]func()) {
+		restore()
+	}
+}
+
+// encryptUpdates returns a copy of the updates whose values of the encrypted fields in $set and $setOnInsert are encrypted,
+// the updates are copied so that they can be used again
+func encryptUpdates(ctx context.Context, updates any, fields []*field.Filed) (any, error) {
+	encrypted := byMongoField(field.EncryptedFields(fields))
+	if len(encrypted) == 0 {
+		return updates, nil
+	}
+	result, _, err := mapDocument(updates, func(operator string, value any) (any, error) {
+		if operator != "$set" && operator != "$setOnInsert" {
+			return value, nil
+		}
+		setFields, _, err := mapDocument(value, func(key string, value any) (any, error) {
+			fd, ok := encrypted[key]
+			if !ok || isZero(value) {
+				return value, nil
+			}
+			return encryptValue(ctx, fd, value)
+		})
+		return setFields, err
+	})
+	return result, err
+}
+
+// encryptFilter returns a copy of the filter whose values of the equality conditions on the deterministic fields are encrypted,
+// including the ones of $in, $nin and the conditions nested in $and, $or and $nor
+func encryptFilter(ctx context.Context, filter any, fields map[string]*field.Filed) (any, error) {
+	if len(fields) == 0 {
+		return filter, nil
+	}
+	result, _, err := mapDocument(filter, func(key string, value any) (any, error) {
+		switch key {
+		case "$and", "$or", "$nor":
+			return mapArray(value, func(condition any) (any, error) {
+				return encryptFilter(ctx, condition, fields)
+			})
+		}
+		fd, ok := fields[key]
+		if !ok {
+			return value, nil
+		}
+		operators, isDoc, err := mapDocument(value, func(operator string, value any) (any, error) {
+			switch operator {
+			case "$eq", "$ne":
+				return encryptFilterValue(ctx, fd, value)
+			case "$in", "$nin":
+				return mapArray(value, func(value any) (any, error) {
+					return encryptFilterValue(ctx, fd, value)
+				})
+			}
+			return value, nil
+		})
+		if isDoc {
+			return operators, err
+		}
+		return encryptFilterValue(ctx, fd, value)
+	})
+	return result, err
+}
+
+// encryptFilterValue encrypts the string and []byte values, the other ones can not match an encrypted value and are kept
+func encryptFilterValue(ctx context.Context, fd *field.Filed, value any) (any, error) {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.String, reflect.Slice:
+		if isZero(value) {
+			return value, nil
+		}
+		if _, err := plaintextOf(value); err != nil {
+			return value, nil
+		}
+		return encryptValue(ctx, fd, value)
+	}
+	return value, nil
+}
+
+// encryptValue encrypts a string into a base64 string and a []byte into a []byte
+func encryptValue(ctx context.Context, fd *field.Filed, value any) (any, error) {
+	plaintext, err := plaintextOf(value)
+	if err != nil {
+		return nil, fmt.Errorf("mongox: encrypt field %s: %w", fd.MongoField, err)
+	}
+	ciphertext, err := seal(ctx, fd, plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("mongox: encrypt field %s: %w", fd.MongoField, err)
+	}
+	if reflect.ValueOf(value).Kind() == reflect.String {
+		return base64.StdEncoding.EncodeToString(ciphertext), nil
+	}
+	return ciphertext, nil
+}
+
+func plaintextOf(value any) ([]byte, error) {
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Kind() == reflect.String:
+		return []byte(v.String()), nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return v.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported type %T, only string and []byte can be encrypted", value)
+}
+
+// decryptDoc decrypts the fields of the documents in place. The documents whose type is not the model, e.g. the results
+// of FindAs or AggregateAs, are decrypted by matching the keys of their fields with the keys of the encrypted fields.
+func decryptDoc(ctx context.Context, doc reflect.Value, fields []*field.Filed) error {
+	for doc.Kind() == reflect.Ptr || doc.Kind() == reflect.Interface {
+		if doc.IsNil() {
+			return nil
+		}
+		doc = doc.Elem()
+	}
+	switch doc.Kind() {
+	case reflect.Slice:
+		for i := 0; i < doc.Len(); i++ {
+			if err := decryptDoc(ctx, doc.Index(i), fields); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		if isModel(doc.Type(), fields) {
+			return decryptStruct(ctx, doc, fields)
+		}
+		return decryptProjected(ctx, doc, field.EncryptedFields(fields))
+	}
+	return nil
+}
+
+func decryptStruct(ctx context.Context, dest reflect.Value, fields []*field.Filed) error {
+	for idx, fd := range fields {
+		fieldValue := dest.Field(idx)
+		if fd.InlinedFields != nil {
+			if err := decryptStruct(ctx, fieldValue, fd.InlinedFields); err != nil {
+				return err
+			}
+			continue
+		}
+		if fd.Encrypt == 0 {
+			continue
+		}
+		if err := decryptValue(ctx, fd, fieldValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decryptProjected decrypts the fields of the struct whose keys are the ones of the encrypted fields of the model
+func decryptProjected(ctx context.Context, dest reflect.Value, encrypted []*field.Filed) error {
+	if len(encrypted) == 0 {
+		return nil
+	}
+	for i := 0; i < dest.NumField(); i++ {
+		sf := dest.Type().Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		fieldValue := dest.Field(i)
+		name, inline := utils.BsonName(sf)
+		if inline {
+			for fieldValue.Kind() == reflect.Ptr && !fieldValue.IsNil() {
+				fieldValue = fieldValue.Elem()
+			}
+			if fieldValue.Kind() == reflect.Struct {
+				if err := decryptProjected(ctx, fieldValue, encrypted); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		for _, fd := range encrypted {
+			if fd.MongoField == name {
+				if err := decryptValue(ctx, fd, fieldValue); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// decryptValue decrypts the string or the []byte in place, the other kinds are skipped
+func decryptValue(ctx context.Context, fd *field.Filed, fieldValue reflect.Value) error {
+	if !fieldValue.CanSet() || fieldValue.IsZero() {
+		return nil
+	}
+	if fieldValue.Kind() == reflect.String {
+		ciphertext, err := base64.StdEncoding.DecodeString(fieldValue.String())
+		if err != nil {
+			return fmt.Errorf("mongox: decrypt field %s: %w", fd.MongoField, errMalformedCiphertext)
+		}
+		plaintext, err := open(ctx, fd, ciphertext)
+		if err != nil {
+			return fmt.Errorf("mongox: decrypt field %s: %w", fd.MongoField, err)
+		}
+		fieldValue.SetString(string(plaintext))
+	} else if fieldValue.Kind() == reflect.Slice && fieldValue.Type().Elem().Kind() == reflect.Uint8 {
+		plaintext, err := open(ctx, fd, fieldValue.Bytes())
+		if err != nil {
+			return fmt.Errorf("mongox: decrypt field %s: %w", fd.MongoField, err)
+		}
+		fieldValue.SetBytes(plaintext)
+	}
+	return nil
+}
+
+// isModel reports whether the struct is the model the fields are parsed from, e.g. not the result type of a projection
+func isModel(t reflect.Type, fields []*field.Filed) bool {
+	if t.NumField() != len(fields) {
+		return false
+	}
+	for i, fd := range fields {
+		if t.Field(i).Name != fd.Name {
+			return false
+		}
+	}
+	return true
+}
+
+// seal encrypts the plaintext with AES-GCM, the ciphertext is made of the version, the nonce and the sealed plaintext.
+// The nonce of the deterministic fields is derived from the plaintext so that the same value gives the same ciphertext.
+func seal(ctx context.Context, fd *field.Filed, plaintext []byte) ([]byte, error) {
+	key, aead, err := newAEAD(ctx, fd)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if fd.Encrypt == field.Deterministic {
+		mac := hmac.New(sha256.New, deriveKey(key, nonceKeyInfo, sha256.Size))
+		mac.Write([]byte(fd.MongoField))
+		mac.Write([]byte{0})
+		mac.Write(plaintext)
+		copy(nonce, mac.Sum(nil))
+	} else if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	ciphertext := append([]byte{ciphertextVersion}, nonce...)
+	return aead.Seal(ciphertext, nonce, plaintext, []byte(fd.MongoField)), nil
+}
+
+func open(ctx context.Context, fd *field.Filed, ciphertext []byte) ([]byte, error) {
+	_, aead, err := newAEAD(ctx, fd)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < 1+aead.NonceSize() || ciphertext[0] != ciphertextVersion {
+		return nil, errMalformedCiphertext
+	}
+	nonce := ciphertext[1 : 1+aead.NonceSize()]
+	return aead.Open(nil, nonce, ciphertext[1+aead.NonceSize():], []byte(fd.MongoField))
+}
+
+func newAEAD(ctx context.Context, fd *field.Filed) ([]byte, cipher.AEAD, error) {
+	if fd.KeyProvider == nil {
+		return nil, nil, field.ErrNoKeyProvider
+	}
+	key, err := fd.KeyProvider.Key(ctx, fd.MongoField)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, nil, aes.KeySizeError(len(key))
+	}
+	block, err := aes.NewCipher(deriveKey(key, cipherKeyInfo, len(key)))
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, aead, nil
+}
+
+// deriveKey derives a subkey of the key with HKDF-SHA256 (RFC 5869) without salt, so that the cipher and the nonce
+// of the deterministic fields never use the same key. The size must not exceed sha256.Size.
+func deriveKey(key []byte, info string, size int) []byte {
+	extract := hmac.New(sha256.New, make([]byte, sha256.Size))
+	extract.Write(key)
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write([]byte(info))
+	expand.Write([]byte{1})
+	return expand.Sum(nil)[:size]
+}
+
+func deterministicFields(fields []*field.Filed) map[string]*field.Filed {
+	deterministic := make(map[string]*field.Filed)
+	for _, fd := range field.EncryptedFields(fields) {
+		if fd.Encrypt == field.Deterministic {
+			deterministic[fd.MongoField] = fd
+		}
+	}
+	return deterministic
+}
+
+func byMongoField(fields []*field.Filed) map[string]*field.Filed {
+	result := make(map[string]*field.Filed, len(fields))
+	for _, fd := range fields {
+		result[fd.MongoField] = fd
+	}
+	return result
+}
+
+func isZero(value any) bool {
+	return value == nil || reflect.ValueOf(value).IsZero()
+}
+
+// mapDocument returns a copy of the document whose values are mapped by fn, it reports whether the value is a document
+func mapDocument(doc any, fn func(key string, value any) (any, error)) (any, bool, error) {
+	switch d := doc.(type) {
+	case bson.D:
+		result := make(bson.D, len(d))
+		for i, e := range d {
+			value, err := fn(e.Key, e.Value)
+			if err != nil {
+				return doc, true, err
+			}
+			result[i] = bson.E{Key: e.Key, Value: value}
+		}
+		return result, true, nil
+	case bson.M:
+		result := make(bson.M, len(d))
+		for key, value := range d {
+			mapped, err := fn(key, value)
+			if err != nil {
+				return doc, true, err
+			}
+			result[key] = mapped
+		}
+		return result, true, nil
+	case map[string]any:
+		result := make(map[string]any, len(d))
+		for key, value := range d {
+			mapped, err := fn(key, value)
+			if err != nil {
+				return doc, true, err
+			}
+			result[key] = mapped
+		}
+		return result, true, nil
+	}
+	return doc, false, nil
+}
+
+// mapArray returns the values of the array mapped by fn, the value is kept if it is not an array
+func mapArray(array any, fn func(value any) (any, error)) (any, error) {
+	v := reflect.ValueOf(array)
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return array, nil
+	}
+	result := make(bson.A, v.Len())
+	for i := range result {
+		value, err := fn(v.Index(i).Interface())
+		if err != nil {
+			return array, err
+		}
+		result[i] = value
+	}
+	return result, nil
+}
@@ -0,0 +1,248 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package field
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/chenmingyong0423/go-mongox/v2/field"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type encryptedUser struct {
+	ID     bson.ObjectID `bson:"_id,omitempty" mongox:"autoID"`
+	Name   string        `bson:"name"`
+	Email  string        `bson:"email" mongox:"encrypt:deterministic"`
+	Phone  string        `bson:"phone" mongox:"encrypt"`
+	Secret []byte        `bson:"secret" mongox:"encrypt"`
+}
+
+var testKey = field.StaticKey("0123456789abcdef0123456789abcdef")
+
+func encryptedFields() []*field.Filed {
+	fields := field.ParseFields(encryptedUser{})
+	for _, fd := range field.EncryptedFields(fields) {
+		fd.KeyProvider = testKey
+	}
+	return fields
+}
+
+func TestEncrypt_Insert(t *testing.T) {
+	ctx := context.Background()
+	fields := encryptedFields()
+	user := &encryptedUser{Name: "cmy", Email: "cmy@example.com", Phone: "13800000000", Secret: []byte("secret")}
+	opCtx := operation.NewOpContext(nil, operation.WithDoc(user), operation.WithReflectValue(reflect.ValueOf(user)), operation.WithFields(fields))
+
+	require.NoError(t, Execute(ctx, opCtx, operation.OpTypeBeforeInsert))
+	require.False(t, user.ID.IsZero())
+	require.Equal(t, "cmy", user.Name)
+	require.NotEqual(t, "cmy@example.com", user.Email)
+	require.NotEqual(t, "13800000000", user.Phone)
+	require.NotEqual(t, []byte("secret"), user.Secret)
+	_, err := base64.StdEncoding.DecodeString(user.Email)
+	require.NoError(t, err)
+
+	// the deterministic field gives the same ciphertext, the randomized one does not
+	other := &encryptedUser{Email: "cmy@example.com", Phone: "13800000000"}
+	otherCtx := operation.NewOpContext(nil, operation.WithDoc(other), operation.WithReflectValue(reflect.ValueOf(other)), operation.WithFields(fields))
+	require.NoError(t, Execute(ctx, otherCtx, operation.OpTypeBeforeInsert))
+	require.Equal(t, user.Email, other.Email)
+	require.NotEqual(t, user.Phone, other.Phone)
+
+	// the stored document is decrypted after it is read
+	stored := *user
+	require.NoError(t, Encrypt(ctx, operation.NewOpContext(nil, operation.WithDoc(&stored), operation.WithFields(fields)), operation.OpTypeAfterFind))
+	require.Equal(t, "cmy@example.com", stored.Email)
+	require.Equal(t, "13800000000", stored.Phone)
+	require.Equal(t, []byte("secret"), stored.Secret)
+
+	// the inserted document gets its plaintexts back
+	require.NoError(t, Encrypt(ctx, opCtx, operation.OpTypeAfterInsert))
+	require.Equal(t, "cmy@example.com", user.Email)
+	require.Equal(t, "13800000000", user.Phone)
+	require.Equal(t, []byte("secret"), user.Secret)
+}
+
+func TestEncrypt_InsertMany(t *testing.T) {
+	ctx := context.Background()
+	users := []*encryptedUser{{Email: "cmy@example.com"}, {Email: "burt@example.com"}}
+	opCtx := operation.NewOpContext(nil, operation.WithDoc(users), operation.WithReflectValue(reflect.ValueOf(users)), operation.WithFields(encryptedFields()))
+
+	require.NoError(t, Execute(ctx, opCtx, operation.OpTypeBeforeInsert))
+	require.NotEqual(t, "cmy@example.com", users[0].Email)
+	require.NotEqual(t, "burt@example.com", users[1].Email)
+
+	// the plaintexts are restored when the insert fails
+	require.NoError(t, Encrypt(ctx, opCtx, operation.OpTypeOnError))
+	require.Equal(t, "cmy@example.com", users[0].Email)
+	require.Equal(t, "burt@example.com", users[1].Email)
+
+	// the plaintexts are restored by the operator when no after or onError handler runs, e.g. a later before handler fails
+	opCtx = operation.NewOpContext(nil, operation.WithDoc(users), operation.WithReflectValue(reflect.ValueOf(users)), operation.WithFields(encryptedFields()))
+	require.NoError(t, Execute(ctx, opCtx, operation.OpTypeBeforeInsert))
+	require.NotEqual(t, "cmy@example.com", users[0].Email)
+	opCtx.Release()
+	require.Equal(t, "cmy@example.com", users[0].Email)
+	require.Equal(t, "burt@example.com", users[1].Email)
+}
+
+func Test_deriveKey(t *testing.T) {
+	// RFC 5869 A.3, the first 32 bytes of the output
+	key := bytes.Repeat([]byte{0x0b}, 22)
+	require.Equal(t, "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d", hex.EncodeToString(deriveKey(key, "", 32)))
+
+	require.Len(t, deriveKey(key, cipherKeyInfo, 16), 16)
+	require.NotEqual(t, deriveKey(key, cipherKeyInfo, 32), deriveKey(key, nonceKeyInfo, 32))
+}
+
+func TestEncrypt_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	user := &encryptedUser{Email: "cmy@example.com"}
+	opCtx := operation.NewOpContext(nil, operation.WithDoc(user), operation.WithReflectValue(reflect.ValueOf(user)), operation.WithFields(field.ParseFields(encryptedUser{})))
+	require.ErrorIs(t, Execute(ctx, opCtx, operation.OpTypeBeforeInsert), field.ErrNoKeyProvider)
+	require.Equal(t, "cmy@example.com", user.Email)
+
+	type unsupported struct {
+		Email string `bson:"email" mongox:"encrypt"`
+		Age   int    `bson:"age" mongox:"encrypt"`
+	}
+	fields := field.ParseFields(unsupported{})
+	for _, fd := range field.EncryptedFields(fields) {
+		fd.KeyProvider = testKey
+	}
+	doc := &unsupported{Email: "cmy@example.com", Age: 18}
+	opCtx = operation.NewOpContext(nil, operation.WithDoc(doc), operation.WithReflectValue(reflect.ValueOf(doc)), operation.WithFields(fields))
+	require.EqualError(t, Execute(ctx, opCtx, operation.OpTypeBeforeInsert), "mongox: encrypt field age: unsupported type int, only string and []byte can be encrypted")
+	// the fields encrypted before the failure are restored
+	require.Equal(t, "cmy@example.com", doc.Email)
+
+	stored := &encryptedUser{Email: "not a ciphertext"}
+	require.EqualError(t, Encrypt(ctx, operation.NewOpContext(nil, operation.WithDoc(stored), operation.WithFields(encryptedFields())), operation.OpTypeAfterFind),
+		"mongox: decrypt field email: malformed ciphertext")
+}
+
+func TestEncrypt_Updates(t *testing.T) {
+	ctx := context.Background()
+	updates := bson.M{"$set": bson.M{"name": "cmy", "email": "cmy@example.com"}}
+	opCtx := operation.NewOpContext(nil, operation.WithUpdates(updates), operation.WithFields(encryptedFields()))
+
+	require.NoError(t, Execute(ctx, opCtx, operation.OpTypeBeforeUpdate))
+	setFields := opCtx.Updates.(bson.M)["$set"].(bson.M)
+	require.Equal(t, "cmy", setFields["name"])
+	require.NotEqual(t, "cmy@example.com", setFields["email"])
+	// the updates of the caller are not modified
+	require.Equal(t, "cmy@example.com", updates["$set"].(bson.M)["email"])
+
+	email, err := encryptValue(ctx, deterministicFields(encryptedFields())["email"], "cmy@example.com")
+	require.NoError(t, err)
+	require.Equal(t, email, setFields["email"])
+
+	updated, err := encryptUpdates(ctx, bson.D{{Key: "$setOnInsert", Value: bson.D{{Key: "secret", Value: []byte("secret")}}}}, encryptedFields())
+	require.NoError(t, err)
+	require.NotEqual(t, []byte("secret"), updated.(bson.D)[0].Value.(bson.D)[0].Value)
+}
+
+func TestEncrypt_Filter(t *testing.T) {
+	ctx := context.Background()
+	fields := encryptedFields()
+	email, err := encryptValue(ctx, deterministicFields(fields)["email"], "cmy@example.com")
+	require.NoError(t, err)
+	other, err := encryptValue(ctx, deterministicFields(fields)["email"], "burt@example.com")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		filter any
+		want   any
+	}{
+		{
+			name:   "nil",
+			filter: nil,
+			want:   nil,
+		},
+		{
+			name:   "equality",
+			filter: bson.M{"email": "cmy@example.com", "name": "cmy"},
+			want:   bson.M{"email": email, "name": "cmy"},
+		},
+		{
+			name:   "operators",
+			filter: bson.D{{Key: "email", Value: bson.D{{Key: "$in", Value: []string{"cmy@example.com", "burt@example.com"}}, {Key: "$exists", Value: true}}}},
+			want:   bson.D{{Key: "email", Value: bson.D{{Key: "$in", Value: bson.A{email, other}}, {Key: "$exists", Value: true}}}},
+		},
+		{
+			name:   "nested",
+			filter: bson.D{{Key: "$and", Value: bson.A{bson.M{"email": bson.M{"$eq": "cmy@example.com"}}, bson.D{{Key: "deleted_at", Value: nil}}}}},
+			want:   bson.D{{Key: "$and", Value: bson.A{bson.M{"email": bson.M{"$eq": email}}, bson.D{{Key: "deleted_at", Value: nil}}}}},
+		},
+		{
+			name:   "randomized",
+			filter: bson.M{"phone": "13800000000"},
+			want:   bson.M{"phone": "13800000000"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opCtx := operation.NewOpContext(nil, operation.WithFilter(tc.filter), operation.WithFields(fields))
+			require.NoError(t, Encrypt(ctx, opCtx, operation.OpTypeBeforeFind))
+			require.Equal(t, tc.want, opCtx.Filter)
+
+			// FindOneAndUpdate runs the handlers of the find and the update
+			require.NoError(t, Encrypt(ctx, opCtx, operation.OpTypeBeforeUpdate))
+			require.Equal(t, tc.want, opCtx.Filter)
+		})
+	}
+}
+
+func TestEncrypt_Decrypt(t *testing.T) {
+	ctx := context.Background()
+	fields := encryptedFields()
+	users := []*encryptedUser{{Email: "cmy@example.com", Phone: "13800000000"}, {Name: "burt"}}
+	opCtx := operation.NewOpContext(nil, operation.WithDoc(users), operation.WithReflectValue(reflect.ValueOf(users)), operation.WithFields(fields))
+	require.NoError(t, Execute(ctx, opCtx, operation.OpTypeBeforeInsert))
+	encrypted := []*encryptedUser{{Email: users[0].Email, Phone: users[0].Phone}, {Name: "burt"}}
+
+	require.NoError(t, Encrypt(ctx, operation.NewOpContext(nil, operation.WithDoc(encrypted), operation.WithFields(fields)), operation.OpTypeAfterAggregate))
+	require.Equal(t, []*encryptedUser{{Email: "cmy@example.com", Phone: "13800000000"}, {Name: "burt"}}, encrypted)
+
+	// the documents which are not the model, e.g. the results of FindAs or AggregateAs, are decrypted by the keys of their fields
+	type Contact struct {
+		Phone string `bson:"phone"`
+	}
+	type projectedUser struct {
+		Name    string `bson:"name"`
+		Mail    string `bson:"email"`
+		Contact `bson:",inline"`
+		Secret  []byte
+	}
+	projected := []*projectedUser{{Name: "cmy", Mail: users[0].Email, Contact: Contact{Phone: users[0].Phone}}}
+	require.NoError(t, Encrypt(ctx, operation.NewOpContext(nil, operation.WithDoc(&projected), operation.WithFields(fields)), operation.OpTypeAfterFind))
+	require.Equal(t, []*projectedUser{{Name: "cmy", Mail: "cmy@example.com", Contact: Contact{Phone: "13800000000"}}}, projected)
+
+	// a value which is not a ciphertext is reported
+	malformed := &struct {
+		Email string `bson:"email"`
+	}{Email: "cmy@example.com"}
+	err := Encrypt(ctx, operation.NewOpContext(nil, operation.WithDoc(malformed), operation.WithFields(fields)), operation.OpTypeAfterAggregate)
+	require.ErrorIs(t, err, errMalformedCiphertext)
+}
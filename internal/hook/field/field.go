@@ -35,17 +35,37 @@ func Execute(ctx context.Context, opCtx *operation.OpContext, opType operation.O
 
 		switch valueOf.Type().Kind() {
 		case reflect.Slice:
-			return executeSlice(ctx, valueOf, opType, opCtx.StartTime, opCtx.Fields, opts...)
+			if err := executeSlice(ctx, valueOf, opType, opCtx.StartTime, opCtx.Fields, opts...); err != nil {
+				return err
+			}
 		case reflect.Ptr:
 			if valueOf.IsZero() {
 				return nil
 			}
-			return execute(ctx, valueOf, opType, opCtx.StartTime, opCtx.Fields, opts...)
+			if err := execute(ctx, valueOf, opType, opCtx.StartTime, opCtx.Fields, opts...); err != nil {
+				return err
+			}
 		default:
 			return nil
 		}
+		return encryptDoc(ctx, opCtx, valueOf)
+	case operation.OpTypeBeforeReplace:
+		if err := replace(ctx, opCtx); err != nil {
+			return err
+		}
+		return encryptDoc(ctx, opCtx, reflect.ValueOf(opCtx.Replacement))
 	case operation.OpTypeBeforeUpdate, operation.OpTypeBeforeUpsert:
-		return execute(ctx, opCtx.Updates, opType, opCtx.StartTime, opCtx.Fields, opts...)
+		// the defaults of an upsert skip the paths set by the filter
+		if err := execute(ctx, opCtx.Updates, opType, opCtx.StartTime, opCtx.Fields, opCtx.Filter); err != nil {
+			return err
+		}
+		applyVersion(opCtx)
+		updates, err := encryptUpdates(ctx, opCtx.Updates, opCtx.Fields)
+		if err != nil {
+			return err
+		}
+		opCtx.Updates = updates
+		return nil
 	}
 	return nil
 }
@@ -0,0 +1,228 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package field
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/chenmingyong0423/go-mongox/v2/field"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// replace fills the automatic fields of the replacement document. The update time is set to the current time,
+// the _id and the creation time are kept from the stored document when the replacement does not carry them,
+// so that a replacement does not reset the creation time. They are generated if no document matches the filter.
+//
+// The stored document is read with the filter scoped by the handlers which ran before and the sort of FindOneAndReplace,
+// the filter is then narrowed to its _id so that the fields are never copied from another document than the replaced one.
+// If the model has a version field, the filter is narrowed to the expected version, or the stored one, and the version
+// of the replacement is incremented, as applyVersion does for the updates.
+//
+// The fields set by replace are restored once the operation is over if it failed or replaced no document.
+func replace(ctx context.Context, opCtx *operation.OpContext) error {
+	dest := reflect.ValueOf(opCtx.Replacement)
+	if dest.Kind() != reflect.Ptr || dest.IsNil() || dest.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	dest = dest.Elem()
+	restore := autoFieldsRestore(dest, opCtx.Fields)
+	opCtx.Defer(func() {
+		if !replaced(opCtx) {
+			restore()
+		}
+	})
+
+	versionField := field.VersionField(opCtx.Fields)
+	projection := missingFields(dest, opCtx.Fields)
+	if versionField != nil && opCtx.Version == nil {
+		projection = append(projection, bson.E{Key: versionField.MongoField, Value: 1})
+	}
+
+	var stored bson.Raw
+	if len(projection) > 0 && opCtx.Col != nil && !isNilFilter(opCtx.Filter) {
+		if !hasKey(projection, "_id") {
+			projection = append(projection, bson.E{Key: "_id", Value: 1})
+		}
+		findOptions := options.FindOne().SetProjection(projection)
+		if sort := replaceSort(opCtx.MongoOptions); sort != nil {
+			findOptions.SetSort(sort)
+		}
+		raw, err := opCtx.Col.FindOne(ctx, opCtx.Filter, findOptions).Raw()
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+		stored = raw
+		if id, err := stored.LookupErr("_id"); err == nil {
+			opCtx.Filter = MergeFilter(opCtx.Filter, bson.D{{Key: "_id", Value: id}})
+		}
+	}
+
+	if versionField != nil {
+		expected := opCtx.Version
+		if expected == nil && stored != nil {
+			if value, err := stored.LookupErr(versionField.MongoField); err == nil {
+				if v, ok := value.AsInt64OK(); ok {
+					expected = &v
+				}
+			}
+		}
+		next := int64(1)
+		if expected != nil {
+			opCtx.Filter = MergeFilter(opCtx.Filter, bson.D{{Key: versionField.MongoField, Value: *expected}})
+			opCtx.VersionChecked = true
+			next = *expected + 1
+		}
+		setVersion(dest, opCtx.Fields, next)
+	}
+	return processFields4Replace(dest, opCtx.StartTime, opCtx.Fields, stored)
+}
+
+// autoFieldsRestore returns the function restoring the _id, time and version fields of the replacement to their current values
+func autoFieldsRestore(dest reflect.Value, fields []*field.Filed) func() {
+	var targets, values []reflect.Value
+	var collect func(dest reflect.Value, fields []*field.Filed)
+	collect = func(dest reflect.Value, fields []*field.Filed) {
+		for idx, fd := range fields {
+			fieldValue := dest.Field(idx)
+			switch {
+			case fd.InlinedFields != nil:
+				collect(fieldValue, fd.InlinedFields)
+			case fd.AutoID || fd.AutoCreateTime != 0 || fd.AutoUpdateTime != 0 || fd.Version:
+				value := reflect.New(fieldValue.Type()).Elem()
+				value.Set(fieldValue)
+				targets = append(targets, fieldValue)
+				values = append(values, value)
+			}
+		}
+	}
+	collect(dest, fields)
+	return func() {
+		for i, target := range targets {
+			target.Set(values[i])
+		}
+	}
+}
+
+// replaced reports whether the operation replaced or inserted a document, the result of a bulk write is not per model
+// so that a bulk write is assumed to have replaced the document if it did not fail
+func replaced(opCtx *operation.OpContext) bool {
+	if opCtx.Err != nil || opCtx.Result == nil {
+		return false
+	}
+	if result, ok := opCtx.Result.(*mongo.UpdateResult); ok {
+		return result != nil && (result.MatchedCount > 0 || result.UpsertedCount > 0)
+	}
+	return true
+}
+
+func hasKey(d bson.D, key string) bool {
+	for _, e := range d {
+		if e.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceSort returns the sort of the FindOneAndReplace options, ReplaceOne has no sort
+func replaceSort(mongoOptions any) any {
+	listers, ok := mongoOptions.([]options.Lister[options.FindOneAndReplaceOptions])
+	if !ok {
+		return nil
+	}
+	opts := &options.FindOneAndReplaceOptions{}
+	for _, lister := range listers {
+		if lister == nil {
+			continue
+		}
+		for _, setter := range lister.List() {
+			if setter != nil {
+				_ = setter(opts)
+			}
+		}
+	}
+	return opts.Sort
+}
+
+// missingFields returns the projection of the _id and creation time fields which are not set in the replacement
+func missingFields(dest reflect.Value, fields []*field.Filed) bson.D {
+	projection := bson.D{}
+	for idx, fd := range fields {
+		if fd.InlinedFields != nil {
+			projection = append(projection, missingFields(dest.Field(idx), fd.InlinedFields)...)
+		} else if (fd.AutoID || fd.AutoCreateTime != 0) && dest.Field(idx).IsZero() {
+			projection = append(projection, bson.E{Key: fd.MongoField, Value: 1})
+		}
+	}
+	return projection
+}
+
+func processFields4Replace(dest reflect.Value, currentTime time.Time, fields []*field.Filed, stored bson.Raw) error {
+	for idx, fd := range fields {
+		fieldValue := dest.Field(idx)
+		switch {
+		case fd.InlinedFields != nil:
+			if err := processFields4Replace(fieldValue, currentTime, fd.InlinedFields, stored); err != nil {
+				return err
+			}
+		case fd.AutoID:
+			if !fieldValue.IsZero() {
+				continue
+			}
+			ok, err := setStoredValue(fieldValue, stored, fd.MongoField)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fieldValue.Set(reflect.ValueOf(bson.NewObjectID()))
+			}
+		case fd.AutoCreateTime != 0:
+			if !fieldValue.IsZero() {
+				continue
+			}
+			ok, err := setStoredValue(fieldValue, stored, fd.MongoField)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				setTimeField(fieldValue, fd.AutoCreateTime, currentTime)
+			}
+		case fd.AutoUpdateTime != 0:
+			setTimeField(fieldValue, fd.AutoUpdateTime, currentTime)
+		}
+	}
+	return nil
+}
+
+// setStoredValue decodes the value of the field of the stored document into dest, it reports whether the field was found
+func setStoredValue(dest reflect.Value, stored bson.Raw, mongoField string) (bool, error) {
+	if stored == nil {
+		return false, nil
+	}
+	value, err := stored.LookupErr(mongoField)
+	if err != nil {
+		return false, nil
+	}
+	if err = value.Unmarshal(dest.Addr().Interface()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
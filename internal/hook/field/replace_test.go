@@ -0,0 +1,156 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package field
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/chenmingyong0423/go-mongox/v2/field"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type replacedUser struct {
+	ID               bson.ObjectID `bson:"_id,omitempty" mongox:"autoID"`
+	Name             string        `bson:"name"`
+	CreatedAt        time.Time     `bson:"created_at"`
+	UpdatedAt        time.Time     `bson:"updated_at"`
+	CreateSecondTime int64         `bson:"create_second_time" mongox:"autoCreateTime:second"`
+}
+
+func Test_processFields4Replace(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	createdAt := now.Add(-time.Hour)
+	id := bson.NewObjectID()
+	stored, err := bson.Marshal(bson.M{"_id": id, "created_at": createdAt, "create_second_time": createdAt.Unix()})
+	require.NoError(t, err)
+	fields := field.ParseFields(replacedUser{})
+
+	t.Run("stored document", func(t *testing.T) {
+		doc := &replacedUser{Name: "cmy"}
+		require.NoError(t, processFields4Replace(reflect.ValueOf(doc).Elem(), now, fields, stored))
+		require.Equal(t, id, doc.ID)
+		require.True(t, createdAt.Equal(doc.CreatedAt))
+		require.Equal(t, createdAt.Unix(), doc.CreateSecondTime)
+		require.Equal(t, now, doc.UpdatedAt)
+	})
+
+	t.Run("no stored document", func(t *testing.T) {
+		doc := &replacedUser{Name: "cmy"}
+		require.NoError(t, processFields4Replace(reflect.ValueOf(doc).Elem(), now, fields, nil))
+		require.False(t, doc.ID.IsZero())
+		require.Equal(t, now, doc.CreatedAt)
+		require.Equal(t, now.Unix(), doc.CreateSecondTime)
+		require.Equal(t, now, doc.UpdatedAt)
+	})
+
+	t.Run("fields set in the replacement", func(t *testing.T) {
+		otherID := bson.NewObjectID()
+		doc := &replacedUser{ID: otherID, Name: "cmy", CreatedAt: now.Add(-2 * time.Hour), CreateSecondTime: 1}
+		require.NoError(t, processFields4Replace(reflect.ValueOf(doc).Elem(), now, fields, stored))
+		require.Equal(t, otherID, doc.ID)
+		require.Equal(t, now.Add(-2*time.Hour), doc.CreatedAt)
+		require.Equal(t, int64(1), doc.CreateSecondTime)
+		require.Equal(t, now, doc.UpdatedAt)
+	})
+}
+
+func TestExecute_replace(t *testing.T) {
+	now := time.Now()
+	doc := &replacedUser{ID: bson.NewObjectID(), Name: "cmy", CreatedAt: now.Add(-time.Hour), CreateSecondTime: 1}
+	opCtx := operation.NewOpContext(nil, operation.WithFilter(bson.M{"name": "cmy"}), operation.WithReplacement(doc), operation.WithFields(field.ParseFields(replacedUser{})), operation.WithStartTime(now))
+
+	require.NoError(t, Execute(context.Background(), opCtx, operation.OpTypeBeforeReplace))
+	require.Equal(t, now, doc.UpdatedAt)
+	require.Equal(t, now.Add(-time.Hour), doc.CreatedAt)
+	require.Nil(t, opCtx.Updates)
+	require.False(t, opCtx.VersionChecked)
+}
+
+func TestExecute_replaceVersion(t *testing.T) {
+	type versionedUser struct {
+		ID      string `bson:"_id"`
+		Name    string `bson:"name"`
+		Version int64  `bson:"version" mongox:"version"`
+	}
+	version := int64(3)
+	doc := &versionedUser{ID: "1", Name: "cmy", Version: 3}
+	opCtx := operation.NewOpContext(nil, operation.WithFilter(bson.D{{Key: "_id", Value: "1"}}), operation.WithReplacement(doc), operation.WithFields(field.ParseFields(versionedUser{})), operation.WithVersion(&version))
+
+	require.NoError(t, Execute(context.Background(), opCtx, operation.OpTypeBeforeReplace))
+	require.Equal(t, int64(4), doc.Version)
+	require.Equal(t, bson.D{{Key: "$and", Value: bson.A{bson.D{{Key: "_id", Value: "1"}}, bson.D{{Key: "version", Value: int64(3)}}}}}, opCtx.Filter)
+	require.True(t, opCtx.VersionChecked)
+}
+
+func TestExecute_replaceRestore(t *testing.T) {
+	type versionedUser struct {
+		ID        bson.ObjectID `bson:"_id,omitempty" mongox:"autoID"`
+		Name      string        `bson:"name"`
+		CreatedAt time.Time     `bson:"created_at"`
+		UpdatedAt time.Time     `bson:"updated_at"`
+		Version   int64         `bson:"version" mongox:"version"`
+	}
+	version := int64(3)
+	now := time.Now()
+
+	testCases := []struct {
+		name    string
+		result  any
+		err     error
+		restore bool
+	}{
+		{name: "replaced", result: &mongo.UpdateResult{MatchedCount: 1, ModifiedCount: 1}},
+		{name: "inserted", result: &mongo.UpdateResult{UpsertedCount: 1}},
+		{name: "no document matched", result: &mongo.UpdateResult{}, restore: true},
+		{name: "failed", err: field.ErrVersionConflict, restore: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			doc := &versionedUser{Name: "cmy"}
+			opCtx := operation.NewOpContext(nil, operation.WithFilter(bson.D{{Key: "name", Value: "cmy"}}), operation.WithReplacement(doc), operation.WithFields(field.ParseFields(versionedUser{})), operation.WithStartTime(now), operation.WithVersion(&version))
+
+			require.NoError(t, Execute(context.Background(), opCtx, operation.OpTypeBeforeReplace))
+			require.False(t, doc.ID.IsZero())
+			require.Equal(t, int64(4), doc.Version)
+
+			opCtx.Result, opCtx.Err = tc.result, tc.err
+			opCtx.Release()
+			if tc.restore {
+				require.Equal(t, &versionedUser{Name: "cmy"}, doc)
+			} else {
+				require.False(t, doc.ID.IsZero())
+				require.Equal(t, now, doc.CreatedAt)
+				require.Equal(t, now, doc.UpdatedAt)
+				require.Equal(t, int64(4), doc.Version)
+			}
+		})
+	}
+}
+
+func Test_replaceSort(t *testing.T) {
+	require.Nil(t, replaceSort(nil))
+	require.Nil(t, replaceSort([]options.Lister[options.ReplaceOptions]{options.Replace()}))
+	require.Equal(t, bson.D{{Key: "age", Value: -1}}, replaceSort([]options.Lister[options.FindOneAndReplaceOptions]{
+		options.FindOneAndReplace().SetReturnDocument(options.After),
+		options.FindOneAndReplace().SetSort(bson.D{{Key: "age", Value: -1}}),
+	}))
+}
@@ -0,0 +1,117 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package field
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/chenmingyong0423/go-mongox/v2/field"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// SoftDelete excludes the soft-deleted documents from the filter or the pipeline,
+// and turns the delete operation into an update of the soft-delete field.
+// It does nothing if the model has no soft-delete field or the operation is unscoped.
+func SoftDelete(_ context.Context, opCtx *operation.OpContext, opType operation.OpType, _ ...any) error {
+	if opCtx.Unscoped {
+		return nil
+	}
+	fd := field.SoftDeleteField(opCtx.Fields)
+	if fd == nil {
+		return nil
+	}
+
+	switch opType {
+	case operation.OpTypeBeforeFind, operation.OpTypeBeforeUpdate, operation.OpTypeBeforeUpsert, operation.OpTypeBeforeReplace,
+		operation.OpTypeBeforeCount, operation.OpTypeBeforeDistinct:
+		opCtx.Filter = MergeFilter(opCtx.Filter, NotDeleted(fd))
+	case operation.OpTypeBeforeDelete:
+		opCtx.Filter = MergeFilter(opCtx.Filter, NotDeleted(fd))
+		opCtx.Updates = bson.M{"$set": bson.M{fd.MongoField: getTimeValue(fd.SoftDelete, opCtx.StartTime)}}
+	case operation.OpTypeBeforeAggregate:
+		opCtx.Pipeline = prependMatch(opCtx.Pipeline, NotDeleted(fd))
+	}
+	return nil
+}
+
+// NotDeleted returns the condition which matches the documents that are not soft-deleted
+func NotDeleted(fd *field.Filed) bson.D {
+	return bson.D{{Key: fd.MongoField, Value: nil}}
+}
+
+// Deleted returns the condition which matches the soft-deleted documents
+func Deleted(fd *field.Filed) bson.D {
+	return bson.D{{Key: fd.MongoField, Value: bson.D{{Key: "$ne", Value: nil}}}}
+}
+
+// MergeFilter combines the filter and the condition with $and.
+// A nil filter is returned as it is so that the driver still rejects it, an empty filter is replaced by the condition.
+func MergeFilter(filter any, cond bson.D) any {
+	if isNilFilter(filter) {
+		return filter
+	}
+	if isEmptyFilter(filter) {
+		return cond
+	}
+	if d, ok := filter.(bson.D); ok && len(d) == 1 && d[0].Key == "$and" {
+		if a, ok := d[0].Value.(bson.A); ok && len(a) > 0 && reflect.DeepEqual(a[len(a)-1], cond) {
+			// already merged, e.g. FindOneAndUpdate runs both the find and the update callbacks
+			return filter
+		}
+	}
+	return bson.D{{Key: "$and", Value: bson.A{filter, cond}}}
+}
+
+func isNilFilter(filter any) bool {
+	if filter == nil {
+		return true
+	}
+	v := reflect.ValueOf(filter)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+func isEmptyFilter(filter any) bool {
+	switch f := filter.(type) {
+	case bson.D:
+		return len(f) == 0
+	case bson.M:
+		return len(f) == 0
+	case map[string]any:
+		return len(f) == 0
+	}
+	return false
+}
+
+func prependMatch(pipeline any, cond bson.D) any {
+	match := bson.D{{Key: "$match", Value: cond}}
+	switch p := pipeline.(type) {
+	case mongo.Pipeline:
+		return append(mongo.Pipeline{match}, p...)
+	case []bson.D:
+		return append([]bson.D{match}, p...)
+	case bson.A:
+		return append(bson.A{match}, p...)
+	case []any:
+		return append([]any{match}, p...)
+	}
+	return pipeline
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package field
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/chenmingyong0423/go-mongox/v2/field"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type softDeleteUser struct {
+	ID        bson.ObjectID `bson:"_id,omitempty" mongox:"autoID"`
+	Name      string        `bson:"name"`
+	DeletedAt time.Time     `bson:"deleted_at,omitempty" mongox:"softDelete"`
+}
+
+func TestSoftDelete(t *testing.T) {
+	now := time.Now()
+	notDeleted := bson.D{{Key: "deleted_at", Value: nil}}
+
+	testCases := []struct {
+		name   string
+		opCtx  *operation.OpContext
+		opType operation.OpType
+
+		wantFilter   any
+		wantUpdates  any
+		wantPipeline any
+	}{
+		{
+			name:       "soft delete disabled",
+			opCtx:      operation.NewOpContext(nil, operation.WithFilter(bson.M{"name": "cmy"}), operation.WithFields(field.ParseFields(user{}))),
+			opType:     operation.OpTypeBeforeFind,
+			wantFilter: bson.M{"name": "cmy"},
+		},
+		{
+			name:       "unscoped",
+			opCtx:      operation.NewOpContext(nil, operation.WithFilter(bson.M{"name": "cmy"}), operation.WithFields(field.ParseFields(softDeleteUser{})), operation.WithUnscoped(true)),
+			opType:     operation.OpTypeBeforeFind,
+			wantFilter: bson.M{"name": "cmy"},
+		},
+		{
+			name:       "find with empty filter",
+			opCtx:      operation.NewOpContext(nil, operation.WithFilter(bson.D{}), operation.WithFields(field.ParseFields(softDeleteUser{}))),
+			opType:     operation.OpTypeBeforeFind,
+			wantFilter: notDeleted,
+		},
+		{
+			name:       "find",
+			opCtx:      operation.NewOpContext(nil, operation.WithFilter(bson.M{"name": "cmy"}), operation.WithFields(field.ParseFields(softDeleteUser{}))),
+			opType:     operation.OpTypeBeforeFind,
+			wantFilter: bson.D{{Key: "$and", Value: bson.A{bson.M{"name": "cmy"}, notDeleted}}},
+		},
+		{
+			name:       "update",
+			opCtx:      operation.NewOpContext(nil, operation.WithFilter(bson.M{"name": "cmy"}), operation.WithFields(field.ParseFields(softDeleteUser{}))),
+			opType:     operation.OpTypeBeforeUpdate,
+			wantFilter: bson.D{{Key: "$and", Value: bson.A{bson.M{"name": "cmy"}, notDeleted}}},
+		},
+		{
+			name:        "delete",
+			opCtx:       operation.NewOpContext(nil, operation.WithFilter(bson.M{"name": "cmy"}), operation.WithFields(field.ParseFields(softDeleteUser{})), operation.WithStartTime(now)),
+			opType:      operation.OpTypeBeforeDelete,
+			wantFilter:  bson.D{{Key: "$and", Value: bson.A{bson.M{"name": "cmy"}, notDeleted}}},
+			wantUpdates: bson.M{"$set": bson.M{"deleted_at": now}},
+		},
+		{
+			name:        "delete with nil filter",
+			opCtx:       operation.NewOpContext(nil, operation.WithFilter(bson.D(nil)), operation.WithFields(field.ParseFields(softDeleteUser{})), operation.WithStartTime(now)),
+			opType:      operation.OpTypeBeforeDelete,
+			wantFilter:  bson.D(nil),
+			wantUpdates: bson.M{"$set": bson.M{"deleted_at": now}},
+		},
+		{
+			name:         "aggregate",
+			opCtx:        operation.NewOpContext(nil, operation.WithPipeline(mongo.Pipeline{{{Key: "$sort", Value: bson.D{{Key: "name", Value: 1}}}}}), operation.WithFields(field.ParseFields(softDeleteUser{}))),
+			opType:       operation.OpTypeBeforeAggregate,
+			wantPipeline: mongo.Pipeline{{{Key: "$match", Value: notDeleted}}, {{Key: "$sort", Value: bson.D{{Key: "name", Value: 1}}}}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := SoftDelete(context.Background(), tc.opCtx, tc.opType)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantFilter, tc.opCtx.Filter)
+			require.Equal(t, tc.wantUpdates, tc.opCtx.Updates)
+			require.Equal(t, tc.wantPipeline, tc.opCtx.Pipeline)
+		})
+	}
+}
+
+func TestMergeFilter(t *testing.T) {
+	cond := bson.D{{Key: "deleted_at", Value: nil}}
+	merged := bson.D{{Key: "$and", Value: bson.A{bson.M{"name": "cmy"}, cond}}}
+
+	testCases := []struct {
+		name   string
+		filter any
+		want   any
+	}{
+		{
+			name:   "nil",
+			filter: nil,
+			want:   nil,
+		},
+		{
+			name:   "empty bson.M",
+			filter: bson.M{},
+			want:   cond,
+		},
+		{
+			name:   "filter",
+			filter: bson.M{"name": "cmy"},
+			want:   merged,
+		},
+		{
+			name:   "already merged",
+			filter: merged,
+			want:   merged,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, MergeFilter(tc.filter, cond))
+		})
+	}
+}
@@ -15,9 +15,13 @@
 package field
 
 import (
+	"fmt"
+	"math"
 	"reflect"
+	"strings"
 	"time"
 
+	"github.com/chenmingyong0423/go-mongox/v2/bsonx"
 	"github.com/chenmingyong0423/go-mongox/v2/field"
 
 	"github.com/chenmingyong0423/go-mongox/v2/operation"
@@ -50,6 +54,16 @@ func processFields4Insert(dest reflect.Value, currentTime time.Time, fields []*f
 		} else {
 			if fd.AutoID {
 				dest.Field(idx).Set(reflect.ValueOf(bson.NewObjectID()))
+			} else if fd.Version {
+				if dest.Field(idx).IsZero() {
+					setInteger(dest.Field(idx), 1)
+				}
+			} else if fd.Default != nil || fd.DefaultFn != "" {
+				if dest.Field(idx).IsZero() {
+					if err := setDefault(dest.Field(idx), fd); err != nil {
+						return err
+					}
+				}
 			} else {
 				handleTimeField(dest.Field(idx), fd, currentTime)
 			}
@@ -58,6 +72,73 @@ func processFields4Insert(dest reflect.Value, currentTime time.Time, fields []*f
 	return nil
 }
 
+// setDefault sets the default value of the field, a pointer field is set to a new pointer to the value
+func setDefault(dest reflect.Value, fd *field.Filed) error {
+	value, ok, err := field.DefaultValue(fd)
+	if err != nil || !ok {
+		return err
+	}
+	v := reflect.ValueOf(value)
+	target := dest.Type()
+	if target.Kind() == reflect.Ptr && (!v.IsValid() || v.Type() != target) {
+		target = target.Elem()
+	}
+	switch {
+	case !v.IsValid():
+		return fmt.Errorf("mongox: the default value of field %s is nil", fd.MongoField)
+	case v.Type().AssignableTo(target):
+	case v.Type().ConvertibleTo(target) && (target.Kind() != reflect.String || v.Kind() == reflect.String):
+		// e.g. an int generated for an int64 field, the numbers are not converted to strings
+		converted := v.Convert(target)
+		if !lossless(v, converted) {
+			return fmt.Errorf("mongox: the default value %v of field %s can not be converted to %s without loss", value, fd.MongoField, target)
+		}
+		v = converted
+	default:
+		return fmt.Errorf("mongox: the default value of field %s is a %s, it can not be assigned to %s", fd.MongoField, v.Type(), dest.Type())
+	}
+	if target != dest.Type() {
+		ptr := reflect.New(target)
+		ptr.Elem().Set(v)
+		v = ptr
+	}
+	dest.Set(v)
+	return nil
+}
+
+// lossless reports whether the number is converted without truncation, overflow or change of sign,
+// e.g. 1.5 converted to an int or 300 converted to an int8 is not
+func lossless(v, converted reflect.Value) bool {
+	if !isNumber(v.Kind()) || !isNumber(converted.Kind()) {
+		return true
+	}
+	if isFloat(v.Kind()) && math.IsNaN(v.Float()) {
+		return isFloat(converted.Kind())
+	}
+	if isNegative(v) != isNegative(converted) {
+		return false
+	}
+	return converted.Convert(v.Type()).Interface() == v.Interface()
+}
+
+func isNumber(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Float64
+}
+
+func isFloat(kind reflect.Kind) bool {
+	return kind == reflect.Float32 || kind == reflect.Float64
+}
+
+func isNegative(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() < 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() < 0
+	}
+	return false
+}
+
 // 设置时间字段
 func handleTimeField(dest reflect.Value, fd *field.Filed, currentTime time.Time) {
 	switch {
@@ -102,23 +183,39 @@ func beforeUpdate(dest any, currentTime time.Time, fields []*field.Filed, _ ...a
 	return nil
 }
 
-func beforeUpsert(dest any, currentTime time.Time, fields []*field.Filed, _ ...any) error {
+// beforeUpsert sets the update time with $set and the _id, the creation time and the defaults with $setOnInsert,
+// the filter of the upsert may be passed as the first option
+func beforeUpsert(dest any, currentTime time.Time, fields []*field.Filed, opts ...any) error {
 	updates, ok := dest.(bson.M)
 	if !ok || updates == nil {
 		return nil
 	}
-	setFields, ok := updates["$set"].(bson.M)
-	if !ok {
-		return nil
-	}
+	if setFields, ok := updates["$set"].(bson.M); ok {
+		updatedTimes := findAdditionalFields(currentTime, fields, findUpdatedFields)
 
-	updatedTimes := findAdditionalFields(currentTime, fields, findUpdatedFields)
+		for k, v := range updatedTimes {
+			setFields[k] = v
+		}
+	}
 
-	for k, v := range updatedTimes {
-		setFields[k] = v
+	var filter any
+	if len(opts) > 0 {
+		filter = opts[0]
 	}
+	equalities := filterEqualities(filter)
 
 	idAndCreateFields := findAdditionalFields(currentTime, fields, findUpsertFields)
+	defaults, err := defaultFields(fields)
+	if err != nil {
+		return err
+	}
+	for k, v := range defaults {
+		// the fields set by the update are not defaulted, MongoDB rejects the updates of the same path by two operators,
+		// neither are the ones the filter sets on the inserted document
+		if !updatesPath(updates, k) && !overlapsAny(equalities, k) {
+			idAndCreateFields[k] = v
+		}
+	}
 	if len(idAndCreateFields) > 0 {
 		if updates["$setOnInsert"] == nil {
 			updates["$setOnInsert"] = bson.M{}
@@ -155,6 +252,97 @@ func findAdditionalFields(currentTime time.Time, fields []*field.Filed, handler
 	return result
 }
 
+// defaultFields returns the default values of the fields, including the ones of the inlined structs
+func defaultFields(fields []*field.Filed) (map[string]any, error) {
+	result := make(map[string]any)
+	for _, fd := range fields {
+		if fd.InlinedFields != nil {
+			inlinedFields, err := defaultFields(fd.InlinedFields)
+			if err != nil {
+				return nil, err
+			}
+			for k, v := range inlinedFields {
+				result[k] = v
+			}
+			continue
+		}
+		value, ok, err := field.DefaultValue(fd)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result[fd.MongoField] = value
+		}
+	}
+	return result, nil
+}
+
+// updatesPath reports whether an operator of the updates already writes the path, one of its parents or one of its children
+func updatesPath(updates bson.M, path string) bool {
+	for _, operand := range updates {
+		fields, ok := operand.(bson.M)
+		if !ok {
+			continue
+		}
+		for k := range fields {
+			if overlaps(k, path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filterEqualities returns the paths the filter constrains by equality, including the ones of $and,
+// MongoDB sets them on the document inserted by an upsert
+func filterEqualities(filter any) []string {
+	var paths []string
+	for k, v := range bsonx.ToBsonM(filter) {
+		if k == "$and" {
+			if conditions, ok := v.(bson.A); ok {
+				for _, condition := range conditions {
+					paths = append(paths, filterEqualities(condition)...)
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(k, "$") {
+			continue
+		}
+		if operators, ok := v.(bson.M); ok && isOperators(operators) {
+			if _, ok := operators["$eq"]; !ok {
+				continue
+			}
+		}
+		paths = append(paths, k)
+	}
+	return paths
+}
+
+// isOperators reports whether the document is made of query operators such as {$gt: 18} rather than a value
+func isOperators(doc bson.M) bool {
+	for k := range doc {
+		if strings.HasPrefix(k, "$") {
+			return true
+		}
+	}
+	return false
+}
+
+func overlapsAny(paths []string, path string) bool {
+	for _, p := range paths {
+		if overlaps(p, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps reports whether the paths are the same or one of them is a parent of the other
+func overlaps(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+".") || strings.HasPrefix(b, a+".")
+}
+
 func findUpsertFields(fd *field.Filed, currentTime time.Time) (string, any) {
 	if fd.AutoID {
 		return fd.MongoField, bson.NewObjectID()
@@ -231,3 +231,127 @@ func Test_beforeUpsert(t *testing.T) {
 		})
 	}
 }
+
+func TestDefaults(t *testing.T) {
+	type Status string
+	type defaultUser struct {
+		Name    string        `bson:"name" mongox:"default:anonymous"`
+		Age     int           `bson:"age" mongox:"default:18"`
+		Score   *float64      `bson:"score" mongox:"default:1.5"`
+		Active  bool          `bson:"active" mongox:"default:true"`
+		Status  Status        `bson:"status" mongox:"default:active"`
+		Timeout time.Duration `bson:"timeout" mongox:"default:1m"`
+		Code    string        `bson:"code" mongox:"default:fn=test-code"`
+		Level   int64         `bson:"level" mongox:"default:fn=test-level"`
+	}
+	field.RegisterDefault("test-code", func() any { return "generated" })
+	field.RegisterDefault("test-level", func() any { return 3 })
+	fields := field.ParseFields(defaultUser{})
+	score := 1.5
+
+	t.Run("insert", func(t *testing.T) {
+		u := &defaultUser{Name: "cmy", Level: 5}
+		require.NoError(t, beforeInsert(reflect.ValueOf(u), time.Now(), fields))
+		require.Equal(t, &defaultUser{Name: "cmy", Age: 18, Score: &score, Active: true, Status: "active", Timeout: time.Minute, Code: "generated", Level: 5}, u)
+
+		// the pointers are not shared by the documents
+		other := &defaultUser{}
+		require.NoError(t, beforeInsert(reflect.ValueOf(other), time.Now(), fields))
+		require.Equal(t, "anonymous", other.Name)
+		require.Equal(t, int64(3), other.Level)
+		require.NotSame(t, u.Score, other.Score)
+	})
+
+	t.Run("upsert", func(t *testing.T) {
+		updates := bson.M{"$set": bson.M{"name": "cmy"}, "$inc": bson.M{"level": 1}, "$setOnInsert": bson.M{"code": "custom"}}
+		require.NoError(t, beforeUpsert(updates, time.Now(), fields))
+		require.Equal(t, bson.M{
+			"$set":         bson.M{"name": "cmy"},
+			"$inc":         bson.M{"level": 1},
+			"$setOnInsert": bson.M{"code": "custom", "age": 18, "score": 1.5, "active": true, "status": Status("active"), "timeout": time.Minute},
+		}, updates)
+	})
+
+	t.Run("upsert without $set", func(t *testing.T) {
+		updates := bson.M{"$inc": bson.M{"level": 1}}
+		require.NoError(t, beforeUpsert(updates, time.Now(), fields))
+		require.Equal(t, bson.M{
+			"$inc":         bson.M{"level": 1},
+			"$setOnInsert": bson.M{"name": "anonymous", "code": "generated", "age": 18, "score": 1.5, "active": true, "status": Status("active"), "timeout": time.Minute},
+		}, updates)
+	})
+
+	t.Run("upsert with the equalities of the filter", func(t *testing.T) {
+		updates := bson.M{"$set": bson.M{"code": "custom"}}
+		filter := bson.D{
+			{Key: "name", Value: "cmy"},
+			{Key: "age", Value: bson.M{"$gt": 18}},
+			{Key: "$and", Value: bson.A{bson.D{{Key: "status", Value: bson.M{"$eq": "banned"}}}, bson.M{"score.value": 2}}},
+		}
+		require.NoError(t, beforeUpsert(updates, time.Now(), fields, filter))
+		require.Equal(t, bson.M{
+			"$set":         bson.M{"code": "custom"},
+			"$setOnInsert": bson.M{"age": 18, "active": true, "timeout": time.Minute, "level": 3},
+		}, updates)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		type unregistered struct {
+			Code string `bson:"code" mongox:"default:fn=test-unregistered"`
+		}
+		err := beforeInsert(reflect.ValueOf(&unregistered{}), time.Now(), field.ParseFields(unregistered{}))
+		require.EqualError(t, err, `mongox: the default function "test-unregistered" of field code is not registered`)
+		err = beforeUpsert(bson.M{"$set": bson.M{}}, time.Now(), field.ParseFields(unregistered{}))
+		require.EqualError(t, err, `mongox: the default function "test-unregistered" of field code is not registered`)
+
+		type mismatched struct {
+			Code string `bson:"code" mongox:"default:fn=test-level"`
+		}
+		err = beforeInsert(reflect.ValueOf(&mismatched{}), time.Now(), field.ParseFields(mismatched{}))
+		require.EqualError(t, err, "mongox: the default value of field code is a int, it can not be assigned to string")
+
+		// the generated numbers are converted only if they are not truncated, overflowed or negated
+		field.RegisterDefault("test-fraction", func() any { return 1.5 })
+		field.RegisterDefault("test-whole", func() any { return 2.0 })
+		field.RegisterDefault("test-large", func() any { return 300 })
+		field.RegisterDefault("test-negative", func() any { return -1 })
+		type lossy struct {
+			Fraction int     `bson:"fraction" mongox:"default:fn=test-fraction"`
+			Whole    int     `bson:"whole" mongox:"default:fn=test-whole"`
+			Large    int8    `bson:"large" mongox:"default:fn=test-large"`
+			Negative uint    `bson:"negative" mongox:"default:fn=test-negative"`
+			Ratio    float32 `bson:"ratio" mongox:"default:fn=test-fraction"`
+		}
+		fields := field.ParseFields(lossy{})
+		testCases := []struct {
+			name    string
+			doc     *lossy
+			wantErr string
+		}{
+			{
+				name:    "float to int",
+				doc:     &lossy{Whole: 1, Large: 1, Negative: 1, Ratio: 1},
+				wantErr: "mongox: the default value 1.5 of field fraction can not be converted to int without loss",
+			},
+			{
+				name:    "overflow",
+				doc:     &lossy{Fraction: 1, Whole: 1, Negative: 1, Ratio: 1},
+				wantErr: "mongox: the default value 300 of field large can not be converted to int8 without loss",
+			},
+			{
+				name:    "negative to unsigned",
+				doc:     &lossy{Fraction: 1, Whole: 1, Large: 1, Ratio: 1},
+				wantErr: "mongox: the default value -1 of field negative can not be converted to uint without loss",
+			},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				require.EqualError(t, beforeInsert(reflect.ValueOf(tc.doc), time.Now(), fields), tc.wantErr)
+			})
+		}
+
+		doc := &lossy{Fraction: 1, Large: 1, Negative: 1}
+		require.NoError(t, beforeInsert(reflect.ValueOf(doc), time.Now(), fields))
+		require.Equal(t, &lossy{Fraction: 1, Whole: 2, Large: 1, Negative: 1, Ratio: 1.5}, doc)
+	})
+}
@@ -0,0 +1,85 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package field
+
+import (
+	"reflect"
+
+	"github.com/chenmingyong0423/go-mongox/v2/field"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// applyVersion increments the version field of the updates and, if the expected version is given,
+// adds it to the filter so that the update only matches the document which has not been modified since.
+func applyVersion(opCtx *operation.OpContext) {
+	fd := field.VersionField(opCtx.Fields)
+	if fd == nil {
+		return
+	}
+	updates, ok := opCtx.Updates.(bson.M)
+	if !ok || updates == nil {
+		return
+	}
+
+	if !updatesField(updates, fd.MongoField) {
+		if updates["$inc"] == nil {
+			updates["$inc"] = bson.M{}
+		}
+		if incFields, ok := updates["$inc"].(bson.M); ok {
+			incFields[fd.MongoField] = 1
+		}
+	}
+
+	if opCtx.Version != nil {
+		opCtx.Filter = MergeFilter(opCtx.Filter, bson.D{{Key: fd.MongoField, Value: *opCtx.Version}})
+		opCtx.VersionChecked = true
+	}
+}
+
+// updatesField reports whether the field is already modified by one of the update operators,
+// adding $inc for it would make the update fail with a conflict
+func updatesField(updates bson.M, mongoField string) bool {
+	for _, operator := range []string{"$set", "$inc", "$setOnInsert", "$unset"} {
+		if fields, ok := updates[operator].(bson.M); ok {
+			if _, ok = fields[mongoField]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setVersion sets the version field of the document to the version
+func setVersion(dest reflect.Value, fields []*field.Filed, version int64) {
+	for idx, fd := range fields {
+		switch {
+		case fd.InlinedFields != nil:
+			setVersion(dest.Field(idx), fd.InlinedFields, version)
+		case fd.Version:
+			setInteger(dest.Field(idx), version)
+		}
+	}
+}
+
+// setInteger sets the signed or unsigned integer, ParseFields only accepts the version fields of these kinds
+func setInteger(dest reflect.Value, value int64) {
+	switch dest.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		dest.SetUint(uint64(value))
+	default:
+		dest.SetInt(value)
+	}
+}
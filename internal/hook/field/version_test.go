@@ -0,0 +1,98 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package field
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/chenmingyong0423/go-mongox/v2/field"
+	"github.com/chenmingyong0423/go-mongox/v2/internal/pkg/utils"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type versionedUser struct {
+	ID      bson.ObjectID `bson:"_id,omitempty" mongox:"autoID"`
+	Name    string        `bson:"name"`
+	Version int64         `bson:"version" mongox:"version"`
+}
+
+func Test_applyVersion(t *testing.T) {
+	testCases := []struct {
+		name  string
+		opCtx *operation.OpContext
+
+		wantFilter  any
+		wantUpdates any
+	}{
+		{
+			name:        "no version field",
+			opCtx:       operation.NewOpContext(nil, operation.WithFilter(bson.M{"name": "cmy"}), operation.WithUpdates(bson.M{"$set": bson.M{"name": "burt"}}), operation.WithFields(field.ParseFields(user{})), operation.WithVersion(utils.ToPtr(int64(1)))),
+			wantFilter:  bson.M{"name": "cmy"},
+			wantUpdates: bson.M{"$set": bson.M{"name": "burt"}},
+		},
+		{
+			name:        "without expected version",
+			opCtx:       operation.NewOpContext(nil, operation.WithFilter(bson.M{"name": "cmy"}), operation.WithUpdates(bson.M{"$set": bson.M{"name": "burt"}}), operation.WithFields(field.ParseFields(versionedUser{}))),
+			wantFilter:  bson.M{"name": "cmy"},
+			wantUpdates: bson.M{"$set": bson.M{"name": "burt"}, "$inc": bson.M{"version": 1}},
+		},
+		{
+			name:        "with expected version",
+			opCtx:       operation.NewOpContext(nil, operation.WithFilter(bson.M{"name": "cmy"}), operation.WithUpdates(bson.M{"$set": bson.M{"name": "burt"}}), operation.WithFields(field.ParseFields(versionedUser{})), operation.WithVersion(utils.ToPtr(int64(3)))),
+			wantFilter:  bson.D{{Key: "$and", Value: bson.A{bson.M{"name": "cmy"}, bson.D{{Key: "version", Value: int64(3)}}}}},
+			wantUpdates: bson.M{"$set": bson.M{"name": "burt"}, "$inc": bson.M{"version": 1}},
+		},
+		{
+			name:        "version already updated",
+			opCtx:       operation.NewOpContext(nil, operation.WithFilter(bson.M{"name": "cmy"}), operation.WithUpdates(bson.M{"$set": bson.M{"version": 10}}), operation.WithFields(field.ParseFields(versionedUser{}))),
+			wantFilter:  bson.M{"name": "cmy"},
+			wantUpdates: bson.M{"$set": bson.M{"version": 10}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			applyVersion(tc.opCtx)
+			require.Equal(t, tc.wantFilter, tc.opCtx.Filter)
+			require.Equal(t, tc.wantUpdates, tc.opCtx.Updates)
+		})
+	}
+}
+
+func TestExecute_InsertVersion(t *testing.T) {
+	users := []*versionedUser{{Name: "cmy"}, {Name: "burt", Version: 5}}
+	opCtx := operation.NewOpContext(nil, operation.WithReflectValue(reflect.ValueOf(users)), operation.WithFields(field.ParseFields(versionedUser{})))
+	require.NoError(t, Execute(context.Background(), opCtx, operation.OpTypeBeforeInsert))
+	require.Equal(t, int64(1), users[0].Version)
+	require.Equal(t, int64(5), users[1].Version)
+}
+
+func TestExecute_InsertUnsignedVersion(t *testing.T) {
+	type unsignedUser struct {
+		Name    string `bson:"name"`
+		Version uint32 `bson:"version" mongox:"version"`
+	}
+	users := []*unsignedUser{{Name: "cmy"}, {Name: "burt", Version: 5}}
+	opCtx := operation.NewOpContext(nil, operation.WithReflectValue(reflect.ValueOf(users)), operation.WithFields(field.ParseFields(unsignedUser{})))
+	require.NoError(t, Execute(context.Background(), opCtx, operation.OpTypeBeforeInsert))
+	require.Equal(t, uint32(1), users[0].Version)
+	require.Equal(t, uint32(5), users[1].Version)
+
+	setVersion(reflect.ValueOf(users[1]).Elem(), opCtx.Fields, 6)
+	require.Equal(t, uint32(6), users[1].Version)
+}
@@ -15,8 +15,11 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/v2/mongo"
 
@@ -103,3 +106,35 @@ func IsNumeric(value any) bool {
 		return false
 	}
 }
+
+// WithTimeout returns a copy of ctx which is canceled after timeout.
+// ctx is returned as it is if timeout is not positive or ctx already has a deadline, the caller's deadline wins.
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// BsonName returns the key of the struct field the way the bson codec names it and whether the field is inlined,
+// the key defaults to the lowercased field name
+func BsonName(sf reflect.StructField) (string, bool) {
+	tag, ok := sf.Tag.Lookup("bson")
+	if !ok && !strings.Contains(string(sf.Tag), ":") && len(sf.Tag) > 0 {
+		tag = string(sf.Tag)
+	}
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	for _, part := range parts[1:] {
+		if part == "inline" {
+			return name, true
+		}
+	}
+	if name == "" {
+		name = strings.ToLower(sf.Name)
+	}
+	return name, false
+}
@@ -0,0 +1,28 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongox
+
+import (
+	"context"
+
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+)
+
+// WithOpLabels returns a copy of the context carrying the labels,
+// e.g. WithOpLabels(ctx, map[string]string{"tenant": "acme", "feature": "checkout"}). The labels are merged with the ones already in the context
+// and the plugins read them as OpContext.Labels of the operations run with the context.
+func WithOpLabels(ctx context.Context, labels map[string]string) context.Context {
+	return operation.ContextWithLabels(ctx, labels)
+}
@@ -0,0 +1,33 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongox
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithOpLabels(t *testing.T) {
+	ctx := WithOpLabels(context.Background(), map[string]string{"tenant": "acme", "feature": "checkout"})
+	child := WithOpLabels(ctx, map[string]string{"feature": "refund"})
+
+	require.Equal(t, map[string]string{"tenant": "acme", "feature": "checkout"}, operation.LabelsFromContext(ctx))
+	require.Equal(t, map[string]string{"tenant": "acme", "feature": "refund"}, operation.LabelsFromContext(child))
+	require.Nil(t, operation.LabelsFromContext(context.Background()))
+	require.Equal(t, operation.LabelsFromContext(ctx), operation.LabelsFromContext(WithOpLabels(ctx, nil)))
+}
@@ -0,0 +1,62 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: bulkwriter.go
+//
+// Generated by this command:
+//
+//	mockgen -source=bulkwriter.go -destination=../mock/bulkwriter.mock.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	bulkwriter "github.com/chenmingyong0423/go-mongox/v2/bulkwriter"
+	options "go.mongodb.org/mongo-driver/v2/mongo/options"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockIBulkWriter is a mock of IBulkWriter interface.
+type MockIBulkWriter[T any] struct {
+	ctrl     *gomock.Controller
+	recorder *MockIBulkWriterMockRecorder[T]
+}
+
+// MockIBulkWriterMockRecorder is the mock recorder for MockIBulkWriter.
+type MockIBulkWriterMockRecorder[T any] struct {
+	mock *MockIBulkWriter[T]
+}
+
+// NewMockIBulkWriter creates a new mock instance.
+func NewMockIBulkWriter[T any](ctrl *gomock.Controller) *MockIBulkWriter[T] {
+	mock := &MockIBulkWriter[T]{ctrl: ctrl}
+	mock.recorder = &MockIBulkWriterMockRecorder[T]{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIBulkWriter[T]) EXPECT() *MockIBulkWriterMockRecorder[T] {
+	return m.recorder
+}
+
+// BulkWrite mocks base method.
+func (m *MockIBulkWriter[T]) BulkWrite(ctx context.Context, opts ...options.Lister[options.BulkWriteOptions]) (*bulkwriter.BulkWriteResult, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "BulkWrite", varargs...)
+	ret0, _ := ret[0].(*bulkwriter.BulkWriteResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BulkWrite indicates an expected call of BulkWrite.
+func (mr *MockIBulkWriterMockRecorder[T]) BulkWrite(ctx any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkWrite", reflect.TypeOf((*MockIBulkWriter[T])(nil).BulkWrite), varargs...)
+}
@@ -24,6 +24,7 @@ import (
 // - ID: the primary key of the document
 // - CreatedAt: the time when the document was created
 // - UpdatedAt: the time when the document was last updated
+// - DeletedAt: the time when the document was soft-deleted, it is used when the collection is created with WithSoftDelete
 // It may be embedded into a struct to provide these fields.
 // Example:
 //
@@ -44,6 +45,8 @@ func (m *Model) DefaultId() bson.ObjectID {
 	return m.ID
 }
 
+// DefaultCreatedAt sets CreatedAt to the local time if it is zero.
+// The timestamps filled by the operators follow Config.Clock and Config.UTC instead.
 func (m *Model) DefaultCreatedAt() time.Time {
 	if m.CreatedAt.IsZero() {
 		m.CreatedAt = time.Now().Local()
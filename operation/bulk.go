@@ -0,0 +1,46 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operation
+
+// Bulk is shared by the operation contexts of the models of a BulkWrite. The handlers run for each model one at a time,
+// the ones acting once per operation, e.g. a span, use it to act once for the whole bulk write:
+// they start with the before handlers of the first model and end with the after or onError handlers of the last one.
+type Bulk struct {
+	// Size is the number of models of the bulk write
+	Size int
+	// Index is the index of the model whose before handlers are running
+	Index int
+	// Pending is the number of models whose after or onError handlers have not run yet,
+	// it is 0 for the last handlers of the bulk write, the models following a failure may never be executed
+	Pending int
+	// Err is the error returned by the bulk write, it is set before the after and onError handlers run
+	Err error
+	// Metadata is shared by the handlers of all the models
+	Metadata map[string]any
+}
+
+// Set stores the value in the metadata shared by the models
+func (b *Bulk) Set(key string, value any) {
+	if b.Metadata == nil {
+		b.Metadata = make(map[string]any)
+	}
+	b.Metadata[key] = value
+}
+
+// Get returns the value stored in the metadata shared by the models
+func (b *Bulk) Get(key string) (any, bool) {
+	value, ok := b.Metadata[key]
+	return value, ok
+}
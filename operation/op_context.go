@@ -0,0 +1,67 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operation
+
+import "context"
+
+// Set stores the value in the metadata of the operation
+func (o *OpContext) Set(key string, value any) {
+	if o.Metadata == nil {
+		o.Metadata = make(map[string]any)
+	}
+	o.Metadata[key] = value
+}
+
+// Get returns the value stored in the metadata of the operation
+func (o *OpContext) Get(key string) (any, bool) {
+	value, ok := o.Metadata[key]
+	return value, ok
+}
+
+// SetContext replaces the context passed to the driver and to the after handlers once the before handlers have run,
+// e.g. a tracing handler sets the context carrying its span so that the command spans of the driver are its children.
+// The context must be derived from Context(ctx) so that the values set by the other handlers are kept.
+func (o *OpContext) SetContext(ctx context.Context) {
+	o.ctx = ctx
+}
+
+// Context returns the context set with SetContext, or ctx if none was set
+func (o *OpContext) Context(ctx context.Context) context.Context {
+	if o.ctx != nil {
+		return o.ctx
+	}
+	return ctx
+}
+
+// Defer registers a function which Release runs once the operation is over whatever its outcome,
+// e.g. to restore the document of the caller modified in place by a before handler.
+// The operators writing the documents of the caller, i.e. the inserts, the replacements and the bulk writes, call Release.
+func (o *OpContext) Defer(fn func()) {
+	o.deferred = append(o.deferred, fn)
+}
+
+// Release runs the functions registered with Defer in the reverse order, it does nothing if they have already run
+func (o *OpContext) Release() {
+	deferred := o.deferred
+	o.deferred = nil
+	for i := len(deferred) - 1; i >= 0; i-- {
+		deferred[i]()
+	}
+}
+
+// InTransaction reports whether the operation is running inside a multi-document transaction
+func (o *OpContext) InTransaction() bool {
+	return o.Session != nil && o.Session.ClientSession() != nil && o.Session.ClientSession().TransactionRunning()
+}
@@ -0,0 +1,56 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpContext_Metadata(t *testing.T) {
+	opCtx := NewOpContext(nil)
+	_, ok := opCtx.Get("span")
+	require.False(t, ok)
+
+	opCtx.Set("span", 1)
+	value, ok := opCtx.Get("span")
+	require.True(t, ok)
+	require.Equal(t, 1, value)
+}
+
+func TestOpContext_Context(t *testing.T) {
+	type key struct{}
+	ctx := context.Background()
+	opCtx := NewOpContext(nil)
+	require.Equal(t, ctx, opCtx.Context(ctx))
+
+	spanCtx := context.WithValue(ctx, key{}, "span")
+	opCtx.SetContext(spanCtx)
+	require.Equal(t, spanCtx, opCtx.Context(ctx))
+}
+
+func TestOpContext_Release(t *testing.T) {
+	var calls []int
+	opCtx := NewOpContext(nil)
+	opCtx.Defer(func() { calls = append(calls, 1) })
+	opCtx.Defer(func() { calls = append(calls, 2) })
+
+	opCtx.Release()
+	require.Equal(t, []int{2, 1}, calls)
+	opCtx.Release()
+	require.Equal(t, []int{2, 1}, calls)
+}
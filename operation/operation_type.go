@@ -19,6 +19,7 @@
 package operation
 
 import (
+	"context"
 	"reflect"
 	"time"
 
@@ -30,37 +31,107 @@ import (
 type OpType string
 
 const (
-	OpTypeBeforeInsert OpType = "beforeInsert"
-	OpTypeAfterInsert  OpType = "afterInsert"
-	OpTypeBeforeUpdate OpType = "beforeUpdate"
-	OpTypeAfterUpdate  OpType = "afterUpdate"
-	OpTypeBeforeDelete OpType = "beforeDelete"
-	OpTypeAfterDelete  OpType = "afterDelete"
-	OpTypeBeforeUpsert OpType = "beforeUpsert"
-	OpTypeAfterUpsert  OpType = "afterUpsert"
-	OpTypeBeforeFind   OpType = "beforeFind"
-	OpTypeAfterFind    OpType = "afterFind"
-	OpTypeBeforeAny    OpType = "before*"
-	OpTypeAfterAny     OpType = "after*"
+	OpTypeBeforeInsert    OpType = "beforeInsert"
+	OpTypeAfterInsert     OpType = "afterInsert"
+	OpTypeBeforeUpdate    OpType = "beforeUpdate"
+	OpTypeAfterUpdate     OpType = "afterUpdate"
+	OpTypeBeforeDelete    OpType = "beforeDelete"
+	OpTypeAfterDelete     OpType = "afterDelete"
+	OpTypeBeforeUpsert    OpType = "beforeUpsert"
+	OpTypeAfterUpsert     OpType = "afterUpsert"
+	OpTypeBeforeFind      OpType = "beforeFind"
+	OpTypeAfterFind       OpType = "afterFind"
+	OpTypeBeforeReplace   OpType = "beforeReplace"
+	OpTypeAfterReplace    OpType = "afterReplace"
+	OpTypeBeforeAggregate OpType = "beforeAggregate"
+	OpTypeAfterAggregate  OpType = "afterAggregate"
+	OpTypeBeforeCount     OpType = "beforeCount"
+	OpTypeAfterCount      OpType = "afterCount"
+	OpTypeBeforeDistinct  OpType = "beforeDistinct"
+	OpTypeAfterDistinct   OpType = "afterDistinct"
+	// OpTypeOnError handlers run instead of the after handlers when the operation fails,
+	// OpContext.Err holds the error and the handlers may replace it
+	OpTypeOnError   OpType = "onError"
+	OpTypeBeforeAny OpType = "before*"
+	OpTypeAfterAny  OpType = "after*"
+)
+
+// the names of the operations, OpContext.OpName is the name of the method of the operator which runs the operation
+const (
+	OpNameInsertOne         = "InsertOne"
+	OpNameInsertMany        = "InsertMany"
+	OpNameUpdateOne         = "UpdateOne"
+	OpNameUpdateMany        = "UpdateMany"
+	OpNameUpsert            = "Upsert"
+	OpNameReplaceOne        = "ReplaceOne"
+	OpNameDeleteOne         = "DeleteOne"
+	OpNameDeleteMany        = "DeleteMany"
+	OpNameFindOne           = "FindOne"
+	OpNameFind              = "Find"
+	OpNameCount             = "Count"
+	OpNameEstimatedCount    = "EstimatedCount"
+	OpNameDistinct          = "Distinct"
+	OpNameFindOneAndUpdate  = "FindOneAndUpdate"
+	OpNameFindOneAndUpsert  = "FindOneAndUpsert"
+	OpNameFindOneAndReplace = "FindOneAndReplace"
+	OpNameFindOneAndDelete  = "FindOneAndDelete"
+	OpNamePaginate          = "Paginate"
+	OpNamePageAfter         = "PageAfter"
+	OpNameAggregate         = "Aggregate"
+	OpNameBulkWrite         = "BulkWrite"
 )
 
 //go:generate optioner -type OpContext -output operation_type.go -mode append
 type OpContext struct {
-	Col    *mongo.Collection `opt:"-"`
+	Col *mongo.Collection `opt:"-"`
+	// DatabaseName and CollectionName are the names of the database and the collection of Col
+	DatabaseName   string `opt:"-"`
+	CollectionName string `opt:"-"`
+	// OpName is the name of the operation, e.g. UpdateOne or UpdateMany for the beforeUpdate handlers
+	OpName string
+	// Multi reports whether the operation may write several documents, e.g. InsertMany, UpdateMany or BulkWrite
+	Multi bool
+	// Bulk is shared by the models of a BulkWrite, nil for the other operations
+	Bulk   *Bulk
 	Fields []*field.Filed
 
 	Doc any
 	// filter also can be used as query
-	Filter       any
-	Updates      any
+	Filter  any
+	Updates any
+	// Replacement is the document which replaces the matched one
+	Replacement  any
 	Pipeline     any
 	MongoOptions any
 	ModelHook    any
 	ReflectValue reflect.Value
 	StartTime    time.Time
+	// Session is the session carried by the context, nil if the operation is not bound to a session
+	Session *mongo.Session
+	// Unscoped disables the soft-delete scope of the operation
+	Unscoped bool
+	// Version is the version the document is expected to have, it is used when the model has a version field
+	Version *int64
+	// VersionChecked reports whether the filter has been narrowed to the expected version, or to the stored one
+	// when no version is expected, so that a write matching no document is a version conflict
+	VersionChecked bool
 
 	// result of the collection operation
 	Result any
+	// Err is the error of the collection operation, it is only set for the OpTypeOnError handlers
+	Err error
+	// Duration is the time spent in the collection operation, measured with the clock of the operator
+	Duration time.Duration
+
+	// Labels are the labels of the context of the operation, see WithLabels
+	Labels map[string]string
+	// Metadata is shared by the handlers of the operation, e.g. a span started before the operation and ended after it
+	Metadata map[string]any
+
+	// ctx replaces the context of the operation once the before handlers have run, see SetContext
+	ctx context.Context
+	// deferred are the functions run by Release
+	deferred []func()
 }
 
 type OpContextOption func(*OpContext)
@@ -69,6 +140,12 @@ func NewOpContext(col *mongo.Collection, opts ...OpContextOption) *OpContext {
 	opContext := &OpContext{
 		Col: col,
 	}
+	if col != nil {
+		opContext.CollectionName = col.Name()
+		if db := col.Database(); db != nil {
+			opContext.DatabaseName = db.Name()
+		}
+	}
 
 	for _, opt := range opts {
 		opt(opContext)
@@ -101,6 +178,12 @@ func WithUpdates(updates any) OpContextOption {
 	}
 }
 
+func WithReplacement(replacement any) OpContextOption {
+	return func(opContext *OpContext) {
+		opContext.Replacement = replacement
+	}
+}
+
 func WithPipeline(pipeline any) OpContextOption {
 	return func(opContext *OpContext) {
 		opContext.Pipeline = pipeline
@@ -131,8 +214,74 @@ func WithStartTime(startTime time.Time) OpContextOption {
 	}
 }
 
+func WithSession(session *mongo.Session) OpContextOption {
+	return func(opContext *OpContext) {
+		opContext.Session = session
+	}
+}
+
+func WithUnscoped(unscoped bool) OpContextOption {
+	return func(opContext *OpContext) {
+		opContext.Unscoped = unscoped
+	}
+}
+
+func WithVersion(version *int64) OpContextOption {
+	return func(opContext *OpContext) {
+		opContext.Version = version
+	}
+}
+
+func WithVersionChecked(versionChecked bool) OpContextOption {
+	return func(opContext *OpContext) {
+		opContext.VersionChecked = versionChecked
+	}
+}
+
 func WithResult(result any) OpContextOption {
 	return func(opContext *OpContext) {
 		opContext.Result = result
 	}
 }
+
+func WithErr(err error) OpContextOption {
+	return func(opContext *OpContext) {
+		opContext.Err = err
+	}
+}
+
+func WithDuration(duration time.Duration) OpContextOption {
+	return func(opContext *OpContext) {
+		opContext.Duration = duration
+	}
+}
+
+func WithOpName(opName string) OpContextOption {
+	return func(opContext *OpContext) {
+		opContext.OpName = opName
+	}
+}
+
+func WithMulti(multi bool) OpContextOption {
+	return func(opContext *OpContext) {
+		opContext.Multi = multi
+	}
+}
+
+func WithBulk(bulk *Bulk) OpContextOption {
+	return func(opContext *OpContext) {
+		opContext.Bulk = bulk
+	}
+}
+
+func WithLabels(labels map[string]string) OpContextOption {
+	return func(opContext *OpContext) {
+		opContext.Labels = labels
+	}
+}
+
+func WithMetadata(metadata map[string]any) OpContextOption {
+	return func(opContext *OpContext) {
+		opContext.Metadata = metadata
+	}
+}
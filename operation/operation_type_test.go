@@ -0,0 +1,36 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestNewOpContext(t *testing.T) {
+	client, err := mongo.Connect()
+	require.NoError(t, err)
+	opCtx := NewOpContext(client.Database("db-test").Collection("orders"), WithOpName(OpNameUpdateMany), WithMulti(true), WithLabels(map[string]string{"tenant": "acme"}))
+	require.Equal(t, "db-test", opCtx.DatabaseName)
+	require.Equal(t, "orders", opCtx.CollectionName)
+	require.Equal(t, OpNameUpdateMany, opCtx.OpName)
+	require.True(t, opCtx.Multi)
+	require.Equal(t, map[string]string{"tenant": "acme"}, opCtx.Labels)
+
+	require.Empty(t, NewOpContext(nil).CollectionName)
+	require.Empty(t, NewOpContext(&mongo.Collection{}).DatabaseName)
+}
@@ -0,0 +1,54 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongox
+
+import (
+	"fmt"
+
+	"github.com/chenmingyong0423/go-mongox/v2/callback"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+)
+
+// Plugin packages the handlers of a feature, e.g. tracing or auditing, so that they are registered together
+type Plugin interface {
+	// Name returns the name of the plugin, it is used in the errors of Use
+	Name() string
+	// Initialize registers the handlers of the plugin in the scope
+	Initialize(scope PluginScope) error
+}
+
+// PluginScope is where the handlers are registered, it is implemented by Client, Database and Collection.
+// The handlers of the client run first, then the ones of the database, then the ones of the collection,
+// and then the hooks registered on the operator.
+type PluginScope interface {
+	RegisterPlugin(name string, cb callback.CbFn, opType operation.OpType, opts ...callback.RegisterOption) error
+	RemovePlugin(name string, opType operation.OpType)
+	Plugins() map[operation.OpType][]callback.PluginInfo
+}
+
+var (
+	_ PluginScope = (*Client)(nil)
+	_ PluginScope = (*Database)(nil)
+	_ PluginScope = (*Collection[any])(nil)
+)
+
+func use(scope PluginScope, plugins []Plugin) error {
+	for _, plugin := range plugins {
+		if err := plugin.Initialize(scope); err != nil {
+			return fmt.Errorf("mongox: initialize plugin %s: %w", plugin.Name(), err)
+		}
+	}
+	return nil
+}
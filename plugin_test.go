@@ -0,0 +1,81 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongox
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/chenmingyong0423/go-mongox/v2/callback"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type recordPlugin struct {
+	name  string
+	names *[]string
+	err   error
+}
+
+func (p recordPlugin) Name() string {
+	return p.name
+}
+
+func (p recordPlugin) Initialize(scope PluginScope) error {
+	if p.err != nil {
+		return p.err
+	}
+	return scope.RegisterPlugin(p.name, func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+		*p.names = append(*p.names, p.name)
+		return nil
+	}, operation.OpTypeBeforeFind)
+}
+
+func TestPluginScopes(t *testing.T) {
+	var names []string
+	client := NewClient(&mongo.Client{}, &Config{})
+	db := client.NewDatabase("db-test")
+	orders := NewCollection[any](db, "orders")
+	users := NewCollection[any](db, "users")
+
+	// registered in the reverse order of the execution
+	require.NoError(t, orders.Use(recordPlugin{name: "collection", names: &names}))
+	require.NoError(t, db.Use(recordPlugin{name: "database", names: &names}))
+	require.NoError(t, client.Use(recordPlugin{name: "client", names: &names}))
+
+	require.NoError(t, orders.callbacks.Execute(context.Background(), operation.NewOpContext(nil), operation.OpTypeBeforeFind))
+	require.Equal(t, []string{"client", "database", "collection"}, names)
+
+	names = nil
+	require.NoError(t, users.callbacks.Execute(context.Background(), operation.NewOpContext(nil), operation.OpTypeBeforeFind))
+	require.Equal(t, []string{"client", "database"}, names)
+
+	require.Equal(t, []callback.PluginInfo{{Name: "collection"}}, orders.Plugins()[operation.OpTypeBeforeFind])
+	require.Equal(t, []callback.PluginInfo{{Name: callback.SoftDeletePlugin}, {Name: "database"}}, db.Plugins()[operation.OpTypeBeforeFind])
+
+	names = nil
+	orders.RemovePlugin("collection", operation.OpTypeBeforeFind)
+	client.RemovePlugin("client", operation.OpTypeBeforeFind)
+	require.NoError(t, orders.callbacks.Execute(context.Background(), operation.NewOpContext(nil), operation.OpTypeBeforeFind))
+	require.Equal(t, []string{"database"}, names)
+}
+
+func TestUse_Error(t *testing.T) {
+	client := NewClient(&mongo.Client{}, &Config{})
+	err := client.Use(recordPlugin{name: "broken", err: errors.New("invalid config")})
+	require.EqualError(t, err, "mongox: initialize plugin broken: invalid config")
+}
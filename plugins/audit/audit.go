@@ -0,0 +1,425 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit writes an audit entry for each write operation of go-mongox
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/chenmingyong0423/go-mongox/v2"
+	"github.com/chenmingyong0423/go-mongox/v2/callback"
+	hookfield "github.com/chenmingyong0423/go-mongox/v2/internal/hook/field"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	// PluginName is the name the handlers of the plugin are registered with
+	PluginName = "mongox:audit"
+
+	// DefaultCollection is the name of the collection of the audit entries suggested by the package
+	DefaultCollection = "audit_log"
+
+	preImageKey = "mongox:audit:preImage"
+	// the pre-image is read once the built-in handlers of the scope have rewritten the filter
+	preImagePriority = -1000
+	// the entry is written before the other after handlers, e.g. before the plaintexts of the encrypted fields are restored
+	recordPriority = 1000
+)
+
+// the actions of the audit entries
+const (
+	ActionInsert  = "insert"
+	ActionUpdate  = "update"
+	ActionUpsert  = "upsert"
+	ActionReplace = "replace"
+	ActionDelete  = "delete"
+)
+
+// AuditEntry is the document written for each insert, update, upsert, replace and delete,
+// the entries can be queried with a mongox.Collection[AuditEntry]
+type AuditEntry struct {
+	ID bson.ObjectID `bson:"_id,omitempty"`
+	// Actor is the one who ran the operation, see ContextWithActor and WithActorFunc
+	Actor      string `bson:"actor,omitempty"`
+	Database   string `bson:"database"`
+	Collection string `bson:"collection"`
+	// Action is one of the Action constants, Operation is the name of the method which ran the operation, e.g. UpdateOne
+	Action    string `bson:"action"`
+	Operation string `bson:"operation"`
+	// DocumentIDs are the _id of the inserted, upserted or changed documents, they are unknown for UpdateMany and DeleteMany
+	DocumentIDs []any `bson:"document_ids,omitempty"`
+	Filter      any   `bson:"filter,omitempty"`
+	// Update is the update or the replacement of the operation
+	Update any `bson:"update,omitempty"`
+	// Changes are the fields changed by the operations writing a single document, see WithDiff
+	Changes   []Change          `bson:"changes,omitempty"`
+	Labels    map[string]string `bson:"labels,omitempty"`
+	CreatedAt time.Time         `bson:"created_at"`
+}
+
+// Change is a field whose value differs before and after the operation, the embedded documents are compared field by field.
+// Before is nil if the field was added and After is nil if the field was removed.
+type Change struct {
+	// Field is the dotted path of the field, e.g. address.city
+	Field  string `bson:"field"`
+	Before any    `bson:"before,omitempty"`
+	After  any    `bson:"after,omitempty"`
+}
+
+type actorKey struct{}
+
+// ContextWithActor returns a copy of the context carrying the actor recorded in the audit entries
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor carried by the context, or an empty string
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+var _ mongox.Plugin = (*Plugin)(nil)
+
+// Plugin writes an AuditEntry once an insert, update, upsert, replace or delete succeeded.
+// The entries are written with the driver directly, so the handlers of the scopes do not run for them.
+//
+// For the operations writing a single document, the entry holds the changed fields. FindOneAndUpdate and the like
+// return the document before the operation atomically, the document after it is read by its _id.
+// For the other ones, and the ones returning the document after the operation, the document is read before
+// the operation with the filter and the sort as the handlers which ran before the plugin left it, and the filter
+// is pinned to its _id so that the operation writes the document recorded. Use the plugin on a database or
+// a collection rather than on a client, so that the filter is already scoped to the documents not soft-deleted
+// when the pre-image is read.
+//
+// The entries of the operations running in a transaction are written outside of the transaction,
+// unless WithSharedTransaction is used. The errors writing the entries outside of the transaction of the operation
+// are passed to the error handler rather than returned, the operation succeeded, see WithErrorHandler.
+type Plugin struct {
+	collection        *mongox.Collection[AuditEntry]
+	actor             func(ctx context.Context) string
+	diff              bool
+	sharedTransaction bool
+	errorHandler      func(ctx context.Context, entry *AuditEntry, err error)
+}
+
+type Option func(*Plugin)
+
+// WithActorFunc sets the function which returns the actor of the operation, ActorFromContext by default
+func WithActorFunc(fn func(ctx context.Context) string) Option {
+	return func(p *Plugin) {
+		p.actor = fn
+	}
+}
+
+// WithDiff sets whether the changed fields are recorded for the operations writing a single document, true by default.
+// Recording them costs a read before and after the operation.
+func WithDiff(diff bool) Option {
+	return func(p *Plugin) {
+		p.diff = diff
+	}
+}
+
+// WithSharedTransaction sets whether the entry of an operation running in a transaction is written in the transaction,
+// so that it is discarded if the transaction aborts. An error writing the entry then fails the operation.
+func WithSharedTransaction(shared bool) Option {
+	return func(p *Plugin) {
+		p.sharedTransaction = shared
+	}
+}
+
+// WithErrorHandler sets the function handling the errors recording the entry of an operation which succeeded,
+// the errors are logged with the log package by default
+func WithErrorHandler(fn func(ctx context.Context, entry *AuditEntry, err error)) Option {
+	return func(p *Plugin) {
+		p.errorHandler = fn
+	}
+}
+
+// NewPlugin returns a plugin writing the entries in the collection,
+// e.g. mongox.NewCollection[audit.AuditEntry](db, audit.DefaultCollection)
+func NewPlugin(collection *mongox.Collection[AuditEntry], opts ...Option) *Plugin {
+	p := &Plugin{
+		collection: collection,
+		actor:      ActorFromContext,
+		diff:       true,
+		errorHandler: func(_ context.Context, entry *AuditEntry, err error) {
+			log.Printf("%v, operation %s on %s.%s", err, entry.Operation, entry.Database, entry.Collection)
+		},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *Plugin) Name() string {
+	return PluginName
+}
+
+func (p *Plugin) Initialize(scope mongox.PluginScope) error {
+	if p.diff {
+		for _, opType := range []operation.OpType{operation.OpTypeBeforeUpdate, operation.OpTypeBeforeUpsert, operation.OpTypeBeforeReplace, operation.OpTypeBeforeDelete} {
+			if err := scope.RegisterPlugin(PluginName, p.readPreImage, opType, callback.Priority(preImagePriority)); err != nil {
+				return err
+			}
+		}
+	}
+	actions := map[operation.OpType]string{
+		operation.OpTypeAfterInsert:  ActionInsert,
+		operation.OpTypeAfterUpdate:  ActionUpdate,
+		operation.OpTypeAfterUpsert:  ActionUpsert,
+		operation.OpTypeAfterReplace: ActionReplace,
+		operation.OpTypeAfterDelete:  ActionDelete,
+	}
+	for _, opType := range []operation.OpType{operation.OpTypeAfterInsert, operation.OpTypeAfterUpdate, operation.OpTypeAfterUpsert, operation.OpTypeAfterReplace, operation.OpTypeAfterDelete} {
+		action := actions[opType]
+		err := scope.RegisterPlugin(PluginName, func(ctx context.Context, opCtx *operation.OpContext, _ ...any) error {
+			return p.record(ctx, opCtx, action)
+		}, opType, callback.Priority(recordPriority))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Plugin) readPreImage(ctx context.Context, opCtx *operation.OpContext, _ ...any) error {
+	if opCtx.Multi || opCtx.Col == nil {
+		return nil
+	}
+	findOneAnd := findOneAndOptionsOf(opCtx.MongoOptions)
+	if findOneAnd.returnsPreImage() {
+		// the operation returns the document it writes atomically
+		return nil
+	}
+	preImage, err := findOne(opCtx.Context(ctx), opCtx.Col, opCtx.Filter, options.FindOne().SetSort(findOneAnd.sort))
+	if err != nil {
+		return fmt.Errorf("mongox: audit: read the pre-image: %w", err)
+	}
+	if preImage == nil {
+		return nil
+	}
+	opCtx.Set(preImageKey, preImage)
+	// the document matching the filter may change until the operation runs, the operation writes the one recorded
+	opCtx.Filter = hookfield.MergeFilter(opCtx.Filter, bson.D{{Key: "_id", Value: preImage.Lookup("_id")}})
+	return nil
+}
+
+func (p *Plugin) record(ctx context.Context, opCtx *operation.OpContext, action string) error {
+	ctx = opCtx.Context(ctx)
+	entry := &AuditEntry{
+		ID:          bson.NewObjectID(),
+		Actor:       p.actor(ctx),
+		Database:    opCtx.DatabaseName,
+		Collection:  opCtx.CollectionName,
+		Action:      action,
+		Operation:   opCtx.OpName,
+		DocumentIDs: documentIDs(opCtx),
+		Filter:      opCtx.Filter,
+		Update:      opCtx.Updates,
+		Labels:      opCtx.Labels,
+		CreatedAt:   opCtx.StartTime,
+	}
+	if opCtx.Replacement != nil {
+		entry.Update = opCtx.Replacement
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	err := p.write(ctx, opCtx, action, entry)
+	if err != nil && !(p.sharedTransaction && opCtx.InTransaction()) {
+		// the write of the operation is not undone with the entry, the operation succeeded
+		p.errorHandler(ctx, entry, err)
+		return nil
+	}
+	return err
+}
+
+func (p *Plugin) write(ctx context.Context, opCtx *operation.OpContext, action string, entry *AuditEntry) error {
+	if p.diff && !opCtx.Multi {
+		preImage, postImage, err := images(ctx, opCtx, action)
+		if err != nil {
+			return fmt.Errorf("mongox: audit: read the post-image: %w", err)
+		}
+		if len(entry.DocumentIDs) == 0 {
+			entry.DocumentIDs = documentID(preImage, postImage)
+		}
+		entry.Changes = diff("", preImage, postImage)
+	}
+
+	if !p.sharedTransaction {
+		// detach the entry from the session, so that it is not written in the transaction of the operation
+		ctx = mongo.NewSessionContext(ctx, nil)
+	}
+	if _, err := p.collection.Collection().InsertOne(ctx, entry); err != nil {
+		return fmt.Errorf("mongox: audit: write the entry: %w", err)
+	}
+	return nil
+}
+
+// images returns the document before and after the operation, nil if it did not exist
+func images(ctx context.Context, opCtx *operation.OpContext, action string) (bson.Raw, bson.Raw, error) {
+	if action == ActionInsert {
+		postImage, err := bson.Marshal(opCtx.Doc)
+		return nil, postImage, err
+	}
+
+	preImage, _ := opCtx.Get(preImageKey)
+	before, _ := preImage.(bson.Raw)
+	findOneAnd := findOneAndOptionsOf(opCtx.MongoOptions)
+	returned := returnedImage(opCtx, findOneAnd)
+	if findOneAnd.returnsPreImage() {
+		before = returned
+	}
+	if action == ActionDelete && opCtx.Updates == nil {
+		// the document is gone unless it was soft-deleted
+		return before, nil, nil
+	}
+	if findOneAnd.returnsPostImage() && returned != nil {
+		return before, returned, nil
+	}
+	var filter any
+	switch {
+	case before != nil:
+		filter = bson.D{{Key: "_id", Value: before.Lookup("_id")}}
+	case action == ActionUpsert:
+		filter = opCtx.Filter
+		if r, ok := opCtx.Result.(*mongo.UpdateResult); ok && r != nil && r.UpsertedID != nil {
+			filter = bson.D{{Key: "_id", Value: r.UpsertedID}}
+		}
+	default:
+		// no document matched the filter
+		return nil, nil, nil
+	}
+	after, err := findOne(ctx, opCtx.Col, filter)
+	return before, after, err
+}
+
+// findOneAndOptions are the options of FindOneAndUpdate, FindOneAndReplace and FindOneAndDelete the images depend on
+type findOneAndOptions struct {
+	// ok is false for the other operations
+	ok         bool
+	sort       any
+	projection any
+	after      bool
+}
+
+// returnsPreImage reports whether the operation returns the whole document before it wrote it
+func (o findOneAndOptions) returnsPreImage() bool {
+	return o.ok && o.projection == nil && !o.after
+}
+
+// returnsPostImage reports whether the operation returns the whole document after it wrote it
+func (o findOneAndOptions) returnsPostImage() bool {
+	return o.ok && o.projection == nil && o.after
+}
+
+func findOneAndOptionsOf(mongoOptions any) findOneAndOptions {
+	switch listers := mongoOptions.(type) {
+	case []options.Lister[options.FindOneAndUpdateOptions]:
+		opts := applyOptions(listers)
+		return findOneAndOptions{ok: true, sort: opts.Sort, projection: opts.Projection, after: isAfter(opts.ReturnDocument)}
+	case []options.Lister[options.FindOneAndReplaceOptions]:
+		opts := applyOptions(listers)
+		return findOneAndOptions{ok: true, sort: opts.Sort, projection: opts.Projection, after: isAfter(opts.ReturnDocument)}
+	case []options.Lister[options.FindOneAndDeleteOptions]:
+		opts := applyOptions(listers)
+		return findOneAndOptions{ok: true, sort: opts.Sort, projection: opts.Projection}
+	}
+	return findOneAndOptions{}
+}
+
+func applyOptions[T any](listers []options.Lister[T]) *T {
+	opts := new(T)
+	for _, lister := range listers {
+		if lister == nil {
+			continue
+		}
+		for _, setter := range lister.List() {
+			if setter != nil {
+				_ = setter(opts)
+			}
+		}
+	}
+	return opts
+}
+
+func isAfter(returnDocument *options.ReturnDocument) bool {
+	return returnDocument != nil && *returnDocument == options.After
+}
+
+// returnedImage returns the document returned by FindOneAndUpdate and the like, nil for the other operations
+func returnedImage(opCtx *operation.OpContext, findOneAnd findOneAndOptions) bson.Raw {
+	if !findOneAnd.ok {
+		return nil
+	}
+	result, ok := opCtx.Result.(*mongo.SingleResult)
+	if !ok || result == nil {
+		return nil
+	}
+	raw, err := result.Raw()
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+func findOne(ctx context.Context, col *mongo.Collection, filter any, opts ...options.Lister[options.FindOneOptions]) (bson.Raw, error) {
+	if filter == nil {
+		filter = bson.D{}
+	}
+	doc, err := col.FindOne(ctx, filter, opts...).Raw()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	return doc, err
+}
+
+// documentIDs returns the _id of the inserted and upserted documents
+func documentIDs(opCtx *operation.OpContext) []any {
+	switch r := opCtx.Result.(type) {
+	case *mongo.InsertOneResult:
+		if r != nil {
+			return []any{r.InsertedID}
+		}
+	case *mongo.InsertManyResult:
+		if r != nil {
+			return r.InsertedIDs
+		}
+	case *mongo.UpdateResult:
+		if r != nil && r.UpsertedID != nil {
+			return []any{r.UpsertedID}
+		}
+	}
+	return nil
+}
+
+func documentID(images ...bson.Raw) []any {
+	for _, image := range images {
+		if id := lookup(image, "_id"); id.Type != 0 {
+			return []any{rawValue(id)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build e2e
+
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/chenmingyong0423/go-mongox/v2"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type User struct {
+	mongox.Model `bson:",inline"`
+	Name         string `bson:"name"`
+	Age          int    `bson:"age"`
+}
+
+func newClient(t *testing.T) *mongo.Client {
+	client, err := mongo.Connect(options.Client().ApplyURI("mongodb://localhost:27017").SetAuth(options.Credential{
+		Username:   "test",
+		Password:   "test",
+		AuthSource: "db-test",
+	}))
+	require.NoError(t, err)
+	return client
+}
+
+func TestPlugin_e2e(t *testing.T) {
+	db := mongox.NewClient(newClient(t), &mongox.Config{}).NewDatabase("db-test")
+	entries := mongox.NewCollection[AuditEntry](db, DefaultCollection)
+	require.NoError(t, db.Use(NewPlugin(entries)))
+	users := mongox.NewCollection[User](db, "test_user")
+	ctx := ContextWithActor(context.Background(), "admin")
+	defer func() {
+		_, err := users.Collection().DeleteMany(ctx, bson.D{})
+		require.NoError(t, err)
+		_, err = entries.Collection().DeleteMany(ctx, bson.D{})
+		require.NoError(t, err)
+	}()
+
+	user := &User{Name: "chenmingyong", Age: 24}
+	_, err := users.Creator().InsertOne(ctx, user)
+	require.NoError(t, err)
+	entry, err := entries.Finder().Filter(bson.M{"action": ActionInsert}).FindOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "admin", entry.Actor)
+	require.Equal(t, "db-test", entry.Database)
+	require.Equal(t, "test_user", entry.Collection)
+	require.Equal(t, "InsertOne", entry.Operation)
+	require.Equal(t, []any{user.ID}, entry.DocumentIDs)
+	require.Contains(t, entry.Changes, Change{Field: "name", After: "chenmingyong"})
+
+	_, err = users.Updater().Filter(bson.M{"name": "chenmingyong"}).Updates(bson.M{"$set": bson.M{"age": 25}}).UpdateOne(ctx)
+	require.NoError(t, err)
+	entry, err = entries.Finder().Filter(bson.M{"action": ActionUpdate}).FindOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "UpdateOne", entry.Operation)
+	require.Equal(t, []any{user.ID}, entry.DocumentIDs)
+	require.NotNil(t, entry.Filter)
+	require.NotNil(t, entry.Update)
+	require.Contains(t, entry.Changes, Change{Field: "age", Before: int32(24), After: int32(25)})
+
+	_, err = users.Deleter().Filter(bson.M{"name": "chenmingyong"}).DeleteOne(ctx)
+	require.NoError(t, err)
+	entry, err = entries.Finder().Filter(bson.M{"action": ActionDelete}).FindOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []any{user.ID}, entry.DocumentIDs)
+	require.Contains(t, entry.Changes, Change{Field: "name", Before: "chenmingyong"})
+
+	// the operations writing several documents are recorded without the changes
+	_, err = users.Creator().InsertMany(ctx, []*User{{Name: "burt"}, {Name: "Mingyong Chen"}})
+	require.NoError(t, err)
+	_, err = users.Updater().Filter(bson.M{}).Updates(bson.M{"$inc": bson.M{"age": 1}}).UpdateMany(ctx)
+	require.NoError(t, err)
+	entry, err = entries.Finder().Filter(bson.M{"operation": "UpdateMany"}).FindOne(ctx)
+	require.NoError(t, err)
+	require.Empty(t, entry.DocumentIDs)
+	require.Empty(t, entry.Changes)
+
+	count, err := entries.Finder().Filter(bson.M{"actor": "admin"}).Count(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), count)
+}
+
+func TestPlugin_e2e_FindOneAnd(t *testing.T) {
+	db := mongox.NewClient(newClient(t), &mongox.Config{}).NewDatabase("db-test")
+	entries := mongox.NewCollection[AuditEntry](db, DefaultCollection)
+	require.NoError(t, db.Use(NewPlugin(entries)))
+	users := mongox.NewCollection[User](db, "test_user")
+	ctx := context.Background()
+	defer func() {
+		_, err := users.Collection().DeleteMany(ctx, bson.D{})
+		require.NoError(t, err)
+		_, err = entries.Collection().DeleteMany(ctx, bson.D{})
+		require.NoError(t, err)
+	}()
+
+	_, err := users.Creator().InsertMany(ctx, []*User{{Name: "chenmingyong", Age: 24}, {Name: "burt", Age: 30}})
+	require.NoError(t, err)
+
+	// the entry records the document chosen by the sort
+	updated, err := users.Finder().Filter(bson.M{}).Updates(bson.M{"$inc": bson.M{"age": 1}}).FindOneAndUpdate(ctx, options.FindOneAndUpdate().SetSort(bson.M{"age": -1}))
+	require.NoError(t, err)
+	require.Equal(t, "burt", updated.Name)
+	entry, err := entries.Finder().Filter(bson.M{"operation": "FindOneAndUpdate"}).FindOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []any{updated.ID}, entry.DocumentIDs)
+	require.Contains(t, entry.Changes, Change{Field: "age", Before: int32(30), After: int32(31)})
+
+	// the pre-image read for the document returned after the replacement is the one replaced
+	replaced, err := users.Finder().Filter(bson.M{}).Replacement(&User{Name: "cmy", Age: 18}).ReturnDocument(options.After).
+		FindOneAndReplace(ctx, options.FindOneAndReplace().SetSort(bson.M{"age": 1}))
+	require.NoError(t, err)
+	entry, err = entries.Finder().Filter(bson.M{"operation": "FindOneAndReplace"}).FindOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []any{replaced.ID}, entry.DocumentIDs)
+	require.Contains(t, entry.Changes, Change{Field: "name", Before: "chenmingyong", After: "cmy"})
+}
+
+func TestPlugin_e2e_SharedTransaction(t *testing.T) {
+	c := newClient(t)
+	var hello bson.M
+	require.NoError(t, c.Database("admin").RunCommand(context.Background(), bson.D{{Key: "hello", Value: 1}}).Decode(&hello))
+	if hello["setName"] == nil && hello["msg"] != "isdbgrid" {
+		t.Skip("transactions are not supported by a standalone server")
+	}
+
+	client := mongox.NewClient(c, &mongox.Config{})
+	wantErr := errors.New("abort")
+	for _, shared := range []bool{true, false} {
+		db := client.NewDatabase("db-test")
+		entries := mongox.NewCollection[AuditEntry](db, DefaultCollection)
+		require.NoError(t, db.Use(NewPlugin(entries, WithSharedTransaction(shared))))
+		users := mongox.NewCollection[User](db, "test_user")
+		ctx := context.Background()
+
+		err := client.WithTransaction(ctx, func(ctx context.Context) error {
+			if _, err := users.Creator().InsertOne(ctx, &User{Name: "chenmingyong"}); err != nil {
+				return err
+			}
+			return wantErr
+		})
+		require.Equal(t, wantErr, err)
+
+		// the entry is discarded with the transaction only if it shares it
+		count, err := entries.Finder().Count(ctx)
+		require.NoError(t, err)
+		if shared {
+			require.Equal(t, int64(0), count)
+		} else {
+			require.Equal(t, int64(1), count)
+		}
+		_, err = entries.Collection().DeleteMany(ctx, bson.D{})
+		require.NoError(t, err)
+	}
+}
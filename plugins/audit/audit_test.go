@@ -0,0 +1,267 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chenmingyong0423/go-mongox/v2"
+	"github.com/chenmingyong0423/go-mongox/v2/callback"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// scope is a PluginScope backed by a bare registry
+type scope struct {
+	*callback.Callback
+}
+
+func (s scope) RegisterPlugin(name string, cb callback.CbFn, opType operation.OpType, opts ...callback.RegisterOption) error {
+	return s.Register(opType, name, cb, opts...)
+}
+
+func (s scope) RemovePlugin(name string, opType operation.OpType) {
+	s.Remove(opType, name)
+}
+
+var _ mongox.PluginScope = scope{}
+
+func TestPlugin_Initialize(t *testing.T) {
+	s := scope{callback.NewScope(nil)}
+	require.NoError(t, NewPlugin(nil).Initialize(s))
+	plugins := s.Plugins()
+	for _, opType := range []operation.OpType{
+		operation.OpTypeBeforeUpdate, operation.OpTypeBeforeUpsert, operation.OpTypeBeforeReplace, operation.OpTypeBeforeDelete,
+		operation.OpTypeAfterInsert, operation.OpTypeAfterUpdate, operation.OpTypeAfterUpsert, operation.OpTypeAfterReplace, operation.OpTypeAfterDelete,
+	} {
+		require.Len(t, plugins[opType], 1, opType)
+		require.Equal(t, PluginName, plugins[opType][0].Name)
+	}
+	require.Empty(t, plugins[operation.OpTypeBeforeInsert])
+	require.Empty(t, plugins[operation.OpTypeAfterFind])
+	require.Empty(t, plugins[operation.OpTypeOnError])
+
+	// the pre-images are not read without the diff
+	s = scope{callback.NewScope(nil)}
+	require.NoError(t, NewPlugin(nil, WithDiff(false)).Initialize(s))
+	plugins = s.Plugins()
+	require.Empty(t, plugins[operation.OpTypeBeforeUpdate])
+	require.Len(t, plugins[operation.OpTypeAfterUpdate], 1)
+}
+
+func TestActor(t *testing.T) {
+	ctx := context.Background()
+	require.Equal(t, "", ActorFromContext(ctx))
+	require.Equal(t, "cmy", ActorFromContext(ContextWithActor(ctx, "cmy")))
+}
+
+func TestDiff(t *testing.T) {
+	marshal := func(doc any) bson.Raw {
+		raw, err := bson.Marshal(doc)
+		require.NoError(t, err)
+		return raw
+	}
+
+	testCases := []struct {
+		name   string
+		before bson.Raw
+		after  bson.Raw
+		want   []Change
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name:  "inserted",
+			after: marshal(bson.D{{Key: "name", Value: "cmy"}, {Key: "age", Value: int32(18)}}),
+			want:  []Change{{Field: "name", After: "cmy"}, {Field: "age", After: int32(18)}},
+		},
+		{
+			name:   "deleted",
+			before: marshal(bson.D{{Key: "name", Value: "cmy"}}),
+			want:   []Change{{Field: "name", Before: "cmy"}},
+		},
+		{
+			name:   "unchanged",
+			before: marshal(bson.D{{Key: "name", Value: "cmy"}}),
+			after:  marshal(bson.D{{Key: "name", Value: "cmy"}}),
+		},
+		{
+			name:   "changed",
+			before: marshal(bson.D{{Key: "name", Value: "cmy"}, {Key: "age", Value: int32(18)}, {Key: "phone", Value: "138"}}),
+			after:  marshal(bson.D{{Key: "name", Value: "cmy"}, {Key: "age", Value: int64(19)}, {Key: "email", Value: "cmy@example.com"}}),
+			want: []Change{
+				{Field: "age", Before: int32(18), After: int64(19)},
+				{Field: "phone", Before: "138"},
+				{Field: "email", After: "cmy@example.com"},
+			},
+		},
+		{
+			name:   "embedded",
+			before: marshal(bson.D{{Key: "address", Value: bson.D{{Key: "city", Value: "Shenzhen"}, {Key: "zip", Value: "518000"}}}, {Key: "tags", Value: bson.A{"a"}}}),
+			after:  marshal(bson.D{{Key: "address", Value: bson.D{{Key: "city", Value: "Guangzhou"}, {Key: "zip", Value: "518000"}}}, {Key: "tags", Value: bson.A{"a", "b"}}}),
+			want: []Change{
+				{Field: "address.city", Before: "Shenzhen", After: "Guangzhou"},
+				{Field: "tags", Before: bson.A{"a"}, After: bson.A{"a", "b"}},
+			},
+		},
+		{
+			name:   "embedded replaced",
+			before: marshal(bson.D{{Key: "address", Value: "Shenzhen"}}),
+			after:  marshal(bson.D{{Key: "address", Value: bson.D{{Key: "city", Value: "Shenzhen"}}}}),
+			want:   []Change{{Field: "address", Before: "Shenzhen", After: bson.D{{Key: "city", Value: "Shenzhen"}}}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, diff("", tc.before, tc.after))
+		})
+	}
+}
+
+func TestImages(t *testing.T) {
+	ctx := context.Background()
+	id := bson.NewObjectID()
+
+	type user struct {
+		ID   bson.ObjectID `bson:"_id"`
+		Name string        `bson:"name"`
+	}
+	before, after, err := images(ctx, operation.NewOpContext(nil, operation.WithDoc(&user{ID: id, Name: "cmy"})), ActionInsert)
+	require.NoError(t, err)
+	require.Nil(t, before)
+	require.Equal(t, []Change{{Field: "_id", After: id}, {Field: "name", After: "cmy"}}, diff("", before, after))
+	require.Equal(t, []any{id}, documentID(before, after))
+
+	preImage, err := bson.Marshal(user{ID: id, Name: "cmy"})
+	require.NoError(t, err)
+	opCtx := operation.NewOpContext(nil)
+	opCtx.Set(preImageKey, bson.Raw(preImage))
+	before, after, err = images(ctx, opCtx, ActionDelete)
+	require.NoError(t, err)
+	require.Equal(t, bson.Raw(preImage), before)
+	require.Nil(t, after)
+
+	// nothing matched the filter
+	before, after, err = images(ctx, operation.NewOpContext(nil), ActionUpdate)
+	require.NoError(t, err)
+	require.Nil(t, before)
+	require.Nil(t, after)
+	require.Nil(t, documentID(before, after))
+}
+
+func TestImages_findOneAnd(t *testing.T) {
+	ctx := context.Background()
+	type user struct {
+		ID   string `bson:"_id"`
+		Name string `bson:"name"`
+	}
+	returned, err := bson.Marshal(user{ID: "1", Name: "cmy"})
+	require.NoError(t, err)
+	preImage, err := bson.Marshal(user{ID: "1", Name: "chenmingyong"})
+	require.NoError(t, err)
+	newOpContext := func(opts any) *operation.OpContext {
+		opCtx := operation.NewOpContext(nil, operation.WithMongoOptions(opts), operation.WithResult(mongo.NewSingleResultFromDocument(user{ID: "1", Name: "cmy"}, nil, nil)))
+		opCtx.Set(preImageKey, bson.Raw(preImage))
+		return opCtx
+	}
+
+	// the document returned after the replacement is the post-image
+	before, after, err := images(ctx, newOpContext([]options.Lister[options.FindOneAndReplaceOptions]{options.FindOneAndReplace().SetReturnDocument(options.After)}), ActionReplace)
+	require.NoError(t, err)
+	require.Equal(t, bson.Raw(preImage), before)
+	require.Equal(t, bson.Raw(returned), after)
+
+	// the document returned by the deletion is the pre-image
+	before, after, err = images(ctx, newOpContext([]options.Lister[options.FindOneAndDeleteOptions]{options.FindOneAndDelete().SetSort(bson.M{"name": 1})}), ActionDelete)
+	require.NoError(t, err)
+	require.Equal(t, bson.Raw(returned), before)
+	require.Nil(t, after)
+}
+
+func TestFindOneAndOptionsOf(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts any
+		want findOneAndOptions
+	}{
+		{
+			name: "update one",
+			opts: []options.Lister[options.UpdateOneOptions]{options.UpdateOne().SetUpsert(true)},
+		},
+		{
+			name: "find one and update",
+			opts: []options.Lister[options.FindOneAndUpdateOptions]{options.FindOneAndUpdate().SetSort(bson.M{"age": -1})},
+			want: findOneAndOptions{ok: true, sort: bson.M{"age": -1}},
+		},
+		{
+			name: "find one and replace returning the document after",
+			opts: []options.Lister[options.FindOneAndReplaceOptions]{options.FindOneAndReplace().SetReturnDocument(options.After)},
+			want: findOneAndOptions{ok: true, after: true},
+		},
+		{
+			name: "find one and delete with a projection",
+			opts: []options.Lister[options.FindOneAndDeleteOptions]{options.FindOneAndDelete().SetProjection(bson.M{"name": 1})},
+			want: findOneAndOptions{ok: true, projection: bson.M{"name": 1}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, findOneAndOptionsOf(tc.opts))
+		})
+	}
+}
+
+func TestPlugin_recordError(t *testing.T) {
+	client, err := mongo.Connect()
+	require.NoError(t, err)
+	entries := mongox.NewCollection[AuditEntry](mongox.NewClient(client, &mongox.Config{}).NewDatabase("db-test"), DefaultCollection)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, shared := range []bool{false, true} {
+		var handled error
+		p := NewPlugin(entries, WithDiff(false), WithSharedTransaction(shared), WithErrorHandler(func(ctx context.Context, entry *AuditEntry, err error) {
+			handled = err
+		}))
+		// the operation is not in a transaction, the error writing its entry is not returned
+		require.NoError(t, p.record(ctx, operation.NewOpContext(nil, operation.WithOpName(operation.OpNameUpdateOne)), ActionUpdate))
+		require.ErrorIs(t, handled, context.Canceled)
+	}
+}
+
+func TestDocumentIDs(t *testing.T) {
+	testCases := []struct {
+		name   string
+		result any
+		want   []any
+	}{
+		{name: "insert one", result: &mongo.InsertOneResult{InsertedID: "1"}, want: []any{"1"}},
+		{name: "insert many", result: &mongo.InsertManyResult{InsertedIDs: []any{"1", "2"}}, want: []any{"1", "2"}},
+		{name: "upserted", result: &mongo.UpdateResult{UpsertedID: "1"}, want: []any{"1"}},
+		{name: "updated", result: &mongo.UpdateResult{MatchedCount: 1}},
+		{name: "deleted", result: &mongo.DeleteResult{DeletedCount: 1}},
+		{name: "nil", result: (*mongo.InsertOneResult)(nil)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, documentIDs(operation.NewOpContext(nil, operation.WithResult(tc.result))))
+		})
+	}
+}
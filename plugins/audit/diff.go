@@ -0,0 +1,74 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// diff returns the changes between the documents in the order of their fields, the fields of before first.
+// The embedded documents present on both sides are compared field by field.
+func diff(prefix string, before, after bson.Raw) []Change {
+	var changes []Change
+	seen := make(map[string]bool)
+	elements, _ := before.Elements()
+	for _, e := range elements {
+		key := e.Key()
+		seen[key] = true
+		changes = append(changes, diffValue(prefix+key, e.Value(), lookup(after, key))...)
+	}
+	elements, _ = after.Elements()
+	for _, e := range elements {
+		if key := e.Key(); !seen[key] {
+			changes = append(changes, diffValue(prefix+key, bson.RawValue{}, e.Value())...)
+		}
+	}
+	return changes
+}
+
+func diffValue(path string, before, after bson.RawValue) []Change {
+	if before.Type == bson.TypeEmbeddedDocument && after.Type == bson.TypeEmbeddedDocument {
+		return diff(path+".", before.Document(), after.Document())
+	}
+	if before.Type == after.Type && bytes.Equal(before.Value, after.Value) {
+		return nil
+	}
+	return []Change{{Field: path, Before: rawValue(before), After: rawValue(after)}}
+}
+
+func lookup(doc bson.Raw, key string) bson.RawValue {
+	if doc == nil {
+		return bson.RawValue{}
+	}
+	value, err := doc.LookupErr(key)
+	if err != nil {
+		return bson.RawValue{}
+	}
+	return value
+}
+
+// rawValue decodes the value, nil if it is missing
+func rawValue(value bson.RawValue) any {
+	if value.Type == 0 {
+		return nil
+	}
+	var v any
+	if err := value.Unmarshal(&v); err != nil {
+		return value
+	}
+	return v
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+
+package logger
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Redacted replaces the values of the sensitive fields
+const Redacted = "[REDACTED]"
+
+// extJSON returns the value as relaxed Extended JSON, the values of the sensitive fields are redacted,
+// including the ones in the operators such as $set or $and and in the stages of a pipeline
+func extJSON(value any, sensitive map[string]bool) string {
+	v := normalize(value)
+	if len(sensitive) > 0 {
+		v = redact(v, sensitive)
+	}
+	if d, ok := v.(bson.D); ok {
+		data, err := bson.MarshalExtJSON(d, false, false)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(data)
+	}
+	// only a document can be marshaled at the top level
+	data, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: v}}, false, false)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(string(data), `{"v":`), "}")
+}
+
+// normalize turns the documents into bson.D and the arrays, e.g. the pipelines, into bson.A
+func normalize(value any) any {
+	if data, err := bson.Marshal(value); err == nil {
+		var d bson.D
+		if err = bson.Unmarshal(data, &d); err == nil {
+			return d
+		}
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		a := make(bson.A, v.Len())
+		for i := range a {
+			a[i] = normalize(v.Index(i).Interface())
+		}
+		return a
+	}
+	return value
+}
+
+func redact(value any, sensitive map[string]bool) any {
+	switch v := value.(type) {
+	case bson.D:
+		for i, e := range v {
+			if isSensitive(e.Key, sensitive) {
+				v[i].Value = Redacted
+				continue
+			}
+			v[i].Value = redact(e.Value, sensitive)
+		}
+	case bson.A:
+		for i := range v {
+			v[i] = redact(v[i], sensitive)
+		}
+	}
+	return value
+}
+
+// isSensitive reports whether the key is a sensitive field or a path through one, e.g. phones.0
+func isSensitive(key string, sensitive map[string]bool) bool {
+	for _, segment := range strings.Split(key, ".") {
+		if sensitive[segment] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+
+package logger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestExtJSON(t *testing.T) {
+	sensitive := map[string]bool{"phone": true, "id_card": true}
+
+	testCases := []struct {
+		name      string
+		value     any
+		sensitive map[string]bool
+		want      string
+	}{
+		{
+			name:  "filter",
+			value: bson.D{{Key: "name", Value: "cmy"}, {Key: "age", Value: bson.D{{Key: "$gt", Value: 18}}}},
+			want:  `{"name":"cmy","age":{"$gt":18}}`,
+		},
+		{
+			name:  "relaxed",
+			value: bson.D{{Key: "_id", Value: bson.ObjectID{1}}, {Key: "count", Value: int64(3)}},
+			want:  `{"_id":{"$oid":"010000000000000000000000"},"count":3}`,
+		},
+		{
+			name:      "redacted filter",
+			value:     bson.D{{Key: "name", Value: "cmy"}, {Key: "phone", Value: bson.D{{Key: "$in", Value: bson.A{"123", "456"}}}}},
+			sensitive: sensitive,
+			want:      `{"name":"cmy","phone":"[REDACTED]"}`,
+		},
+		{
+			name:      "redacted operators",
+			value:     bson.M{"$or": bson.A{bson.M{"id_card": "110"}, bson.M{"phone.0": "123"}}},
+			sensitive: sensitive,
+			want:      `{"$or":[{"id_card":"[REDACTED]"},{"phone.0":"[REDACTED]"}]}`,
+		},
+		{
+			name:      "redacted update",
+			value:     bson.D{{Key: "$set", Value: bson.D{{Key: "name", Value: "cmy"}, {Key: "phone", Value: "123"}}}},
+			sensitive: sensitive,
+			want:      `{"$set":{"name":"cmy","phone":"[REDACTED]"}}`,
+		},
+		{
+			name:      "redacted pipeline",
+			value:     mongo.Pipeline{{{Key: "$match", Value: bson.D{{Key: "phone", Value: "123"}}}}, {{Key: "$limit", Value: 1}}},
+			sensitive: sensitive,
+			want:      `[{"$match":{"phone":"[REDACTED]"}},{"$limit":1}]`,
+		},
+		{
+			name:  "not a document",
+			value: "name",
+			want:  `"name"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, extJSON(tc.value, tc.sensitive))
+		})
+	}
+}
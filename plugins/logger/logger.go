@@ -0,0 +1,245 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+
+// Package logger writes a log/slog record for each operation of go-mongox
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"reflect"
+	"time"
+
+	"github.com/chenmingyong0423/go-mongox/v2"
+	"github.com/chenmingyong0423/go-mongox/v2/callback"
+	"github.com/chenmingyong0423/go-mongox/v2/field"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const (
+	// PluginName is the name the handlers of the plugin are registered with
+	PluginName = "mongox:logger"
+
+	// DefaultSlowThreshold is the duration above which an operation is slow if WithSlowThreshold is not used
+	DefaultSlowThreshold = 200 * time.Millisecond
+
+	// Message is the message of the records
+	Message = "mongox operation"
+
+	loggedKey = "mongox:logger:logged"
+	// the record is written before the other after handlers, so that an error of them does not drop it
+	logPriority = 1000
+)
+
+var _ mongox.Plugin = (*Plugin)(nil)
+
+// Plugin writes one record for each operation with the operation, the collection, the filter, the update
+// and the pipeline as relaxed Extended JSON, the duration and the result counts.
+// The values of the fields tagged with mongox:"sensitive" are replaced by Redacted.
+//
+// The records of the successful operations are written at the level of WithLevel, the ones of the slow operations
+// at the level of WithSlowLevel and the ones of the failed operations at the error level.
+// Only the successful operations which are not slow are sampled.
+// Cursor is logged once its query returns, before any document is decoded, and FindOneAndUpdate and the like are logged once.
+type Plugin struct {
+	logger        *slog.Logger
+	level         slog.Level
+	slowThreshold time.Duration
+	slowLevel     slog.Level
+	sampleRate    float64
+}
+
+type Option func(*Plugin)
+
+// WithLogger sets the logger, slog.Default() is used by default
+func WithLogger(logger *slog.Logger) Option {
+	return func(p *Plugin) {
+		p.logger = logger
+	}
+}
+
+// WithLevel sets the level of the successful operations, slog.LevelDebug by default
+func WithLevel(level slog.Level) Option {
+	return func(p *Plugin) {
+		p.level = level
+	}
+}
+
+// WithSlowThreshold sets the duration above which an operation is slow, 0 disables the slow operations
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(p *Plugin) {
+		p.slowThreshold = threshold
+	}
+}
+
+// WithSlowLevel sets the level of the slow operations, slog.LevelWarn by default
+func WithSlowLevel(level slog.Level) Option {
+	return func(p *Plugin) {
+		p.slowLevel = level
+	}
+}
+
+// WithSampleRate sets the fraction of the successful operations which are logged, from 0 to 1, 1 by default
+func WithSampleRate(rate float64) Option {
+	return func(p *Plugin) {
+		p.sampleRate = rate
+	}
+}
+
+func NewPlugin(opts ...Option) *Plugin {
+	p := &Plugin{
+		logger:        slog.Default(),
+		level:         slog.LevelDebug,
+		slowThreshold: DefaultSlowThreshold,
+		slowLevel:     slog.LevelWarn,
+		sampleRate:    1,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *Plugin) Name() string {
+	return PluginName
+}
+
+func (p *Plugin) Initialize(scope mongox.PluginScope) error {
+	if err := scope.RegisterPlugin(PluginName, p.log, operation.OpTypeAfterAny, callback.Priority(logPriority)); err != nil {
+		return err
+	}
+	return scope.RegisterPlugin(PluginName, p.log, operation.OpTypeOnError, callback.Priority(logPriority))
+}
+
+func (p *Plugin) log(ctx context.Context, opCtx *operation.OpContext, _ ...any) error {
+	if _, ok := opCtx.Get(loggedKey); ok {
+		return nil
+	}
+	opCtx.Set(loggedKey, true)
+
+	// the duration is 0 if the operation failed before the driver was called
+	duration := opCtx.Duration
+	slow := p.slowThreshold > 0 && duration > p.slowThreshold
+	level := p.level
+	switch {
+	case opCtx.Err != nil:
+		level = slog.LevelError
+	case slow:
+		level = p.slowLevel
+	case p.sampleRate < 1 && rand.Float64() >= p.sampleRate:
+		return nil
+	}
+	if !p.logger.Enabled(ctx, level) {
+		return nil
+	}
+
+	attrs := []slog.Attr{
+		slog.String("database", opCtx.DatabaseName),
+		slog.String("collection", opCtx.CollectionName),
+		slog.String("operation", opCtx.OpName),
+		slog.Duration("duration", duration),
+	}
+	sensitive := sensitiveFields(opCtx.Fields)
+	if opCtx.Filter != nil {
+		attrs = append(attrs, slog.String("filter", extJSON(opCtx.Filter, sensitive)))
+	}
+	if opCtx.Updates != nil {
+		attrs = append(attrs, slog.String("update", extJSON(opCtx.Updates, sensitive)))
+	}
+	if opCtx.Pipeline != nil {
+		attrs = append(attrs, slog.String("pipeline", extJSON(opCtx.Pipeline, sensitive)))
+	}
+	if slow {
+		attrs = append(attrs, slog.Bool("slow", true))
+	}
+	if opCtx.Err != nil {
+		attrs = append(attrs, slog.Any("error", opCtx.Err))
+	} else {
+		attrs = append(attrs, resultAttrs(opCtx)...)
+	}
+	if len(opCtx.Labels) > 0 {
+		labels := make([]any, 0, len(opCtx.Labels))
+		for key, value := range opCtx.Labels {
+			labels = append(labels, slog.String(key, value))
+		}
+		attrs = append(attrs, slog.Group("labels", labels...))
+	}
+	p.logger.LogAttrs(ctx, level, Message, attrs...)
+	return nil
+}
+
+func sensitiveFields(fields []*field.Filed) map[string]bool {
+	sensitive := field.SensitiveFields(fields)
+	if len(sensitive) == 0 {
+		return nil
+	}
+	names := make(map[string]bool, len(sensitive))
+	for _, fd := range sensitive {
+		names[fd.MongoField] = true
+	}
+	return names
+}
+
+func resultAttrs(opCtx *operation.OpContext) []slog.Attr {
+	switch r := opCtx.Result.(type) {
+	case *mongo.InsertOneResult:
+		return []slog.Attr{slog.Int64("inserted", 1)}
+	case *mongo.InsertManyResult:
+		if r != nil {
+			return []slog.Attr{slog.Int("inserted", len(r.InsertedIDs))}
+		}
+		return nil
+	case *mongo.UpdateResult:
+		if r != nil {
+			return []slog.Attr{slog.Int64("matched", r.MatchedCount), slog.Int64("modified", r.ModifiedCount), slog.Int64("upserted", r.UpsertedCount)}
+		}
+		return nil
+	case *mongo.DeleteResult:
+		if r != nil {
+			return []slog.Attr{slog.Int64("deleted", r.DeletedCount)}
+		}
+		return nil
+	case *mongo.BulkWriteResult:
+		if r != nil {
+			return []slog.Attr{
+				slog.Int64("inserted", r.InsertedCount), slog.Int64("matched", r.MatchedCount), slog.Int64("modified", r.ModifiedCount),
+				slog.Int64("upserted", r.UpsertedCount), slog.Int64("deleted", r.DeletedCount),
+			}
+		}
+		return nil
+	case int64:
+		// Count and EstimatedCount
+		return []slog.Attr{slog.Int64("count", r)}
+	}
+
+	v := reflect.ValueOf(opCtx.Doc)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		if v.Elem().Kind() != reflect.Slice {
+			// a single decoded document, e.g. the one of FindOne
+			return []slog.Attr{slog.Int("returned", 1)}
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice {
+		return []slog.Attr{slog.Int("returned", v.Len())}
+	}
+	return nil
+}
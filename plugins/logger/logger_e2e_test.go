@@ -0,0 +1,75 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build e2e && go1.21
+
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/chenmingyong0423/go-mongox/v2"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func TestPlugin_e2e(t *testing.T) {
+	type User struct {
+		mongox.Model `bson:",inline"`
+		Name         string `bson:"name"`
+		Phone        string `bson:"phone" mongox:"sensitive"`
+	}
+	client, err := mongo.Connect(options.Client().ApplyURI("mongodb://localhost:27017").SetAuth(options.Credential{
+		Username:   "test",
+		Password:   "test",
+		AuthSource: "db-test",
+	}))
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	mongoxClient := mongox.NewClient(client, &mongox.Config{})
+	require.NoError(t, mongoxClient.Use(NewPlugin(WithLogger(slog.New(slog.NewTextHandler(buf, nil))), WithLevel(slog.LevelInfo))))
+	users := mongox.NewCollection[User](mongoxClient.NewDatabase("db-test"), "test_user")
+	ctx := context.Background()
+	defer func() {
+		_, err := users.Collection().DeleteMany(ctx, bson.D{})
+		require.NoError(t, err)
+	}()
+
+	_, err = users.Creator().InsertOne(ctx, &User{Name: "chenmingyong", Phone: "13800000000"})
+	require.NoError(t, err)
+	user, err := users.Finder().Filter(bson.M{"phone": "13800000000"}).FindOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "chenmingyong", user.Name)
+
+	require.Contains(t, buf.String(), "operation=InsertOne")
+	require.Contains(t, buf.String(), "operation=FindOne")
+	require.Contains(t, buf.String(), `filter="{\"phone\":\"[REDACTED]\"`)
+	require.NotContains(t, buf.String(), "13800000000")
+
+	// a cursor is logged once its query returns, even if no document is decoded
+	buf.Reset()
+	c, err := users.Finder().Filter(bson.M{"name": "burt"}).Cursor(ctx)
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), "operation=Find ")
+	require.False(t, c.Next(ctx))
+	require.NoError(t, c.Close(ctx))
+	require.Equal(t, 1, strings.Count(buf.String(), Message))
+}
@@ -0,0 +1,189 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/chenmingyong0423/go-mongox/v2"
+	"github.com/chenmingyong0423/go-mongox/v2/callback"
+	"github.com/chenmingyong0423/go-mongox/v2/field"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// scope is a PluginScope backed by a bare registry
+type scope struct {
+	*callback.Callback
+}
+
+func (s scope) RegisterPlugin(name string, cb callback.CbFn, opType operation.OpType, opts ...callback.RegisterOption) error {
+	return s.Register(opType, name, cb, opts...)
+}
+
+func (s scope) RemovePlugin(name string, opType operation.OpType) {
+	s.Remove(opType, name)
+}
+
+var _ mongox.PluginScope = scope{}
+
+type user struct {
+	Name  string `bson:"name"`
+	Phone string `bson:"phone" mongox:"sensitive"`
+}
+
+func newOpContext(opts ...operation.OpContextOption) *operation.OpContext {
+	client, _ := mongo.Connect()
+	opts = append([]operation.OpContextOption{operation.WithFields(field.ParseFields(user{}))}, opts...)
+	return operation.NewOpContext(client.Database("db-test").Collection("users"), opts...)
+}
+
+func TestPlugin(t *testing.T) {
+	testCases := []struct {
+		name   string
+		opts   []Option
+		opCtx  *operation.OpContext
+		opType []operation.OpType
+		err    error
+
+		want []map[string]any
+	}{
+		{
+			name: "find",
+			opCtx: newOpContext(operation.WithOpName(operation.OpNameFind), operation.WithDuration(time.Millisecond),
+				operation.WithFilter(bson.M{"phone": "123"}), operation.WithDoc([]*user{{Name: "cmy"}}), operation.WithLabels(map[string]string{"tenant": "acme"})),
+			opType: []operation.OpType{operation.OpTypeAfterFind},
+			want: []map[string]any{{
+				"level": "DEBUG", "msg": Message, "database": "db-test", "collection": "users", "operation": "Find", "duration": float64(time.Millisecond),
+				"filter": `{"phone":"[REDACTED]"}`, "returned": float64(1), "labels": map[string]any{"tenant": "acme"},
+			}},
+		},
+		{
+			name: "update",
+			opCtx: newOpContext(operation.WithOpName(operation.OpNameUpdateOne), operation.WithDuration(time.Millisecond),
+				operation.WithFilter(bson.D{{Key: "name", Value: "cmy"}}), operation.WithUpdates(bson.M{"$set": bson.M{"phone": "123"}}),
+				operation.WithResult(&mongo.UpdateResult{MatchedCount: 1, ModifiedCount: 1})),
+			opType: []operation.OpType{operation.OpTypeAfterUpdate},
+			want: []map[string]any{{
+				"level": "DEBUG", "msg": Message, "database": "db-test", "collection": "users", "operation": "UpdateOne", "duration": float64(time.Millisecond),
+				"filter": `{"name":"cmy"}`, "update": `{"$set":{"phone":"[REDACTED]"}}`, "matched": float64(1), "modified": float64(1), "upserted": float64(0),
+			}},
+		},
+		{
+			name:   "find one and update is logged once",
+			opCtx:  newOpContext(operation.WithOpName(operation.OpNameFindOneAndUpdate), operation.WithDuration(time.Millisecond), operation.WithDoc(&user{})),
+			opType: []operation.OpType{operation.OpTypeAfterFind, operation.OpTypeAfterUpdate},
+			want: []map[string]any{{
+				"level": "DEBUG", "msg": Message, "database": "db-test", "collection": "users", "operation": "FindOneAndUpdate", "duration": float64(time.Millisecond),
+				"returned": float64(1),
+			}},
+		},
+		{
+			name:   "slow",
+			opts:   []Option{WithSlowThreshold(time.Second), WithSampleRate(0)},
+			opCtx:  newOpContext(operation.WithOpName(operation.OpNameCount), operation.WithDuration(2*time.Second), operation.WithResult(int64(3))),
+			opType: []operation.OpType{operation.OpTypeAfterCount},
+			want: []map[string]any{{
+				"level": "WARN", "msg": Message, "database": "db-test", "collection": "users", "operation": "Count", "duration": float64(2 * time.Second),
+				"slow": true, "count": float64(3),
+			}},
+		},
+		{
+			name:  "error",
+			opts:  []Option{WithSampleRate(0)},
+			opCtx: newOpContext(operation.WithOpName(operation.OpNameInsertOne), operation.WithDuration(time.Millisecond)),
+			err:   errors.New("duplicate key"),
+			want: []map[string]any{{
+				"level": "ERROR", "msg": Message, "database": "db-test", "collection": "users", "operation": "InsertOne", "duration": float64(time.Millisecond),
+				"error": "duplicate key",
+			}},
+		},
+		{
+			name: "cursor is logged once its query returns",
+			opCtx: newOpContext(operation.WithOpName(operation.OpNameFind), operation.WithDuration(time.Millisecond),
+				operation.WithFilter(bson.M{"name": "cmy"}), operation.WithResult(&mongo.Cursor{})),
+			opType: []operation.OpType{operation.OpTypeAfterFind},
+			want: []map[string]any{{
+				"level": "DEBUG", "msg": Message, "database": "db-test", "collection": "users", "operation": "Find", "duration": float64(time.Millisecond),
+				"filter": `{"name":"cmy"}`,
+			}},
+		},
+		{
+			name:  "failed before the driver call",
+			opts:  []Option{WithSlowThreshold(time.Second)},
+			opCtx: newOpContext(operation.WithOpName(operation.OpNameInsertOne), operation.WithStartTime(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))),
+			err:   errors.New("rejected"),
+			want: []map[string]any{{
+				"level": "ERROR", "msg": Message, "database": "db-test", "collection": "users", "operation": "InsertOne", "duration": float64(0),
+				"error": "rejected",
+			}},
+		},
+		{
+			name:   "sampled out",
+			opts:   []Option{WithSampleRate(0)},
+			opCtx:  newOpContext(operation.WithOpName(operation.OpNameFind), operation.WithDuration(time.Millisecond)),
+			opType: []operation.OpType{operation.OpTypeAfterFind},
+		},
+		{
+			name:   "level disabled",
+			opts:   []Option{WithLevel(slog.LevelDebug - 1)},
+			opCtx:  newOpContext(operation.WithOpName(operation.OpNameFind), operation.WithDuration(time.Millisecond)),
+			opType: []operation.OpType{operation.OpTypeAfterFind},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{
+				Level: slog.LevelDebug,
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey && len(groups) == 0 {
+						return slog.Attr{}
+					}
+					return a
+				},
+			}))
+			s := scope{callback.NewScope(nil)}
+			require.NoError(t, NewPlugin(append([]Option{WithLogger(logger)}, tc.opts...)...).Initialize(s))
+
+			ctx := context.Background()
+			for _, opType := range tc.opType {
+				require.NoError(t, s.Execute(ctx, tc.opCtx, opType))
+			}
+			if tc.err != nil {
+				require.Equal(t, tc.err, s.OnError(ctx, tc.opCtx, tc.err))
+			}
+
+			var records []map[string]any
+			decoder := json.NewDecoder(buf)
+			for decoder.More() {
+				record := make(map[string]any)
+				require.NoError(t, decoder.Decode(&record))
+				records = append(records, record)
+			}
+			require.Equal(t, tc.want, records)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"expvar"
+	"sync"
+)
+
+var _ Sink = (*ExpvarSink)(nil)
+
+// ExpvarSink publishes the measurements with expvar, the map holds one map per collection and operation, e.g.
+//
+//	{"users.Find": {"count": 3, "errors": 0, "documents": 12, "slow": 1, "duration_seconds": 0.42}}
+//
+// where duration_seconds is the total duration of the operations.
+type ExpvarSink struct {
+	// mu serializes the creation of the maps of the operations
+	mu   sync.Mutex
+	vars *expvar.Map
+}
+
+// NewExpvarSink publishes the map under the name, or reuses the map already published under it
+func NewExpvarSink(name string) *ExpvarSink {
+	if vars, ok := expvar.Get(name).(*expvar.Map); ok {
+		return &ExpvarSink{vars: vars}
+	}
+	return &ExpvarSink{vars: expvar.NewMap(name)}
+}
+
+// Map returns the published map
+func (s *ExpvarSink) Map() *expvar.Map {
+	return s.vars
+}
+
+func (s *ExpvarSink) Record(m Measurement) {
+	operation := s.operation(m.Collection + "." + m.Operation)
+	operation.Add("count", 1)
+	operation.AddFloat("duration_seconds", m.Duration.Seconds())
+	if m.Err != nil {
+		operation.Add("errors", 1)
+	}
+	operation.Add("documents", m.Documents)
+	if m.Slow {
+		operation.Add("slow", 1)
+	}
+}
+
+func (s *ExpvarSink) operation(key string) *expvar.Map {
+	if operation, ok := s.vars.Get(key).(*expvar.Map); ok {
+		return operation
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if operation, ok := s.vars.Get(key).(*expvar.Map); ok {
+		return operation
+	}
+	// the map is initialized before it is published, so that the readers never see a partial one
+	operation := new(expvar.Map).Init()
+	for _, name := range []string{"count", "errors", "documents", "slow"} {
+		operation.Set(name, new(expvar.Int))
+	}
+	operation.Set("duration_seconds", new(expvar.Float))
+	s.vars.Set(key, operation)
+	return operation
+}
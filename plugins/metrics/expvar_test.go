@@ -0,0 +1,44 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpvarSink(t *testing.T) {
+	sink := NewExpvarSink("mongox_test")
+	require.Same(t, sink.Map(), NewExpvarSink("mongox_test").Map())
+
+	sink.Record(Measurement{Collection: "users", Operation: "Find", Duration: 300 * time.Millisecond, Documents: 2, Slow: true})
+	sink.Record(Measurement{Collection: "users", Operation: "Find", Duration: 100 * time.Millisecond, Documents: 3})
+	sink.Record(Measurement{Collection: "users", Operation: "InsertOne", Duration: time.Millisecond, Err: errors.New("duplicate key")})
+
+	find := sink.Map().Get("users.Find").(*expvar.Map)
+	require.Equal(t, "2", find.Get("count").String())
+	require.Equal(t, "0", find.Get("errors").String())
+	require.Equal(t, "5", find.Get("documents").String())
+	require.Equal(t, "1", find.Get("slow").String())
+	require.Equal(t, "0.4", find.Get("duration_seconds").String())
+
+	insert := sink.Map().Get("users.InsertOne").(*expvar.Map)
+	require.Equal(t, "1", insert.Get("errors").String())
+	require.Equal(t, "0", insert.Get("documents").String())
+}
@@ -0,0 +1,181 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics records the latency, the errors and the documents of the operations of go-mongox
+// It is a separate module, so go-mongox does not depend on Prometheus: go get github.com/chenmingyong0423/go-mongox/v2/plugins/metrics
+package metrics
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/chenmingyong0423/go-mongox/v2"
+	"github.com/chenmingyong0423/go-mongox/v2/bulkwriter"
+	"github.com/chenmingyong0423/go-mongox/v2/callback"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const (
+	// PluginName is the name the handlers of the plugin are registered with
+	PluginName = "mongox:metrics"
+
+	// DefaultSlowThreshold is the latency above which an operation is slow if WithSlowThreshold is not used
+	DefaultSlowThreshold = 200 * time.Millisecond
+
+	recordedKey = "mongox:metrics:recorded"
+	// the measurement is recorded before the other after handlers, so that an error of them does not drop it
+	recordPriority = 1000
+)
+
+// Measurement is what is recorded for an operation
+type Measurement struct {
+	Database   string
+	Collection string
+	// Operation is the name of the operation, e.g. Find or UpdateMany
+	Operation string
+	// Duration is the time between OpContext.StartTime and the end of the driver call,
+	// 0 if the operation failed before the driver was called
+	Duration time.Duration
+	// Err is the error of the operation, nil if it succeeded
+	Err error
+	// Documents is the number of documents returned by a read or affected by a write,
+	// 0 for Cursor whose documents are decoded after the measurement
+	Documents int64
+	// Slow reports whether the duration exceeds the slow threshold
+	Slow bool
+}
+
+// Sink receives the measurements, it must be safe for concurrent use
+type Sink interface {
+	Record(m Measurement)
+}
+
+var _ mongox.Plugin = (*Plugin)(nil)
+
+// Plugin records a measurement in the sink after each operation.
+// Cursor is recorded once its query returns, before any document is decoded,
+// and FindOneAndUpdate and the like and the bulk writes are recorded once.
+type Plugin struct {
+	sink          Sink
+	slowThreshold time.Duration
+}
+
+type Option func(*Plugin)
+
+// WithSlowThreshold sets the latency above which an operation is slow, 0 disables the slow operations
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(p *Plugin) {
+		p.slowThreshold = threshold
+	}
+}
+
+func NewPlugin(sink Sink, opts ...Option) *Plugin {
+	p := &Plugin{
+		sink:          sink,
+		slowThreshold: DefaultSlowThreshold,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *Plugin) Name() string {
+	return PluginName
+}
+
+func (p *Plugin) Initialize(scope mongox.PluginScope) error {
+	if err := scope.RegisterPlugin(PluginName, p.record, operation.OpTypeAfterAny, callback.Priority(recordPriority)); err != nil {
+		return err
+	}
+	return scope.RegisterPlugin(PluginName, p.record, operation.OpTypeOnError, callback.Priority(recordPriority))
+}
+
+func (p *Plugin) record(_ context.Context, opCtx *operation.OpContext, _ ...any) error {
+	if opCtx.Bulk != nil && opCtx.Bulk.Pending > 0 {
+		// a bulk write is recorded once, with the handlers of its last model
+		return nil
+	}
+	if _, ok := opCtx.Get(recordedKey); ok {
+		return nil
+	}
+	opCtx.Set(recordedKey, true)
+
+	m := Measurement{
+		Database:   opCtx.DatabaseName,
+		Collection: opCtx.CollectionName,
+		Operation:  opCtx.OpName,
+		Duration:   opCtx.Duration,
+		Err:        opCtx.Err,
+		Slow:       p.slowThreshold > 0 && opCtx.Duration > p.slowThreshold,
+	}
+	if opCtx.Bulk != nil {
+		m.Err = opCtx.Bulk.Err
+	}
+	if m.Err == nil {
+		m.Documents = documents(opCtx)
+	}
+	p.sink.Record(m)
+	return nil
+}
+
+// documents returns the number of documents affected according to the result of a write,
+// or the number of documents returned according to the decoded documents of a read
+func documents(opCtx *operation.OpContext) int64 {
+	switch r := opCtx.Result.(type) {
+	case *mongo.InsertOneResult:
+		return 1
+	case *mongo.InsertManyResult:
+		if r != nil {
+			return int64(len(r.InsertedIDs))
+		}
+		return 0
+	case *mongo.UpdateResult:
+		if r != nil {
+			return r.ModifiedCount + r.UpsertedCount
+		}
+		return 0
+	case *mongo.DeleteResult:
+		if r != nil {
+			return r.DeletedCount
+		}
+		return 0
+	case *bulkwriter.BulkWriteResult:
+		if r != nil {
+			return r.InsertedCount + r.ModifiedCount + r.UpsertedCount + r.DeletedCount
+		}
+		return 0
+	case *mongo.Cursor:
+		// the documents of a cursor are decoded once the query is recorded
+		return 0
+	}
+
+	v := reflect.ValueOf(opCtx.Doc)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return 0
+		}
+		if v.Elem().Kind() != reflect.Slice {
+			// a single decoded document, e.g. the one of FindOne
+			return 1
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice {
+		return int64(v.Len())
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build e2e
+
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chenmingyong0423/go-mongox/v2"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func TestPlugin_e2e(t *testing.T) {
+	type User struct {
+		mongox.Model `bson:",inline"`
+		Name         string `bson:"name"`
+	}
+	client, err := mongo.Connect(options.Client().ApplyURI("mongodb://localhost:27017").SetAuth(options.Credential{
+		Username:   "test",
+		Password:   "test",
+		AuthSource: "db-test",
+	}))
+	require.NoError(t, err)
+
+	sink := &recordSink{}
+	users := mongox.NewCollection[User](mongox.NewClient(client, &mongox.Config{}).NewDatabase("db-test"), "test_user")
+	require.NoError(t, users.Use(NewPlugin(sink)))
+	ctx := context.Background()
+	defer func() {
+		_, err := users.Collection().DeleteMany(ctx, bson.D{})
+		require.NoError(t, err)
+	}()
+
+	_, err = users.Creator().InsertMany(ctx, []*User{{Name: "chenmingyong"}, {Name: "burt"}})
+	require.NoError(t, err)
+	found, err := users.Finder().Find(ctx)
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	_, err = users.Creator().InsertOne(ctx, found[0])
+	require.Error(t, err)
+
+	require.Len(t, sink.measurements, 3)
+	require.Equal(t, "InsertMany", sink.measurements[0].Operation)
+	require.Equal(t, int64(2), sink.measurements[0].Documents)
+	require.Equal(t, "Find", sink.measurements[1].Operation)
+	require.Equal(t, "test_user", sink.measurements[1].Collection)
+	require.Equal(t, int64(2), sink.measurements[1].Documents)
+	require.Positive(t, sink.measurements[1].Duration)
+	require.Equal(t, "InsertOne", sink.measurements[2].Operation)
+	require.Error(t, sink.measurements[2].Err)
+
+	// a bulk write is recorded once
+	_, err = users.BulkWriter().InsertOne(&User{Name: "gopher"}).UpdateOne(bson.M{"name": "burt"}, bson.M{"$set": bson.M{"name": "Burt"}}).BulkWrite(ctx)
+	require.NoError(t, err)
+	require.Len(t, sink.measurements, 4)
+	require.Equal(t, "BulkWrite", sink.measurements[3].Operation)
+	require.Equal(t, int64(2), sink.measurements[3].Documents)
+	require.NoError(t, sink.measurements[3].Err)
+
+	// a cursor is recorded once its query returns, even if no document is decoded
+	c, err := users.Finder().Filter(bson.M{"name": "cmy"}).Cursor(ctx)
+	require.NoError(t, err)
+	require.Len(t, sink.measurements, 5)
+	require.Equal(t, "Find", sink.measurements[4].Operation)
+	require.Equal(t, int64(0), sink.measurements[4].Documents)
+	require.False(t, c.Next(ctx))
+	require.NoError(t, c.Close(ctx))
+	require.Len(t, sink.measurements, 5)
+}
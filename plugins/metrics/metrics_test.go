@@ -0,0 +1,209 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chenmingyong0423/go-mongox/v2"
+	"github.com/chenmingyong0423/go-mongox/v2/bulkwriter"
+	"github.com/chenmingyong0423/go-mongox/v2/callback"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// scope is a PluginScope backed by a bare registry
+type scope struct {
+	*callback.Callback
+}
+
+func (s scope) RegisterPlugin(name string, cb callback.CbFn, opType operation.OpType, opts ...callback.RegisterOption) error {
+	return s.Register(opType, name, cb, opts...)
+}
+
+func (s scope) RemovePlugin(name string, opType operation.OpType) {
+	s.Remove(opType, name)
+}
+
+var _ mongox.PluginScope = scope{}
+
+type recordSink struct {
+	mu           sync.Mutex
+	measurements []Measurement
+}
+
+func (s *recordSink) Record(m Measurement) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.measurements = append(s.measurements, m)
+}
+
+type user struct {
+	Name string `bson:"name"`
+}
+
+func newOpContext(opts ...operation.OpContextOption) *operation.OpContext {
+	client, _ := mongo.Connect()
+	return operation.NewOpContext(client.Database("db-test").Collection("users"), opts...)
+}
+
+func TestPlugin(t *testing.T) {
+	driverErr := errors.New("connection refused")
+
+	testCases := []struct {
+		name   string
+		opts   []Option
+		opCtx  *operation.OpContext
+		opType []operation.OpType
+		err    error
+
+		want Measurement
+	}{
+		{
+			name:   "find",
+			opCtx:  newOpContext(operation.WithOpName(operation.OpNameFind), operation.WithDuration(time.Millisecond), operation.WithDoc([]*user{{Name: "cmy"}, {Name: "burt"}})),
+			opType: []operation.OpType{operation.OpTypeAfterFind},
+			want:   Measurement{Database: "db-test", Collection: "users", Operation: "Find", Duration: time.Millisecond, Documents: 2},
+		},
+		{
+			name:   "find one",
+			opCtx:  newOpContext(operation.WithOpName(operation.OpNameFindOne), operation.WithDuration(time.Millisecond), operation.WithDoc(&user{Name: "cmy"})),
+			opType: []operation.OpType{operation.OpTypeAfterFind},
+			want:   Measurement{Database: "db-test", Collection: "users", Operation: "FindOne", Duration: time.Millisecond, Documents: 1},
+		},
+		{
+			name:   "aggregate with parse",
+			opCtx:  newOpContext(operation.WithOpName(operation.OpNameAggregate), operation.WithDuration(time.Millisecond), operation.WithDoc(&[]bson.M{{"count": 1}})),
+			opType: []operation.OpType{operation.OpTypeAfterAggregate},
+			want:   Measurement{Database: "db-test", Collection: "users", Operation: "Aggregate", Duration: time.Millisecond, Documents: 1},
+		},
+		{
+			name:   "count",
+			opCtx:  newOpContext(operation.WithOpName(operation.OpNameCount), operation.WithDuration(time.Millisecond), operation.WithResult(int64(3))),
+			opType: []operation.OpType{operation.OpTypeAfterCount},
+			want:   Measurement{Database: "db-test", Collection: "users", Operation: "Count", Duration: time.Millisecond},
+		},
+		{
+			name:   "insert many",
+			opCtx:  newOpContext(operation.WithOpName(operation.OpNameInsertMany), operation.WithDuration(time.Millisecond), operation.WithResult(&mongo.InsertManyResult{InsertedIDs: []any{1, 2}})),
+			opType: []operation.OpType{operation.OpTypeAfterInsert},
+			want:   Measurement{Database: "db-test", Collection: "users", Operation: "InsertMany", Duration: time.Millisecond, Documents: 2},
+		},
+		{
+			name:   "upsert",
+			opCtx:  newOpContext(operation.WithOpName(operation.OpNameUpsert), operation.WithDuration(time.Millisecond), operation.WithResult(&mongo.UpdateResult{MatchedCount: 0, UpsertedCount: 1})),
+			opType: []operation.OpType{operation.OpTypeAfterUpsert},
+			want:   Measurement{Database: "db-test", Collection: "users", Operation: "Upsert", Duration: time.Millisecond, Documents: 1},
+		},
+		{
+			name:   "delete many",
+			opCtx:  newOpContext(operation.WithOpName(operation.OpNameDeleteMany), operation.WithDuration(time.Millisecond), operation.WithResult(&mongo.DeleteResult{DeletedCount: 5})),
+			opType: []operation.OpType{operation.OpTypeAfterDelete},
+			want:   Measurement{Database: "db-test", Collection: "users", Operation: "DeleteMany", Duration: time.Millisecond, Documents: 5},
+		},
+		{
+			name:   "find one and update is recorded once",
+			opCtx:  newOpContext(operation.WithOpName(operation.OpNameFindOneAndUpdate), operation.WithDuration(time.Millisecond), operation.WithDoc(&user{Name: "cmy"})),
+			opType: []operation.OpType{operation.OpTypeAfterFind, operation.OpTypeAfterUpdate},
+			want:   Measurement{Database: "db-test", Collection: "users", Operation: "FindOneAndUpdate", Duration: time.Millisecond, Documents: 1},
+		},
+		{
+			name:   "slow",
+			opts:   []Option{WithSlowThreshold(time.Second)},
+			opCtx:  newOpContext(operation.WithOpName(operation.OpNameUpdateOne), operation.WithDuration(2*time.Second), operation.WithResult(&mongo.UpdateResult{MatchedCount: 1, ModifiedCount: 1})),
+			opType: []operation.OpType{operation.OpTypeAfterUpdate},
+			want:   Measurement{Database: "db-test", Collection: "users", Operation: "UpdateOne", Duration: 2 * time.Second, Documents: 1, Slow: true},
+		},
+		{
+			name:   "slow threshold disabled",
+			opts:   []Option{WithSlowThreshold(0)},
+			opCtx:  newOpContext(operation.WithOpName(operation.OpNameUpdateOne), operation.WithDuration(time.Hour), operation.WithResult(&mongo.UpdateResult{MatchedCount: 1})),
+			opType: []operation.OpType{operation.OpTypeAfterUpdate},
+			want:   Measurement{Database: "db-test", Collection: "users", Operation: "UpdateOne", Duration: time.Hour},
+		},
+		{
+			name:  "error",
+			opCtx: newOpContext(operation.WithOpName(operation.OpNameInsertOne), operation.WithDuration(time.Millisecond)),
+			err:   driverErr,
+			want:  Measurement{Database: "db-test", Collection: "users", Operation: "InsertOne", Duration: time.Millisecond, Err: driverErr},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := &recordSink{}
+			s := scope{callback.NewScope(nil)}
+			require.NoError(t, NewPlugin(sink, tc.opts...).Initialize(s))
+
+			ctx := context.Background()
+			for _, opType := range tc.opType {
+				require.NoError(t, s.Execute(ctx, tc.opCtx, opType))
+			}
+			if tc.err != nil {
+				require.Equal(t, tc.err, s.OnError(ctx, tc.opCtx, tc.err))
+			}
+			require.Equal(t, []Measurement{tc.want}, sink.measurements)
+		})
+	}
+}
+
+func TestPlugin_Cursor(t *testing.T) {
+	sink := &recordSink{}
+	s := scope{callback.NewScope(nil)}
+	require.NoError(t, NewPlugin(sink).Initialize(s))
+	ctx := context.Background()
+
+	// Cursor runs the after handlers once the query returns, then for each decoded document
+	opCtx := newOpContext(operation.WithOpName(operation.OpNameFind), operation.WithDuration(time.Millisecond), operation.WithResult(&mongo.Cursor{}))
+	require.NoError(t, s.Execute(ctx, opCtx, operation.OpTypeAfterFind))
+	require.Equal(t, []Measurement{{Database: "db-test", Collection: "users", Operation: "Find", Duration: time.Millisecond}}, sink.measurements)
+
+	opCtx.Doc = &struct{}{}
+	require.NoError(t, s.Execute(ctx, opCtx, operation.OpTypeAfterFind))
+	require.Len(t, sink.measurements, 1)
+}
+
+func TestPlugin_Bulk(t *testing.T) {
+	sink := &recordSink{}
+	s := scope{callback.NewScope(nil)}
+	require.NoError(t, NewPlugin(sink).Initialize(s))
+	ctx := context.Background()
+
+	bulk := &operation.Bulk{Size: 3, Pending: 3}
+	result := &bulkwriter.BulkWriteResult{InsertedCount: 2, ModifiedCount: 1}
+	for i := 0; i < 3; i++ {
+		bulk.Pending--
+		opCtx := newOpContext(operation.WithOpName(operation.OpNameBulkWrite), operation.WithBulk(bulk), operation.WithDuration(time.Millisecond), operation.WithResult(result))
+		require.NoError(t, s.Execute(ctx, opCtx, operation.OpTypeAfterInsert))
+	}
+	require.Equal(t, []Measurement{{Database: "db-test", Collection: "users", Operation: "BulkWrite", Duration: time.Millisecond, Documents: 3}}, sink.measurements)
+
+	// the error of the bulk write is recorded with the last model, whichever model failed
+	sink.measurements = nil
+	bulk = &operation.Bulk{Size: 2, Pending: 2}
+	bulkErr := errors.New("duplicate key")
+	bulk.Pending--
+	require.Equal(t, bulkErr, s.OnError(ctx, newOpContext(operation.WithOpName(operation.OpNameBulkWrite), operation.WithBulk(bulk), operation.WithDuration(time.Millisecond)), bulkErr))
+	require.Empty(t, sink.measurements)
+	bulk.Err = bulkErr
+	bulk.Pending--
+	require.NoError(t, s.Execute(ctx, newOpContext(operation.WithOpName(operation.OpNameBulkWrite), operation.WithBulk(bulk), operation.WithDuration(time.Millisecond)), operation.OpTypeAfterInsert))
+	require.Equal(t, []Measurement{{Database: "db-test", Collection: "users", Operation: "BulkWrite", Duration: time.Millisecond, Err: bulkErr}}, sink.measurements)
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	_ Sink                 = (*PrometheusSink)(nil)
+	_ prometheus.Collector = (*PrometheusSink)(nil)
+)
+
+// PrometheusSink records the measurements in Prometheus vectors labeled by collection and operation,
+// it is a prometheus.Collector to register, e.g. prometheus.MustRegister(sink)
+type PrometheusSink struct {
+	// Latency is the histogram of the durations in seconds
+	Latency *prometheus.HistogramVec
+	// Errors counts the failed operations
+	Errors *prometheus.CounterVec
+	// Documents counts the documents returned or affected
+	Documents *prometheus.CounterVec
+	// Slow counts the slow operations
+	Slow *prometheus.CounterVec
+}
+
+// NewPrometheusSink creates the vectors in the namespace, e.g. myapp_mongox_operation_duration_seconds.
+// The histogram uses prometheus.DefBuckets if buckets is empty.
+func NewPrometheusSink(namespace string, buckets ...float64) *PrometheusSink {
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+	labels := []string{"collection", "operation"}
+	return &PrometheusSink{
+		Latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "mongox",
+			Name:      "operation_duration_seconds",
+			Help:      "The latency of the go-mongox operations.",
+			Buckets:   buckets,
+		}, labels),
+		Errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "mongox",
+			Name:      "operation_errors_total",
+			Help:      "The number of failed go-mongox operations.",
+		}, labels),
+		Documents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "mongox",
+			Name:      "documents_total",
+			Help:      "The number of documents returned or affected by the go-mongox operations.",
+		}, labels),
+		Slow: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "mongox",
+			Name:      "slow_operations_total",
+			Help:      "The number of go-mongox operations slower than the slow threshold.",
+		}, labels),
+	}
+}
+
+func (s *PrometheusSink) Record(m Measurement) {
+	s.Latency.WithLabelValues(m.Collection, m.Operation).Observe(m.Duration.Seconds())
+	if m.Err != nil {
+		s.Errors.WithLabelValues(m.Collection, m.Operation).Inc()
+	}
+	if m.Documents > 0 {
+		s.Documents.WithLabelValues(m.Collection, m.Operation).Add(float64(m.Documents))
+	}
+	if m.Slow {
+		s.Slow.WithLabelValues(m.Collection, m.Operation).Inc()
+	}
+}
+
+func (s *PrometheusSink) Describe(ch chan<- *prometheus.Desc) {
+	s.Latency.Describe(ch)
+	s.Errors.Describe(ch)
+	s.Documents.Describe(ch)
+	s.Slow.Describe(ch)
+}
+
+func (s *PrometheusSink) Collect(ch chan<- prometheus.Metric) {
+	s.Latency.Collect(ch)
+	s.Errors.Collect(ch)
+	s.Documents.Collect(ch)
+	s.Slow.Collect(ch)
+}
@@ -0,0 +1,48 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusSink(t *testing.T) {
+	sink := NewPrometheusSink("shop")
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(sink))
+
+	sink.Record(Measurement{Collection: "users", Operation: "Find", Duration: 300 * time.Millisecond, Documents: 2, Slow: true})
+	sink.Record(Measurement{Collection: "users", Operation: "Find", Duration: 10 * time.Millisecond, Documents: 3})
+	sink.Record(Measurement{Collection: "users", Operation: "InsertOne", Duration: time.Millisecond, Err: errors.New("duplicate key")})
+
+	require.Equal(t, 2, testutil.CollectAndCount(sink.Latency))
+	require.Equal(t, float64(5), testutil.ToFloat64(sink.Documents.WithLabelValues("users", "Find")))
+	require.Equal(t, float64(1), testutil.ToFloat64(sink.Slow.WithLabelValues("users", "Find")))
+	require.Equal(t, float64(1), testutil.ToFloat64(sink.Errors.WithLabelValues("users", "InsertOne")))
+
+	err := testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP shop_mongox_operation_errors_total The number of failed go-mongox operations.
+# TYPE shop_mongox_operation_errors_total counter
+shop_mongox_operation_errors_total{collection="users",operation="InsertOne"} 1
+`), "shop_mongox_operation_errors_total")
+	require.NoError(t, err)
+}
@@ -0,0 +1,231 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otel traces the operations of go-mongox with OpenTelemetry
+// It is a separate module, so go-mongox does not depend on OpenTelemetry: go get github.com/chenmingyong0423/go-mongox/v2/plugins/otel
+package otel
+
+import (
+	"context"
+
+	"github.com/chenmingyong0423/go-mongox/v2"
+	"github.com/chenmingyong0423/go-mongox/v2/bulkwriter"
+	"github.com/chenmingyong0423/go-mongox/v2/callback"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// PluginName is the name the handlers of the plugin are registered with
+	PluginName = "mongox:otel"
+
+	instrumentationName = "github.com/chenmingyong0423/go-mongox/v2/plugins/otel"
+	spanKey             = "mongox:otel:span"
+
+	// the span starts after the other before handlers of the scope, so that the operations they reject are not traced,
+	// and ends before the other after handlers, so that an error of them does not leave it open
+	startPriority = -1000
+	endPriority   = 1000
+)
+
+// The attributes of the spans besides the semantic conventions of the database spans
+const (
+	// FilterKey is the shape of the filter, its values are replaced by "?"
+	FilterKey        = attribute.Key("mongox.filter")
+	MultiKey         = attribute.Key("mongox.multi")
+	InsertedCountKey = attribute.Key("mongox.inserted_count")
+	MatchedCountKey  = attribute.Key("mongox.matched_count")
+	ModifiedCountKey = attribute.Key("mongox.modified_count")
+	UpsertedCountKey = attribute.Key("mongox.upserted_count")
+	DeletedCountKey  = attribute.Key("mongox.deleted_count")
+	// ModelsKey is the number of models of a bulk write
+	ModelsKey = attribute.Key("mongox.models")
+	// LabelKeyPrefix prefixes the labels of the context of the operation, see mongox.WithOpLabels
+	LabelKeyPrefix = "mongox.label."
+)
+
+var _ mongox.Plugin = (*Plugin)(nil)
+
+// Plugin records a span named mongox.<collection>.<operation> for each operation, e.g. mongox.users.Find,
+// a bulk write is recorded with one span for all its models.
+// The context passed to the driver carries the span, so the command spans of the driver instrumentation,
+// e.g. otelmongo, are its children.
+type Plugin struct {
+	tracerProvider trace.TracerProvider
+	attributes     []attribute.KeyValue
+
+	tracer trace.Tracer
+}
+
+type Option func(*Plugin)
+
+// WithTracerProvider sets the provider of the tracer, the global one is used by default
+func WithTracerProvider(tracerProvider trace.TracerProvider) Option {
+	return func(p *Plugin) {
+		p.tracerProvider = tracerProvider
+	}
+}
+
+// WithAttributes adds the attributes to all the spans
+func WithAttributes(attributes ...attribute.KeyValue) Option {
+	return func(p *Plugin) {
+		p.attributes = append(p.attributes, attributes...)
+	}
+}
+
+func NewPlugin(opts ...Option) *Plugin {
+	p := &Plugin{
+		tracerProvider: otel.GetTracerProvider(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.tracer = p.tracerProvider.Tracer(instrumentationName)
+	return p
+}
+
+func (p *Plugin) Name() string {
+	return PluginName
+}
+
+func (p *Plugin) Initialize(scope mongox.PluginScope) error {
+	if err := scope.RegisterPlugin(PluginName, p.start, operation.OpTypeBeforeAny, callback.Priority(startPriority)); err != nil {
+		return err
+	}
+	if err := scope.RegisterPlugin(PluginName, p.end, operation.OpTypeAfterAny, callback.Priority(endPriority)); err != nil {
+		return err
+	}
+	return scope.RegisterPlugin(PluginName, p.end, operation.OpTypeOnError, callback.Priority(endPriority))
+}
+
+func (p *Plugin) start(ctx context.Context, opCtx *operation.OpContext, _ ...any) error {
+	if _, ok := opCtx.Get(spanKey); ok {
+		// FindOneAndUpdate and the like run the handlers of two operation types
+		return nil
+	}
+	if opCtx.Bulk != nil && opCtx.Bulk.Index > 0 {
+		// the span of the bulk write is started by the first model, the context carrying it is passed to the next ones
+		return nil
+	}
+	attributes := []attribute.KeyValue{
+		semconv.DBSystemMongoDB,
+		semconv.DBName(opCtx.DatabaseName),
+		semconv.DBMongoDBCollection(opCtx.CollectionName),
+		semconv.DBOperation(opCtx.OpName),
+	}
+	if opCtx.Multi {
+		attributes = append(attributes, MultiKey.Bool(true))
+	}
+	for key, value := range opCtx.Labels {
+		attributes = append(attributes, attribute.String(LabelKeyPrefix+key, value))
+	}
+	attributes = append(attributes, p.attributes...)
+
+	ctx, span := p.tracer.Start(opCtx.Context(ctx), "mongox."+opCtx.CollectionName+"."+opCtx.OpName,
+		trace.WithSpanKind(trace.SpanKindInternal), trace.WithAttributes(attributes...))
+	if opCtx.Bulk != nil {
+		opCtx.Bulk.Set(spanKey, span)
+	} else {
+		opCtx.Set(spanKey, span)
+	}
+	opCtx.SetContext(ctx)
+	return nil
+}
+
+func (p *Plugin) end(_ context.Context, opCtx *operation.OpContext, _ ...any) error {
+	if opCtx.Bulk != nil {
+		return p.endBulk(opCtx)
+	}
+	value, ok := opCtx.Get(spanKey)
+	if !ok {
+		// the after handlers of Cursor run once the query returns, which ends the span, then for each decoded document
+		return nil
+	}
+	delete(opCtx.Metadata, spanKey)
+	span := value.(trace.Span)
+
+	if shape := filterShape(opCtx.Filter); shape != "" {
+		span.SetAttributes(FilterKey.String(shape))
+	}
+	span.SetAttributes(resultAttributes(opCtx.Result)...)
+	endSpan(span, opCtx.Err)
+	return nil
+}
+
+// endBulk ends the span of the bulk write with the handlers of its last model
+func (p *Plugin) endBulk(opCtx *operation.OpContext) error {
+	if opCtx.Bulk.Pending > 0 {
+		return nil
+	}
+	value, ok := opCtx.Bulk.Get(spanKey)
+	if !ok {
+		return nil
+	}
+	delete(opCtx.Bulk.Metadata, spanKey)
+	span := value.(trace.Span)
+
+	span.SetAttributes(ModelsKey.Int(opCtx.Bulk.Size))
+	span.SetAttributes(resultAttributes(opCtx.Result)...)
+	endSpan(span, opCtx.Bulk.Err)
+	return nil
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func resultAttributes(result any) []attribute.KeyValue {
+	switch r := result.(type) {
+	case *mongo.InsertOneResult:
+		if r != nil {
+			return []attribute.KeyValue{InsertedCountKey.Int64(1)}
+		}
+	case *mongo.InsertManyResult:
+		if r != nil {
+			return []attribute.KeyValue{InsertedCountKey.Int(len(r.InsertedIDs))}
+		}
+	case *mongo.UpdateResult:
+		if r != nil {
+			return []attribute.KeyValue{MatchedCountKey.Int64(r.MatchedCount), ModifiedCountKey.Int64(r.ModifiedCount), UpsertedCountKey.Int64(r.UpsertedCount)}
+		}
+	case *mongo.DeleteResult:
+		if r != nil {
+			return []attribute.KeyValue{DeletedCountKey.Int64(r.DeletedCount)}
+		}
+	case *bulkwriter.BulkWriteResult:
+		if r != nil {
+			return []attribute.KeyValue{
+				InsertedCountKey.Int64(r.InsertedCount), MatchedCountKey.Int64(r.MatchedCount), ModifiedCountKey.Int64(r.ModifiedCount),
+				UpsertedCountKey.Int64(r.UpsertedCount), DeletedCountKey.Int64(r.DeletedCount),
+			}
+		}
+	case *mongo.BulkWriteResult:
+		if r != nil {
+			return []attribute.KeyValue{
+				InsertedCountKey.Int64(r.InsertedCount), MatchedCountKey.Int64(r.MatchedCount), ModifiedCountKey.Int64(r.ModifiedCount),
+				UpsertedCountKey.Int64(r.UpsertedCount), DeletedCountKey.Int64(r.DeletedCount),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build e2e
+
+package otel
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/chenmingyong0423/go-mongox/v2"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/update"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestPlugin_e2e(t *testing.T) {
+	type User struct {
+		mongox.Model `bson:",inline"`
+		Name         string `bson:"name"`
+		Age          int    `bson:"age"`
+	}
+
+	// the monitor records the spans of the contexts of the commands, like the driver instrumentation does
+	var (
+		mu           sync.Mutex
+		commandSpans = make(map[string]trace.SpanContext)
+	)
+	monitor := &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			commandSpans[evt.CommandName] = trace.SpanContextFromContext(ctx)
+		},
+	}
+	client, err := mongo.Connect(options.Client().ApplyURI("mongodb://localhost:27017").SetAuth(options.Credential{
+		Username:   "test",
+		Password:   "test",
+		AuthSource: "db-test",
+	}).SetMonitor(monitor))
+	require.NoError(t, err)
+
+	exporter := tracetest.NewInMemoryExporter()
+	mongoxClient := mongox.NewClient(client, &mongox.Config{})
+	require.NoError(t, mongoxClient.Use(NewPlugin(WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))))))
+	users := mongox.NewCollection[User](mongoxClient.NewDatabase("db-test"), "test_user")
+	ctx := context.Background()
+	defer func() {
+		_, err := users.Collection().DeleteMany(ctx, bson.D{})
+		require.NoError(t, err)
+	}()
+
+	_, err = users.Creator().InsertOne(ctx, &User{Name: "chenmingyong", Age: 24})
+	require.NoError(t, err)
+	result, err := users.Updater().Filter(bson.M{"name": "chenmingyong"}).Updates(update.Set("age", 25)).UpdateOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.ModifiedCount)
+	_, err = users.Finder().Filter(bson.M{"name": "burt"}).FindOne(ctx)
+	require.ErrorIs(t, err, mongo.ErrNoDocuments)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+	require.Equal(t, "mongox.test_user.InsertOne", spans[0].Name)
+	require.Equal(t, "mongox.test_user.UpdateOne", spans[1].Name)
+	require.Contains(t, spans[1].Attributes, MatchedCountKey.Int64(1))
+	require.Contains(t, spans[1].Attributes, ModifiedCountKey.Int64(1))
+	require.Equal(t, "mongox.test_user.FindOne", spans[2].Name)
+	require.Contains(t, spans[2].Attributes, FilterKey.String(`{"name":"?"}`))
+	require.Equal(t, "Error", spans[2].Status.Code.String())
+
+	mu.Lock()
+	require.Equal(t, spans[0].SpanContext, commandSpans["insert"])
+	require.Equal(t, spans[1].SpanContext, commandSpans["update"])
+	require.Equal(t, spans[2].SpanContext, commandSpans["find"])
+	mu.Unlock()
+
+	// the span of a cursor ends once the query returns, even if no document is decoded
+	c, err := users.Finder().Filter(bson.M{"name": "burt"}).Cursor(ctx)
+	require.NoError(t, err)
+	spans = exporter.GetSpans()
+	require.Len(t, spans, 4)
+	require.Equal(t, "mongox.test_user.Find", spans[3].Name)
+	require.False(t, c.Next(ctx))
+	require.NoError(t, c.Close(ctx))
+	require.Len(t, exporter.GetSpans(), 4)
+}
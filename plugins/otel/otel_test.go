@@ -0,0 +1,261 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/chenmingyong0423/go-mongox/v2"
+	"github.com/chenmingyong0423/go-mongox/v2/callback"
+	"github.com/chenmingyong0423/go-mongox/v2/operation"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// scope is a PluginScope backed by a bare registry
+type scope struct {
+	*callback.Callback
+}
+
+func (s scope) RegisterPlugin(name string, cb callback.CbFn, opType operation.OpType, opts ...callback.RegisterOption) error {
+	return s.Register(opType, name, cb, opts...)
+}
+
+func (s scope) RemovePlugin(name string, opType operation.OpType) {
+	s.Remove(opType, name)
+}
+
+var _ mongox.PluginScope = scope{}
+
+func newScope(t *testing.T) (scope, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	s := scope{callback.NewScope(nil)}
+	require.NoError(t, NewPlugin(WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))), WithAttributes(attribute.String("service", "shop"))).Initialize(s))
+	return s, exporter
+}
+
+func newOpContext(opts ...operation.OpContextOption) *operation.OpContext {
+	client, _ := mongo.Connect()
+	return operation.NewOpContext(client.Database("db-test").Collection("users"), opts...)
+}
+
+func TestPlugin(t *testing.T) {
+	driverErr := errors.New("connection refused")
+
+	testCases := []struct {
+		name   string
+		opCtx  *operation.OpContext
+		before []operation.OpType
+		after  []operation.OpType
+		err    error
+
+		wantName       string
+		wantAttributes []attribute.KeyValue
+		wantStatus     codes.Code
+	}{
+		{
+			name:     "find",
+			opCtx:    newOpContext(operation.WithOpName(operation.OpNameFind), operation.WithFilter(bson.M{"name": "cmy", "age": bson.M{"$gt": 18}})),
+			before:   []operation.OpType{operation.OpTypeBeforeFind},
+			after:    []operation.OpType{operation.OpTypeAfterFind},
+			wantName: "mongox.users.Find",
+			wantAttributes: []attribute.KeyValue{
+				attribute.String("db.system", "mongodb"),
+				attribute.String("db.name", "db-test"),
+				attribute.String("db.mongodb.collection", "users"),
+				attribute.String("db.operation", "Find"),
+				attribute.String("service", "shop"),
+				FilterKey.String(`{"age":{"$gt":"?"},"name":"?"}`),
+			},
+		},
+		{
+			name: "update many",
+			opCtx: newOpContext(operation.WithOpName(operation.OpNameUpdateMany), operation.WithMulti(true), operation.WithLabels(map[string]string{"tenant": "acme"}),
+				operation.WithFilter(bson.D{}), operation.WithResult(&mongo.UpdateResult{MatchedCount: 3, ModifiedCount: 2})),
+			before:   []operation.OpType{operation.OpTypeBeforeUpdate},
+			after:    []operation.OpType{operation.OpTypeAfterUpdate},
+			wantName: "mongox.users.UpdateMany",
+			wantAttributes: []attribute.KeyValue{
+				attribute.String("db.system", "mongodb"),
+				attribute.String("db.name", "db-test"),
+				attribute.String("db.mongodb.collection", "users"),
+				attribute.String("db.operation", "UpdateMany"),
+				MultiKey.Bool(true),
+				attribute.String("mongox.label.tenant", "acme"),
+				attribute.String("service", "shop"),
+				FilterKey.String("{}"),
+				MatchedCountKey.Int64(3),
+				ModifiedCountKey.Int64(2),
+				UpsertedCountKey.Int64(0),
+			},
+		},
+		{
+			name:     "find one and update runs the handlers of two operation types",
+			opCtx:    newOpContext(operation.WithOpName(operation.OpNameFindOneAndUpdate)),
+			before:   []operation.OpType{operation.OpTypeBeforeFind, operation.OpTypeBeforeUpdate},
+			after:    []operation.OpType{operation.OpTypeAfterFind, operation.OpTypeAfterUpdate},
+			wantName: "mongox.users.FindOneAndUpdate",
+			wantAttributes: []attribute.KeyValue{
+				attribute.String("db.system", "mongodb"),
+				attribute.String("db.name", "db-test"),
+				attribute.String("db.mongodb.collection", "users"),
+				attribute.String("db.operation", "FindOneAndUpdate"),
+				attribute.String("service", "shop"),
+			},
+		},
+		{
+			name:     "error",
+			opCtx:    newOpContext(operation.WithOpName(operation.OpNameDeleteOne), operation.WithFilter(bson.D{{Key: "_id", Value: 1}})),
+			before:   []operation.OpType{operation.OpTypeBeforeDelete},
+			err:      driverErr,
+			wantName: "mongox.users.DeleteOne",
+			wantAttributes: []attribute.KeyValue{
+				attribute.String("db.system", "mongodb"),
+				attribute.String("db.name", "db-test"),
+				attribute.String("db.mongodb.collection", "users"),
+				attribute.String("db.operation", "DeleteOne"),
+				attribute.String("service", "shop"),
+				FilterKey.String(`{"_id":"?"}`),
+			},
+			wantStatus: codes.Error,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, exporter := newScope(t)
+			ctx := context.Background()
+			for _, opType := range tc.before {
+				require.NoError(t, s.Execute(ctx, tc.opCtx, opType))
+			}
+			// the context passed to the driver carries the span
+			spanCtx := trace.SpanContextFromContext(tc.opCtx.Context(ctx))
+			require.True(t, spanCtx.IsValid())
+
+			if tc.err != nil {
+				require.Equal(t, tc.err, s.OnError(ctx, tc.opCtx, tc.err))
+			}
+			for _, opType := range tc.after {
+				require.NoError(t, s.Execute(ctx, tc.opCtx, opType))
+			}
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			require.Equal(t, tc.wantName, spans[0].Name)
+			require.Equal(t, spanCtx, spans[0].SpanContext)
+			require.Equal(t, trace.SpanKindInternal, spans[0].SpanKind)
+			require.ElementsMatch(t, tc.wantAttributes, spans[0].Attributes)
+			require.Equal(t, tc.wantStatus, spans[0].Status.Code)
+			if tc.err != nil {
+				require.Len(t, spans[0].Events, 1)
+				require.Equal(t, "exception", spans[0].Events[0].Name)
+			}
+		})
+	}
+}
+
+func TestPlugin_ParentSpan(t *testing.T) {
+	s, exporter := newScope(t)
+	ctx, parent := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "handler")
+	opCtx := newOpContext(operation.WithOpName(operation.OpNameFindOne))
+
+	require.NoError(t, s.Execute(ctx, opCtx, operation.OpTypeBeforeFind))
+	require.NoError(t, s.Execute(ctx, opCtx, operation.OpTypeAfterFind))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, parent.SpanContext(), spans[0].Parent)
+}
+
+func TestPlugin_Cursor(t *testing.T) {
+	s, exporter := newScope(t)
+	ctx := context.Background()
+	opCtx := newOpContext(operation.WithOpName(operation.OpNameFind))
+
+	require.NoError(t, s.Execute(ctx, opCtx, operation.OpTypeBeforeFind))
+	// Cursor runs the after handlers once the query returns, the span ends even if no document is decoded
+	opCtx.Result = &mongo.Cursor{}
+	require.NoError(t, s.Execute(ctx, opCtx, operation.OpTypeAfterFind))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, "mongox.users.Find", spans[0].Name)
+
+	// then for each decoded document
+	opCtx.Doc = &struct{}{}
+	require.NoError(t, s.Execute(ctx, opCtx, operation.OpTypeAfterFind))
+	require.Len(t, exporter.GetSpans(), 1)
+}
+
+func TestPlugin_BeforeError(t *testing.T) {
+	errRejected := errors.New("rejected")
+	s, exporter := newScope(t)
+	// runs after the span is started like the before hooks of the operators
+	require.NoError(t, s.Register(operation.OpTypeBeforeInsert, "reject", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+		return errRejected
+	}, callback.Priority(startPriority-1)))
+	opCtx := newOpContext(operation.WithOpName(operation.OpNameInsertOne))
+
+	err := s.Execute(context.Background(), opCtx, operation.OpTypeBeforeInsert)
+	require.Equal(t, errRejected, err)
+	// the operator runs the onError handlers with the error of the before handlers, they end the span
+	require.Equal(t, errRejected, s.OnError(context.Background(), opCtx, err))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, codes.Error, spans[0].Status.Code)
+}
+
+func TestPlugin_Bulk(t *testing.T) {
+	s, exporter := newScope(t)
+	ctx := context.Background()
+	bulk := &operation.Bulk{Size: 2}
+	opCtxs := []*operation.OpContext{
+		newOpContext(operation.WithOpName(operation.OpNameBulkWrite), operation.WithBulk(bulk)),
+		newOpContext(operation.WithOpName(operation.OpNameBulkWrite), operation.WithBulk(bulk)),
+	}
+	opTypes := []operation.OpType{operation.OpTypeBeforeInsert, operation.OpTypeBeforeUpdate}
+	for i, opCtx := range opCtxs {
+		bulk.Index = i
+		bulk.Pending++
+		require.NoError(t, s.Execute(ctx, opCtx, opTypes[i]))
+		// the bulk writer passes the context of the previous model to the next one
+		ctx = opCtx.Context(ctx)
+	}
+	spanCtx := trace.SpanContextFromContext(ctx)
+	require.True(t, spanCtx.IsValid())
+
+	bulk.Pending--
+	require.NoError(t, s.Execute(ctx, opCtxs[0], operation.OpTypeAfterInsert))
+	require.Empty(t, exporter.GetSpans())
+
+	bulk.Err = errors.New("duplicate key")
+	bulk.Pending--
+	require.Equal(t, bulk.Err, s.OnError(ctx, opCtxs[1], bulk.Err))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, "mongox.users.BulkWrite", spans[0].Name)
+	require.Equal(t, spanCtx, spans[0].SpanContext)
+	require.Contains(t, spans[0].Attributes, ModelsKey.Int(2))
+	require.Equal(t, codes.Error, spans[0].Status.Code)
+}
@@ -0,0 +1,85 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otel
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// filterShape returns the filter as JSON with its values replaced by "?", e.g. {"age":{"$gt":"?"},"name":"?"}.
+// The keys are sorted so that the filters of the same shape give the same string, it returns "" if the filter is not a document.
+func filterShape(filter any) string {
+	if filter == nil {
+		return ""
+	}
+	data, err := bson.Marshal(filter)
+	if err != nil {
+		return ""
+	}
+	var b strings.Builder
+	writeDocumentShape(&b, data)
+	return b.String()
+}
+
+func writeDocumentShape(b *strings.Builder, doc bson.Raw) {
+	elements, _ := doc.Elements()
+	sort.SliceStable(elements, func(i, j int) bool {
+		return elements[i].Key() < elements[j].Key()
+	})
+	b.WriteByte('{')
+	for i, element := range elements {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Quote(element.Key()))
+		b.WriteByte(':')
+		writeValueShape(b, element.Value())
+	}
+	b.WriteByte('}')
+}
+
+func writeValueShape(b *strings.Builder, value bson.RawValue) {
+	switch value.Type {
+	case bson.TypeEmbeddedDocument:
+		writeDocumentShape(b, value.Document())
+	case bson.TypeArray:
+		values, _ := value.Array().Values()
+		// the arrays of $and, $or and $nor hold documents, the arrays of $in and the like hold values
+		for _, v := range values {
+			if v.Type == bson.TypeEmbeddedDocument {
+				writeArrayShape(b, values)
+				return
+			}
+		}
+		b.WriteString(`"?"`)
+	default:
+		b.WriteString(`"?"`)
+	}
+}
+
+func writeArrayShape(b *strings.Builder, values []bson.RawValue) {
+	b.WriteByte('[')
+	for i, value := range values {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		writeValueShape(b, value)
+	}
+	b.WriteByte(']')
+}
@@ -0,0 +1,66 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestFilterShape(t *testing.T) {
+	testCases := []struct {
+		name   string
+		filter any
+		want   string
+	}{
+		{
+			name:   "nil",
+			filter: nil,
+			want:   "",
+		},
+		{
+			name:   "not a document",
+			filter: "name",
+			want:   "",
+		},
+		{
+			name:   "empty",
+			filter: bson.M{},
+			want:   "{}",
+		},
+		{
+			name:   "values",
+			filter: bson.D{{Key: "name", Value: "cmy"}, {Key: "age", Value: bson.D{{Key: "$gte", Value: 18}, {Key: "$lt", Value: 30}}}},
+			want:   `{"age":{"$gte":"?","$lt":"?"},"name":"?"}`,
+		},
+		{
+			name:   "array of values",
+			filter: bson.M{"name": bson.M{"$in": bson.A{"cmy", "chenmingyong"}}},
+			want:   `{"name":{"$in":"?"}}`,
+		},
+		{
+			name:   "array of documents",
+			filter: bson.M{"$or": bson.A{bson.M{"name": "cmy"}, bson.M{"age": 18}}},
+			want:   `{"$or":[{"name":"?"},{"age":"?"}]}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, filterShape(tc.filter))
+		})
+	}
+}
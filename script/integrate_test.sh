@@ -18,4 +18,8 @@ docker compose -f script/integration_test_compose.yml up -d
 
 #go test ./... -race -cover -failfast -count=1 -parallel=1 -tags=e2e
 go list ./... | xargs -I {} sh -c 'go test -race -cover -failfast -count=1 -parallel=1 -tags=e2e {} || exit 255'
+# the plugins with third-party dependencies are separate modules
+for module in plugins/otel plugins/metrics; do
+  (cd $module && go list ./... | xargs -I {} sh -c 'go test -race -cover -failfast -count=1 -parallel=1 -tags=e2e {} || exit 255')
+done
 docker compose -f script/integration_test_compose.yml down -v
@@ -0,0 +1,115 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongox
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Session wraps a mongo session for manually managed transactions.
+// Example:
+//
+//	session, err := client.StartSession()
+//	defer session.EndSession(ctx)
+//	err = session.StartTransaction()
+//	ctx = session.Context(ctx)
+//	_, err = userColl.Creator().InsertOne(ctx, user)
+//	err = session.CommitTransaction(ctx)
+type Session struct {
+	session *mongo.Session
+	commits *commitHooks
+}
+
+// Session returns the mongo session
+func (s *Session) Session() *mongo.Session {
+	return s.session
+}
+
+// Context returns a copy of ctx carrying the session, operations executed with it join the session
+func (s *Session) Context(ctx context.Context) context.Context {
+	return mongo.NewSessionContext(ctx, s.session)
+}
+
+func (s *Session) StartTransaction(opts ...options.Lister[options.TransactionOptions]) error {
+	return s.session.StartTransaction(opts...)
+}
+
+func (s *Session) CommitTransaction(ctx context.Context) error {
+	defer s.commits.run(ctx, s.session)
+	return s.session.CommitTransaction(ctx)
+}
+
+func (s *Session) AbortTransaction(ctx context.Context) error {
+	defer s.commits.run(ctx, s.session)
+	return s.session.AbortTransaction(ctx)
+}
+
+func (s *Session) EndSession(ctx context.Context) {
+	defer s.commits.end(ctx, s.session)
+	s.session.EndSession(ctx)
+}
+
+// commitHooks holds the functions registered by the handlers of the writes of a transaction,
+// they run once the transaction is committed or aborted, e.g. to invalidate the cache again.
+// Only the sessions started by the client are tracked, the functions of the other sessions are not kept.
+type commitHooks struct {
+	mu    sync.Mutex
+	hooks map[*mongo.Session][]func(ctx context.Context)
+}
+
+func newCommitHooks() *commitHooks {
+	return &commitHooks{hooks: make(map[*mongo.Session][]func(ctx context.Context))}
+}
+
+// begin starts tracking the session
+func (h *commitHooks) begin(session *mongo.Session) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hooks[session] = nil
+}
+
+// add registers the function for the transaction of the session, it is dropped if the session is not tracked
+func (h *commitHooks) add(session *mongo.Session, fn func(ctx context.Context)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if hooks, ok := h.hooks[session]; ok {
+		h.hooks[session] = append(hooks, fn)
+	}
+}
+
+// run runs and drops the functions registered for the session, the session is still tracked
+func (h *commitHooks) run(ctx context.Context, session *mongo.Session) {
+	h.mu.Lock()
+	hooks, ok := h.hooks[session]
+	if ok {
+		h.hooks[session] = nil
+	}
+	h.mu.Unlock()
+	for _, fn := range hooks {
+		fn(ctx)
+	}
+}
+
+// end runs the functions registered for the session and stops tracking it
+func (h *commitHooks) end(ctx context.Context, session *mongo.Session) {
+	h.run(ctx, session)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.hooks, session)
+}
@@ -0,0 +1,51 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongox
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestCommitHooks(t *testing.T) {
+	ctx := context.Background()
+	hooks := newCommitHooks()
+	tracked, untracked := &mongo.Session{}, &mongo.Session{}
+	calls := 0
+	fn := func(ctx context.Context) {
+		calls++
+	}
+
+	hooks.begin(tracked)
+	hooks.add(tracked, fn)
+	hooks.add(untracked, fn)
+	assert.Len(t, hooks.hooks, 1)
+
+	hooks.run(ctx, tracked)
+	assert.Equal(t, 1, calls)
+	hooks.run(ctx, tracked)
+	assert.Equal(t, 1, calls)
+
+	// the session is still tracked after the commit, e.g. for the next transaction
+	hooks.add(tracked, fn)
+	hooks.end(ctx, tracked)
+	assert.Equal(t, 2, calls)
+	assert.Empty(t, hooks.hooks)
+	hooks.add(tracked, fn)
+	assert.Empty(t, hooks.hooks)
+}
@@ -16,22 +16,28 @@ package updater
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/chenmingyong0423/go-mongox/v2/field"
 
 	"github.com/chenmingyong0423/go-mongox/v2/callback"
 
+	hookfield "github.com/chenmingyong0423/go-mongox/v2/internal/hook/field"
 	"github.com/chenmingyong0423/go-mongox/v2/internal/pkg/utils"
 
 	"github.com/chenmingyong0423/go-mongox/v2/bsonx"
 
 	"github.com/chenmingyong0423/go-mongox/v2/operation"
 
+	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
+// ErrSoftDeleteDisabled is returned by Restore when the model has no soft-delete field
+var ErrSoftDeleteDisabled = errors.New("mongox: soft delete is not enabled for the model")
+
 //go:generate mockgen -source=updater.go -destination=../mock/updater.mock.go -package=mocks
 type IUpdater[T any] interface {
 	UpdateOne(ctx context.Context, opts ...options.Lister[options.UpdateOneOptions]) (*mongo.UpdateResult, error)
@@ -40,7 +46,7 @@ type IUpdater[T any] interface {
 }
 
 func NewUpdater[T any](collection *mongo.Collection, dbCallbacks *callback.Callback, fields []*field.Filed) *Updater[T] {
-	return &Updater[T]{collection: collection, dbCallbacks: dbCallbacks, fields: fields}
+	return &Updater[T]{collection: collection, dbCallbacks: dbCallbacks, fields: fields, clock: time.Now}
 }
 
 var _ IUpdater[any] = (*Updater[any])(nil)
@@ -53,10 +59,15 @@ type Updater[T any] struct {
 	updates     any
 	replacement any
 	modelHook   any
+	unscoped    bool
+	version     *int64
 
 	dbCallbacks *callback.Callback
 	beforeHooks []beforeHookFn
 	afterHooks  []afterHookFn
+
+	clock   func() time.Time
+	timeout time.Duration
 }
 
 // Filter is used to set the filter of the query
@@ -71,16 +82,42 @@ func (u *Updater[T]) Updates(updates any) *Updater[T] {
 	return u
 }
 
+// Replacement is used to set the document which replaces the matched one in ReplaceOne and ReplaceOrInsert
 func (u *Updater[T]) Replacement(replacement any) *Updater[T] {
 	u.replacement = replacement
 	return u
 }
 
+// Unscoped is used to include the soft-deleted documents in the update
+func (u *Updater[T]) Unscoped() *Updater[T] {
+	u.unscoped = true
+	return u
+}
+
+// Version is used to set the version the document is expected to have when the model has a version field,
+// UpdateOne, Upsert, ReplaceOne and ReplaceOrInsert return field.ErrVersionConflict if the document has been modified since
+func (u *Updater[T]) Version(current int64) *Updater[T] {
+	u.version = &current
+	return u
+}
+
 func (u *Updater[T]) ModelHook(modelHook any) *Updater[T] {
 	u.modelHook = modelHook
 	return u
 }
 
+// Clock is used to set the function which returns the current time of the update, time.Now by default
+func (u *Updater[T]) Clock(clock func() time.Time) *Updater[T] {
+	u.clock = clock
+	return u
+}
+
+// Timeout is used to set the timeout of the update, it is ignored if the context already has a deadline
+func (u *Updater[T]) Timeout(timeout time.Duration) *Updater[T] {
+	u.timeout = timeout
+	return u
+}
+
 func (u *Updater[T]) RegisterBeforeHooks(hooks ...beforeHookFn) *Updater[T] {
 	u.beforeHooks = append(u.beforeHooks, hooks...)
 	return u
@@ -91,15 +128,16 @@ func (u *Updater[T]) RegisterAfterHooks(hooks ...afterHookFn) *Updater[T] {
 	return u
 }
 
+// preActionHandler runs the before callbacks and hooks, the onError callbacks are run with the error if one of them fails
 func (u *Updater[T]) preActionHandler(ctx context.Context, globalOpContext *operation.OpContext, opContext *OpContext, opType operation.OpType) error {
 	err := u.dbCallbacks.Execute(ctx, globalOpContext, opType)
 	if err != nil {
-		return err
+		return u.dbCallbacks.OnError(globalOpContext.Context(ctx), globalOpContext, err)
 	}
 	for _, beforeHook := range u.beforeHooks {
 		err = beforeHook(ctx, opContext)
 		if err != nil {
-			return err
+			return u.dbCallbacks.OnError(globalOpContext.Context(ctx), globalOpContext, err)
 		}
 	}
 	return nil
@@ -121,23 +159,30 @@ func (u *Updater[T]) postActionHandler(ctx context.Context, globalOpContext *ope
 
 func (u *Updater[T]) UpdateOne(ctx context.Context, opts ...options.Lister[options.UpdateOneOptions]) (*mongo.UpdateResult, error) {
 
-	currentTime := time.Now()
+	ctx, cancel := utils.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	currentTime := u.clock()
 
 	updates := bsonx.ToBsonM(u.updates)
 	if len(updates) != 0 {
 		u.updates = updates
 	}
 
-	globalOpContext := operation.NewOpContext(u.collection, operation.WithDoc(new(T)), operation.WithFilter(u.filter), operation.WithUpdates(u.updates), operation.WithMongoOptions(opts), operation.WithModelHook(u.modelHook), operation.WithFields(u.fields), operation.WithStartTime(currentTime))
+	globalOpContext := operation.NewOpContext(u.collection, operation.WithDoc(new(T)), operation.WithFilter(u.filter), operation.WithUpdates(u.updates), operation.WithMongoOptions(opts), operation.WithModelHook(u.modelHook), operation.WithFields(u.fields), operation.WithStartTime(currentTime), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameUpdateOne), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(u.unscoped), operation.WithVersion(u.version))
 	opContext := NewOpContext(u.collection, u.filter, u.updates, WithMongoOptions(opts), WithModelHook(u.modelHook), WithFields(u.fields), WithStartTime(currentTime))
 	err := u.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeUpdate)
 	if err != nil {
 		return nil, err
 	}
+	ctx = globalOpContext.Context(ctx)
 
-	result, err := u.collection.UpdateOne(ctx, u.filter, u.updates, opts...)
+	result, err := u.collection.UpdateOne(ctx, globalOpContext.Filter, globalOpContext.Updates, opts...)
+	globalOpContext.Duration = u.clock().Sub(currentTime)
+	if err == nil && globalOpContext.VersionChecked && result.MatchedCount == 0 {
+		err = field.ErrVersionConflict
+	}
 	if err != nil {
-		return nil, err
+		return nil, u.dbCallbacks.OnError(ctx, globalOpContext, err)
 	}
 
 	globalOpContext.Result = result
@@ -150,24 +195,28 @@ func (u *Updater[T]) UpdateOne(ctx context.Context, opts ...options.Lister[optio
 }
 
 func (u *Updater[T]) UpdateMany(ctx context.Context, opts ...options.Lister[options.UpdateManyOptions]) (*mongo.UpdateResult, error) {
-	currentTime := time.Now()
+	ctx, cancel := utils.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	currentTime := u.clock()
 
 	updates := bsonx.ToBsonM(u.updates)
 	if len(updates) != 0 {
 		u.updates = updates
 	}
 
-	globalOpContext := operation.NewOpContext(u.collection, operation.WithDoc(new(T)), operation.WithFilter(u.filter), operation.WithUpdates(u.updates), operation.WithMongoOptions(opts), operation.WithModelHook(u.modelHook), operation.WithFields(u.fields), operation.WithStartTime(currentTime))
+	globalOpContext := operation.NewOpContext(u.collection, operation.WithDoc(new(T)), operation.WithFilter(u.filter), operation.WithUpdates(u.updates), operation.WithMongoOptions(opts), operation.WithModelHook(u.modelHook), operation.WithFields(u.fields), operation.WithStartTime(currentTime), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameUpdateMany), operation.WithMulti(true), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(u.unscoped), operation.WithVersion(u.version))
 	opContext := NewOpContext(u.collection, u.filter, u.updates, WithMongoOptions(opts), WithModelHook(u.modelHook), WithFields(u.fields), WithStartTime(currentTime))
 
 	err := u.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeUpdate)
 	if err != nil {
 		return nil, err
 	}
+	ctx = globalOpContext.Context(ctx)
 
-	result, err := u.collection.UpdateMany(ctx, u.filter, u.updates, opts...)
+	result, err := u.collection.UpdateMany(ctx, globalOpContext.Filter, globalOpContext.Updates, opts...)
+	globalOpContext.Duration = u.clock().Sub(currentTime)
 	if err != nil {
-		return nil, err
+		return nil, u.dbCallbacks.OnError(ctx, globalOpContext, err)
 	}
 
 	globalOpContext.Result = result
@@ -180,7 +229,9 @@ func (u *Updater[T]) UpdateMany(ctx context.Context, opts ...options.Lister[opti
 }
 
 func (u *Updater[T]) Upsert(ctx context.Context, opts ...options.Lister[options.UpdateOneOptions]) (*mongo.UpdateResult, error) {
-	currentTime := time.Now()
+	ctx, cancel := utils.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	currentTime := u.clock()
 
 	if len(opts) == 0 {
 		opts = append(opts, options.UpdateOne().SetUpsert(true))
@@ -198,16 +249,22 @@ func (u *Updater[T]) Upsert(ctx context.Context, opts ...options.Lister[options.
 		u.updates = updates
 	}
 
-	globalOpContext := operation.NewOpContext(u.collection, operation.WithDoc(new(T)), operation.WithFilter(u.filter), operation.WithUpdates(u.updates), operation.WithMongoOptions(opts), operation.WithModelHook(u.modelHook), operation.WithStartTime(currentTime), operation.WithFields(u.fields))
+	globalOpContext := operation.NewOpContext(u.collection, operation.WithDoc(new(T)), operation.WithFilter(u.filter), operation.WithUpdates(u.updates), operation.WithMongoOptions(opts), operation.WithModelHook(u.modelHook), operation.WithStartTime(currentTime), operation.WithFields(u.fields), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameUpsert), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(u.unscoped), operation.WithVersion(u.version))
 	opContext := NewOpContext(u.collection, u.filter, u.updates, WithMongoOptions(opts), WithModelHook(u.modelHook), WithStartTime(currentTime), WithFields(u.fields))
 	err := u.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeUpsert)
 	if err != nil {
 		return nil, err
 	}
+	ctx = globalOpContext.Context(ctx)
 
-	result, err := u.collection.UpdateOne(ctx, u.filter, u.updates, opts...)
+	result, err := u.collection.UpdateOne(ctx, globalOpContext.Filter, globalOpContext.Updates, opts...)
+	globalOpContext.Duration = u.clock().Sub(currentTime)
 	if err != nil {
-		return nil, err
+		if globalOpContext.VersionChecked && mongo.IsDuplicateKeyError(err) {
+			// the document exists with another version, the upsert tried to insert it again
+			err = field.ErrVersionConflict
+		}
+		return nil, u.dbCallbacks.OnError(ctx, globalOpContext, err)
 	}
 
 	globalOpContext.Result = result
@@ -218,3 +275,61 @@ func (u *Updater[T]) Upsert(ctx context.Context, opts ...options.Lister[options.
 	}
 	return result, nil
 }
+
+// ReplaceOne replaces the first document matching the filter with the Replacement.
+// The update time of the replacement is set, its _id and creation time are kept from the stored document if they are not set,
+// and its version is incremented if the model has a version field. Without Version, the version is checked against the stored one,
+// field.ErrVersionConflict is returned if the document is modified between the read of the stored version and the replacement.
+func (u *Updater[T]) ReplaceOne(ctx context.Context, opts ...options.Lister[options.ReplaceOptions]) (*mongo.UpdateResult, error) {
+	ctx, cancel := utils.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	currentTime := u.clock()
+
+	globalOpContext := operation.NewOpContext(u.collection, operation.WithDoc(new(T)), operation.WithFilter(u.filter), operation.WithReplacement(u.replacement), operation.WithMongoOptions(opts), operation.WithModelHook(u.modelHook), operation.WithFields(u.fields), operation.WithStartTime(currentTime), operation.WithSession(mongo.SessionFromContext(ctx)), operation.WithOpName(operation.OpNameReplaceOne), operation.WithLabels(operation.LabelsFromContext(ctx)), operation.WithUnscoped(u.unscoped), operation.WithVersion(u.version))
+	defer globalOpContext.Release()
+	opContext := NewOpContext(u.collection, u.filter, nil, WithReplacement(u.replacement), WithMongoOptions(opts), WithModelHook(u.modelHook), WithFields(u.fields), WithStartTime(currentTime))
+	err := u.preActionHandler(ctx, globalOpContext, opContext, operation.OpTypeBeforeReplace)
+	if err != nil {
+		return nil, err
+	}
+	ctx = globalOpContext.Context(ctx)
+
+	result, err := u.collection.ReplaceOne(ctx, globalOpContext.Filter, globalOpContext.Replacement, opts...)
+	globalOpContext.Duration = u.clock().Sub(currentTime)
+	if err == nil && globalOpContext.VersionChecked && result.MatchedCount == 0 && result.UpsertedCount == 0 {
+		err = field.ErrVersionConflict
+	}
+	if err != nil {
+		if globalOpContext.VersionChecked && mongo.IsDuplicateKeyError(err) {
+			// the document exists with another version, the replacement tried to insert it again
+			err = field.ErrVersionConflict
+		}
+		return nil, u.dbCallbacks.OnError(ctx, globalOpContext, err)
+	}
+
+	globalOpContext.Result = result
+	opContext.Result = result
+	err = u.postActionHandler(ctx, globalOpContext, opContext, operation.OpTypeAfterReplace)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ReplaceOrInsert is like ReplaceOne but inserts the Replacement if no document matches the filter,
+// the _id and the creation time are generated for the inserted document if they are not set.
+func (u *Updater[T]) ReplaceOrInsert(ctx context.Context, opts ...options.Lister[options.ReplaceOptions]) (*mongo.UpdateResult, error) {
+	return u.ReplaceOne(ctx, append(opts, options.Replace().SetUpsert(true))...)
+}
+
+// Restore is used to restore the soft-deleted documents which match the filter
+func (u *Updater[T]) Restore(ctx context.Context, opts ...options.Lister[options.UpdateManyOptions]) (*mongo.UpdateResult, error) {
+	fd := field.SoftDeleteField(u.fields)
+	if fd == nil {
+		return nil, ErrSoftDeleteDisabled
+	}
+	u.filter = hookfield.MergeFilter(u.filter, hookfield.Deleted(fd))
+	u.updates = bson.M{"$unset": bson.M{fd.MongoField: ""}}
+	u.unscoped = true
+	return u.UpdateMany(ctx, opts...)
+}
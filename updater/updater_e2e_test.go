@@ -1399,3 +1399,206 @@ func TestUpdater_e2e_Upsert(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdater_e2e_Version(t *testing.T) {
+	type VersionedUser struct {
+		ID      string `bson:"_id"`
+		Name    string `bson:"name"`
+		Version int64  `bson:"version" mongox:"version"`
+	}
+	collection := getCollection(t)
+	fields := field.ParseFields(VersionedUser{})
+	ctx := context.Background()
+	_, err := collection.InsertOne(ctx, VersionedUser{ID: "1", Name: "Mingyong Chen", Version: 1})
+	require.NoError(t, err)
+	defer func() {
+		_, err := collection.DeleteMany(ctx, query.NewBuilder().Id("1").Build())
+		require.NoError(t, err)
+	}()
+
+	result, err := NewUpdater[VersionedUser](collection, callback.InitializeCallbacks(), fields).
+		Filter(query.Id("1")).Updates(update.Set("name", "chenmingyong")).Version(1).UpdateOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.ModifiedCount)
+
+	var user VersionedUser
+	require.NoError(t, collection.FindOne(ctx, query.Id("1")).Decode(&user))
+	require.Equal(t, int64(2), user.Version)
+
+	// the document has been modified since version 1
+	_, err = NewUpdater[VersionedUser](collection, callback.InitializeCallbacks(), fields).
+		Filter(query.Id("1")).Updates(update.Set("name", "burt")).Version(1).UpdateOne(ctx)
+	require.ErrorIs(t, err, field.ErrVersionConflict)
+}
+
+func TestUpdater_e2e_ReplaceVersion(t *testing.T) {
+	type VersionedUser struct {
+		ID      string `bson:"_id"`
+		Name    string `bson:"name"`
+		Version int64  `bson:"version" mongox:"version"`
+	}
+	collection := getCollection(t)
+	fields := field.ParseFields(VersionedUser{})
+	ctx := context.Background()
+	_, err := collection.InsertOne(ctx, VersionedUser{ID: "1", Name: "Mingyong Chen", Version: 1})
+	require.NoError(t, err)
+	defer func() {
+		_, err := collection.DeleteMany(ctx, query.NewBuilder().Id("1").Build())
+		require.NoError(t, err)
+	}()
+
+	// the stored version is incremented without an expected version
+	result, err := NewUpdater[VersionedUser](collection, callback.InitializeCallbacks(), fields).
+		Filter(query.Id("1")).Replacement(&VersionedUser{ID: "1", Name: "chenmingyong"}).ReplaceOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.ModifiedCount)
+
+	var user VersionedUser
+	require.NoError(t, collection.FindOne(ctx, query.Id("1")).Decode(&user))
+	require.Equal(t, int64(2), user.Version)
+
+	result, err = NewUpdater[VersionedUser](collection, callback.InitializeCallbacks(), fields).
+		Filter(query.Id("1")).Replacement(&VersionedUser{ID: "1", Name: "burt"}).Version(2).ReplaceOne(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.ModifiedCount)
+	require.NoError(t, collection.FindOne(ctx, query.Id("1")).Decode(&user))
+	require.Equal(t, VersionedUser{ID: "1", Name: "burt", Version: 3}, user)
+
+	// the document has been modified since version 2
+	_, err = NewUpdater[VersionedUser](collection, callback.InitializeCallbacks(), fields).
+		Filter(query.Id("1")).Replacement(&VersionedUser{ID: "1", Name: "chenmingyong"}).Version(2).ReplaceOne(ctx)
+	require.ErrorIs(t, err, field.ErrVersionConflict)
+	_, err = NewUpdater[VersionedUser](collection, callback.InitializeCallbacks(), fields).
+		Filter(query.Id("1")).Replacement(&VersionedUser{ID: "1", Name: "chenmingyong"}).Version(2).ReplaceOrInsert(ctx)
+	require.ErrorIs(t, err, field.ErrVersionConflict)
+}
+
+func TestUpdater_e2e_ReplaceConcurrentWrite(t *testing.T) {
+	type VersionedUser struct {
+		ID        string    `bson:"_id,omitempty"`
+		Name      string    `bson:"name"`
+		CreatedAt time.Time `bson:"created_at"`
+		Version   int64     `bson:"version" mongox:"version"`
+	}
+	collection := getCollection(t)
+	fields := field.ParseFields(VersionedUser{})
+	ctx := context.Background()
+	_, err := collection.InsertOne(ctx, VersionedUser{ID: "1", Name: "Mingyong Chen", CreatedAt: time.Now().Add(-time.Hour), Version: 1})
+	require.NoError(t, err)
+	defer func() {
+		_, err := collection.DeleteMany(ctx, query.NewBuilder().Id("1").Build())
+		require.NoError(t, err)
+	}()
+
+	// the document is modified between the read of the stored version and the replacement
+	callbacks := callback.InitializeCallbacks()
+	require.NoError(t, callbacks.Register(operation.OpTypeBeforeReplace, "concurrent write", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+		_, err := collection.UpdateOne(ctx, query.Id("1"), bson.M{"$inc": bson.M{"version": 1}})
+		return err
+	}))
+
+	for _, replace := range []func(u *Updater[VersionedUser]) (*mongo.UpdateResult, error){
+		func(u *Updater[VersionedUser]) (*mongo.UpdateResult, error) { return u.ReplaceOne(ctx) },
+		func(u *Updater[VersionedUser]) (*mongo.UpdateResult, error) { return u.ReplaceOrInsert(ctx) },
+	} {
+		replacement := &VersionedUser{Name: "chenmingyong"}
+		_, err = replace(NewUpdater[VersionedUser](collection, callbacks, fields).Filter(query.Id("1")).Replacement(replacement))
+		require.ErrorIs(t, err, field.ErrVersionConflict)
+		// the fields set for the replacement are restored
+		require.Equal(t, &VersionedUser{Name: "chenmingyong"}, replacement)
+	}
+
+	var user VersionedUser
+	require.NoError(t, collection.FindOne(ctx, query.Id("1")).Decode(&user))
+	require.Equal(t, "Mingyong Chen", user.Name)
+	require.Equal(t, int64(3), user.Version)
+}
+
+func TestUpdater_e2e_ReplaceOne(t *testing.T) {
+	type User struct {
+		ID        bson.ObjectID `bson:"_id,omitempty" mongox:"autoID"`
+		Name      string        `bson:"name"`
+		Age       int64         `bson:"age"`
+		CreatedAt time.Time     `bson:"created_at"`
+		UpdatedAt time.Time     `bson:"updated_at"`
+	}
+	collection := getCollection(t)
+	callbacks := callback.InitializeCallbacks()
+	afterReplaces := 0
+	callbacks.Register(operation.OpTypeAfterReplace, "count", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+		afterReplaces++
+		return nil
+	})
+	newUpdater := func() *Updater[User] {
+		return NewUpdater[User](collection, callbacks, field.ParseFields(User{}))
+	}
+	defer func() {
+		_, err := collection.DeleteMany(context.Background(), query.In("name", "chenmingyong", "burt"))
+		require.NoError(t, err)
+	}()
+
+	createdAt := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	insertOneResult, err := collection.InsertOne(context.Background(), &User{ID: bson.NewObjectID(), Name: "chenmingyong", Age: 24, CreatedAt: createdAt, UpdatedAt: createdAt})
+	require.NoError(t, err)
+
+	t.Run("replace one", func(t *testing.T) {
+		replacement := &User{Name: "chenmingyong", Age: 25}
+		result, err := newUpdater().Filter(query.Eq("name", "chenmingyong")).Replacement(replacement).ReplaceOne(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, int64(1), result.ModifiedCount)
+		require.Equal(t, insertOneResult.InsertedID, replacement.ID)
+		require.True(t, createdAt.Equal(replacement.CreatedAt))
+
+		var user User
+		require.NoError(t, collection.FindOne(context.Background(), query.Eq("_id", insertOneResult.InsertedID)).Decode(&user))
+		require.Equal(t, int64(25), user.Age)
+		require.True(t, createdAt.Equal(user.CreatedAt))
+		require.True(t, user.UpdatedAt.After(createdAt))
+		require.Equal(t, 1, afterReplaces)
+	})
+
+	t.Run("replace or insert", func(t *testing.T) {
+		replacement := &User{Name: "burt", Age: 26}
+		result, err := newUpdater().Filter(query.Eq("name", "burt")).Replacement(replacement).ReplaceOrInsert(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, int64(1), result.UpsertedCount)
+		require.Equal(t, replacement.ID, result.UpsertedID)
+		require.False(t, replacement.CreatedAt.IsZero())
+		require.Equal(t, replacement.CreatedAt, replacement.UpdatedAt)
+	})
+}
+
+func TestUpdater_e2e_OpContextMetadata(t *testing.T) {
+	collection := getCollection(t)
+	callbacks := callback.InitializeCallbacks()
+	var got []*operation.OpContext
+	require.NoError(t, callbacks.Register(operation.OpTypeBeforeUpdate, "start", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+		opCtx.Set("started", true)
+		return nil
+	}))
+	require.NoError(t, callbacks.Register(operation.OpTypeAfterUpdate, "end", func(ctx context.Context, opCtx *operation.OpContext, opts ...any) error {
+		got = append(got, opCtx)
+		return nil
+	}))
+	ctx := operation.ContextWithLabels(context.Background(), map[string]string{"tenant": "acme"})
+
+	_, err := NewUpdater[User](collection, callbacks, field.ParseFields(User{})).Filter(query.Eq("name", "chenmingyong")).Updates(update.Set("age", 24)).UpdateOne(ctx)
+	require.NoError(t, err)
+	_, err = NewUpdater[User](collection, callbacks, field.ParseFields(User{})).Filter(query.Eq("name", "chenmingyong")).Updates(update.Set("age", 24)).UpdateMany(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, got, 2)
+	for i, want := range []struct {
+		opName string
+		multi  bool
+	}{{operation.OpNameUpdateOne, false}, {operation.OpNameUpdateMany, true}} {
+		require.Equal(t, want.opName, got[i].OpName)
+		require.Equal(t, want.multi, got[i].Multi)
+		require.Equal(t, "db-test", got[i].DatabaseName)
+		require.Equal(t, "test_user", got[i].CollectionName)
+		require.Equal(t, map[string]string{"tenant": "acme"}, got[i].Labels)
+		started, ok := got[i].Get("started")
+		require.True(t, ok)
+		require.Equal(t, true, started)
+	}
+}
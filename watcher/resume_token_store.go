@@ -0,0 +1,66 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watcher
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ResumeTokenStore persists the resume tokens of the change streams, each stream is identified by a key
+type ResumeTokenStore interface {
+	// Load returns the token saved under the key, nil if there is none
+	Load(ctx context.Context, key string) (bson.Raw, error)
+	Save(ctx context.Context, key string, token bson.Raw) error
+}
+
+var _ ResumeTokenStore = (*MongoResumeTokenStore)(nil)
+
+// MongoResumeTokenStore saves the resume tokens in a collection, one document per key
+type MongoResumeTokenStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoResumeTokenStore(collection *mongo.Collection) *MongoResumeTokenStore {
+	return &MongoResumeTokenStore{collection: collection}
+}
+
+type resumeToken struct {
+	Key       string    `bson:"_id"`
+	Token     bson.Raw  `bson:"token"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+func (s *MongoResumeTokenStore) Load(ctx context.Context, key string) (bson.Raw, error) {
+	var token resumeToken
+	err := s.collection.FindOne(ctx, bson.M{"_id": key}).Decode(&token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token.Token, nil
+}
+
+func (s *MongoResumeTokenStore) Save(ctx context.Context, key string, token bson.Raw) error {
+	_, err := s.collection.ReplaceOne(ctx, bson.M{"_id": key}, resumeToken{Key: key, Token: token, UpdatedAt: time.Now()}, options.Replace().SetUpsert(true))
+	return err
+}
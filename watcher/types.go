@@ -0,0 +1,63 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watcher
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Operation types of the change events
+const (
+	OperationTypeInsert       = "insert"
+	OperationTypeUpdate       = "update"
+	OperationTypeReplace      = "replace"
+	OperationTypeDelete       = "delete"
+	OperationTypeDrop         = "drop"
+	OperationTypeRename       = "rename"
+	OperationTypeDropDatabase = "dropDatabase"
+	OperationTypeInvalidate   = "invalidate"
+)
+
+// ChangeEvent is a change event whose documents are decoded into T
+type ChangeEvent[T any] struct {
+	// ID is the resume token of the event
+	ID            bson.Raw       `bson:"_id"`
+	OperationType string         `bson:"operationType"`
+	ClusterTime   bson.Timestamp `bson:"clusterTime"`
+	Namespace     Namespace      `bson:"ns"`
+	DocumentKey   bson.M         `bson:"documentKey"`
+	// FullDocument is only set for update events when the fullDocument option is set
+	FullDocument *T `bson:"fullDocument"`
+	// FullDocumentBeforeChange is only set when the fullDocumentBeforeChange option is set
+	// and the pre-images are enabled for the collection
+	FullDocumentBeforeChange *T                 `bson:"fullDocumentBeforeChange"`
+	UpdateDescription        *UpdateDescription `bson:"updateDescription"`
+}
+
+type Namespace struct {
+	Database   string `bson:"db"`
+	Collection string `bson:"coll"`
+}
+
+type UpdateDescription struct {
+	UpdatedFields   bson.M           `bson:"updatedFields"`
+	RemovedFields   []string         `bson:"removedFields"`
+	TruncatedArrays []TruncatedArray `bson:"truncatedArrays"`
+}
+
+type TruncatedArray struct {
+	Field   string `bson:"field"`
+	NewSize int32  `bson:"newSize"`
+}
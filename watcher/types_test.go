@@ -0,0 +1,100 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type user struct {
+	ID   string `bson:"_id"`
+	Name string `bson:"name"`
+	Age  int    `bson:"age"`
+}
+
+func TestChangeEvent_Decode(t *testing.T) {
+	testCases := []struct {
+		name  string
+		event bson.M
+		want  ChangeEvent[user]
+	}{
+		{
+			name: "insert",
+			event: bson.M{
+				"operationType": OperationTypeInsert,
+				"ns":            bson.M{"db": "db-test", "coll": "test_user"},
+				"documentKey":   bson.M{"_id": "1"},
+				"fullDocument":  bson.M{"_id": "1", "name": "chenmingyong", "age": 24},
+			},
+			want: ChangeEvent[user]{
+				OperationType: OperationTypeInsert,
+				Namespace:     Namespace{Database: "db-test", Collection: "test_user"},
+				DocumentKey:   bson.M{"_id": "1"},
+				FullDocument:  &user{ID: "1", Name: "chenmingyong", Age: 24},
+			},
+		},
+		{
+			name: "update",
+			event: bson.M{
+				"operationType": OperationTypeUpdate,
+				"ns":            bson.M{"db": "db-test", "coll": "test_user"},
+				"documentKey":   bson.M{"_id": "1"},
+				"updateDescription": bson.M{
+					"updatedFields":   bson.M{"age": 25},
+					"removedFields":   bson.A{"name"},
+					"truncatedArrays": bson.A{bson.M{"field": "tags", "newSize": 1}},
+				},
+				"fullDocumentBeforeChange": bson.M{"_id": "1", "name": "chenmingyong", "age": 24},
+			},
+			want: ChangeEvent[user]{
+				OperationType: OperationTypeUpdate,
+				Namespace:     Namespace{Database: "db-test", Collection: "test_user"},
+				DocumentKey:   bson.M{"_id": "1"},
+				UpdateDescription: &UpdateDescription{
+					UpdatedFields:   bson.M{"age": int32(25)},
+					RemovedFields:   []string{"name"},
+					TruncatedArrays: []TruncatedArray{{Field: "tags", NewSize: 1}},
+				},
+				FullDocumentBeforeChange: &user{ID: "1", Name: "chenmingyong", Age: 24},
+			},
+		},
+		{
+			name: "delete",
+			event: bson.M{
+				"operationType": OperationTypeDelete,
+				"ns":            bson.M{"db": "db-test", "coll": "test_user"},
+				"documentKey":   bson.M{"_id": "1"},
+			},
+			want: ChangeEvent[user]{
+				OperationType: OperationTypeDelete,
+				Namespace:     Namespace{Database: "db-test", Collection: "test_user"},
+				DocumentKey:   bson.M{"_id": "1"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := bson.Marshal(tc.event)
+			require.NoError(t, err)
+
+			var event ChangeEvent[user]
+			require.NoError(t, bson.Unmarshal(data, &event))
+			require.Equal(t, tc.want, event)
+		})
+	}
+}
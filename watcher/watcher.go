@@ -0,0 +1,151 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watcher
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Watchable is implemented by *mongo.Collection, *mongo.Database and *mongo.Client
+type Watchable interface {
+	Watch(ctx context.Context, pipeline any, opts ...options.Lister[options.ChangeStreamOptions]) (*mongo.ChangeStream, error)
+}
+
+type Watcher[T any] struct {
+	target   Watchable
+	pipeline any
+
+	store    ResumeTokenStore
+	storeKey string
+}
+
+func NewWatcher[T any](target Watchable) *Watcher[T] {
+	return &Watcher[T]{target: target, pipeline: mongo.Pipeline{}}
+}
+
+// Pipeline is used to set the pipeline of the change stream, e.g. the one built by aggregation.StageBuilder
+func (w *Watcher[T]) Pipeline(pipeline any) *Watcher[T] {
+	if pipeline != nil {
+		w.pipeline = pipeline
+	}
+	return w
+}
+
+// ResumeTokenStore is used to checkpoint the resume token of the change stream under the key.
+// Watch resumes after the stored token, ChangeStream.Checkpoint saves the token of the current event.
+func (w *Watcher[T]) ResumeTokenStore(store ResumeTokenStore, key string) *Watcher[T] {
+	w.store = store
+	w.storeKey = key
+	return w
+}
+
+// Watch opens the change stream. If a resume token has been checkpointed it is resumed after the token,
+// the resumeAfter or startAfter options of opts take precedence.
+func (w *Watcher[T]) Watch(ctx context.Context, opts ...options.Lister[options.ChangeStreamOptions]) (*ChangeStream[T], error) {
+	if w.store != nil {
+		token, err := w.store.Load(ctx, w.storeKey)
+		if err != nil {
+			return nil, err
+		}
+		if token != nil {
+			opts = append([]options.Lister[options.ChangeStreamOptions]{options.ChangeStream().SetResumeAfter(token)}, opts...)
+		}
+	}
+
+	stream, err := w.target.Watch(ctx, w.pipeline, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ChangeStream[T]{stream: stream, store: w.store, storeKey: w.storeKey}, nil
+}
+
+// ChangeStream iterates the change events decoded into ChangeEvent[T]
+type ChangeStream[T any] struct {
+	stream *mongo.ChangeStream
+
+	event *ChangeEvent[T]
+	err   error
+
+	store    ResumeTokenStore
+	storeKey string
+}
+
+// Next blocks until the next event is available, it returns false when the stream is closed or fails
+func (cs *ChangeStream[T]) Next(ctx context.Context) bool {
+	return cs.decode(cs.stream.Next(ctx))
+}
+
+// TryNext is the non-blocking version of Next, it returns false if no event is available yet
+func (cs *ChangeStream[T]) TryNext(ctx context.Context) bool {
+	return cs.decode(cs.stream.TryNext(ctx))
+}
+
+func (cs *ChangeStream[T]) decode(ok bool) bool {
+	cs.event = nil
+	if !ok {
+		return false
+	}
+	event := new(ChangeEvent[T])
+	if err := cs.stream.Decode(event); err != nil {
+		cs.err = err
+		return false
+	}
+	cs.event = event
+	return true
+}
+
+// Event returns the current event, nil before the first call of Next or after the stream ends
+func (cs *ChangeStream[T]) Event() *ChangeEvent[T] {
+	return cs.event
+}
+
+// Err returns the error of the last Next or TryNext call
+func (cs *ChangeStream[T]) Err() error {
+	if cs.err != nil {
+		return cs.err
+	}
+	return cs.stream.Err()
+}
+
+// ResumeToken returns the resume token of the last event
+func (cs *ChangeStream[T]) ResumeToken() bson.Raw {
+	return cs.stream.ResumeToken()
+}
+
+// Checkpoint saves the resume token of the last event so that the next Watch resumes after it.
+// It does nothing if no ResumeTokenStore is set.
+func (cs *ChangeStream[T]) Checkpoint(ctx context.Context) error {
+	if cs.store == nil {
+		return nil
+	}
+	token := cs.stream.ResumeToken()
+	if token == nil {
+		return nil
+	}
+	return cs.store.Save(ctx, cs.storeKey, token)
+}
+
+func (cs *ChangeStream[T]) Close(ctx context.Context) error {
+	return cs.stream.Close(ctx)
+}
+
+// ChangeStream returns the underlying mongo change stream
+func (cs *ChangeStream[T]) ChangeStream() *mongo.ChangeStream {
+	return cs.stream
+}
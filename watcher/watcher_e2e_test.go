@@ -0,0 +1,133 @@
+// Copyright 2025 chenmingyong0423
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build e2e
+
+package watcher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/chenmingyong0423/go-mongox/v2/builder/aggregation"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+func getDatabase(t *testing.T) *mongo.Database {
+	client, err := mongo.Connect(options.Client().ApplyURI("mongodb://localhost:27017").SetAuth(options.Credential{
+		Username:   "test",
+		Password:   "test",
+		AuthSource: "db-test",
+	}))
+	assert.NoError(t, err)
+	assert.NoError(t, client.Ping(context.Background(), readpref.Primary()))
+
+	return client.Database("db-test")
+}
+
+// skipIfChangeStreamUnsupported skips the test when the server is a standalone instance,
+// change streams require a replica set or a sharded cluster
+func skipIfChangeStreamUnsupported(t *testing.T, db *mongo.Database) {
+	var result bson.M
+	err := db.Client().Database("admin").RunCommand(context.Background(), bson.D{{Key: "hello", Value: 1}}).Decode(&result)
+	require.NoError(t, err)
+	if result["setName"] == nil && result["msg"] != "isdbgrid" {
+		t.Skip("change streams are not supported by a standalone server")
+	}
+}
+
+func TestMongoResumeTokenStore_e2e(t *testing.T) {
+	collection := getDatabase(t).Collection("test_resume_token")
+	defer func() {
+		_, err := collection.DeleteMany(context.Background(), bson.D{})
+		require.NoError(t, err)
+	}()
+	store := NewMongoResumeTokenStore(collection)
+
+	token, err := store.Load(context.Background(), "users")
+	require.NoError(t, err)
+	require.Nil(t, token)
+
+	for _, data := range []string{"1", "2"} {
+		raw, err := bson.Marshal(bson.M{"_data": data})
+		require.NoError(t, err)
+		require.NoError(t, store.Save(context.Background(), "users", raw))
+
+		token, err = store.Load(context.Background(), "users")
+		require.NoError(t, err)
+		require.Equal(t, bson.Raw(raw), token)
+	}
+}
+
+func TestWatcher_e2e_Watch(t *testing.T) {
+	db := getDatabase(t)
+	skipIfChangeStreamUnsupported(t, db)
+
+	collection := db.Collection("test_user")
+	tokenCollection := db.Collection("test_resume_token")
+	defer func() {
+		_, err := collection.DeleteMany(context.Background(), bson.D{})
+		require.NoError(t, err)
+		_, err = tokenCollection.DeleteMany(context.Background(), bson.D{})
+		require.NoError(t, err)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	store := NewMongoResumeTokenStore(tokenCollection)
+	pipeline := aggregation.NewStageBuilder().Match(query.In("operationType", OperationTypeInsert, OperationTypeUpdate)).Build()
+
+	stream, err := NewWatcher[user](collection).Pipeline(pipeline).ResumeTokenStore(store, "users").
+		Watch(ctx, options.ChangeStream().SetFullDocument(options.UpdateLookup))
+	require.NoError(t, err)
+
+	_, err = collection.InsertOne(ctx, user{ID: "1", Name: "chenmingyong", Age: 24})
+	require.NoError(t, err)
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": "1"}, bson.M{"$set": bson.M{"age": 25}})
+	require.NoError(t, err)
+	_, err = collection.DeleteOne(ctx, bson.M{"_id": "1"})
+	require.NoError(t, err)
+	_, err = collection.InsertOne(ctx, user{ID: "2", Name: "burt", Age: 30})
+	require.NoError(t, err)
+
+	require.True(t, stream.Next(ctx))
+	event := stream.Event()
+	require.Equal(t, OperationTypeInsert, event.OperationType)
+	require.Equal(t, &user{ID: "1", Name: "chenmingyong", Age: 24}, event.FullDocument)
+	require.Equal(t, bson.M{"_id": "1"}, event.DocumentKey)
+
+	require.True(t, stream.Next(ctx))
+	event = stream.Event()
+	require.Equal(t, OperationTypeUpdate, event.OperationType)
+	require.Equal(t, bson.M{"age": int32(25)}, event.UpdateDescription.UpdatedFields)
+	require.NoError(t, stream.Checkpoint(ctx))
+	require.NoError(t, stream.Close(ctx))
+
+	// the delete event is filtered out by the pipeline, the stream resumes from the insert of the second user
+	stream, err = NewWatcher[user](collection).Pipeline(pipeline).ResumeTokenStore(store, "users").Watch(ctx)
+	require.NoError(t, err)
+	defer stream.Close(ctx)
+	require.True(t, stream.Next(ctx))
+	event = stream.Event()
+	require.Equal(t, OperationTypeInsert, event.OperationType)
+	require.Equal(t, &user{ID: "2", Name: "burt", Age: 30}, event.FullDocument)
+	require.NoError(t, stream.Err())
+}